@@ -1,31 +1,111 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"blog/i18n"
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
 )
 
+// errValidation 参数校验未通过，响应已写出
+var errValidation = errors.New("validation failed")
+
+// RequestTimeout 单个请求的处理超时时间，为0时不限制
+var RequestTimeout = 30 * time.Second
+
+func init() {
+	if timeout, err := web.AppConfig.String("requesttimeout"); err == nil && timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			panic(err)
+		}
+		RequestTimeout = d
+	}
+}
+
 // BaseController 基础控制器，提供通用方法
 type BaseController struct {
 	web.Controller
+	ctx    context.Context
+	cancel context.CancelFunc
+	lang   string
+}
+
+// Prepare 为每个请求创建带超时的上下文，客户端断开时自动取消
+func (c *BaseController) Prepare() {
+	if RequestTimeout > 0 {
+		c.ctx, c.cancel = context.WithTimeout(c.Ctx.Request.Context(), RequestTimeout)
+	} else {
+		c.ctx, c.cancel = context.WithCancel(c.Ctx.Request.Context())
+	}
+}
+
+// Finish 请求结束时释放上下文
+func (c *BaseController) Finish() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// Context 获取当前请求的上下文，用于传递给模型层
+func (c *BaseController) Context() context.Context {
+	if c.ctx == nil {
+		return c.Ctx.Request.Context()
+	}
+	return c.ctx
+}
+
+// Lang 当前请求使用的语言：优先用户设置的语言，其次Accept-Language请求头
+//
+// 用户设置的语言取自JWT令牌；通过事件流票据等方式认证、上下文中没有语言时才查询数据库。
+func (c *BaseController) Lang() string {
+	if c.lang != "" {
+		return c.lang
+	}
+	if lang, ok := c.Ctx.Input.GetData("lang").(string); ok {
+		c.lang = lang
+	} else if userID := c.GetUserID(); userID > 0 {
+		if lang, err := models.UserLanguage(c.Context(), userID); err == nil {
+			c.lang = lang
+		}
+	}
+	if c.lang != "" {
+		return c.lang
+	}
+	c.lang = i18n.Negotiate(c.Ctx.Input.Header("Accept-Language"))
+	return c.lang
+}
+
+// T 按当前请求的语言渲染消息
+func (c *BaseController) T(code string, args ...interface{}) string {
+	return i18n.T(c.Lang(), code, args...)
 }
 
 // Response API统一响应格式
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code      int          `json:"code"`
+	Message   string       `json:"message"`
+	ErrorCode string       `json:"error_code,omitempty"` // 稳定的机器可读错误码
+	Data      interface{}  `json:"data,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
 }
 
-// Pagination 分页信息
+// Pagination 分页信息，页码分页返回page，游标分页返回next_cursor；未统计总数时不返回total_*
 type Pagination struct {
-	Page       int `json:"page"`
-	PageSize   int `json:"page_size"`
-	TotalItems int `json:"total_items"`
-	TotalPages int `json:"total_pages"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	TotalItems *int   `json:"total_items,omitempty"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // Success 成功响应
@@ -33,7 +113,7 @@ func (c *BaseController) Success(data interface{}) {
 	c.Ctx.Output.SetStatus(http.StatusOK)
 	c.Data["json"] = Response{
 		Code:    200,
-		Message: "成功",
+		Message: c.T("success"),
 		Data:    data,
 	}
 	c.ServeJSON()
@@ -48,12 +128,59 @@ func (c *BaseController) SuccessWithPagination(data interface{}, pagination Pagi
 	c.Success(result)
 }
 
-// Error 错误响应
-func (c *BaseController) Error(code int, message string) {
-	c.Ctx.Output.SetStatus(code)
+// kindStatus 业务错误类别对应的HTTP状态码
+var kindStatus = map[models.Kind]int{
+	models.KindNotFound:     http.StatusNotFound,
+	models.KindConflict:     http.StatusConflict,
+	models.KindForbidden:    http.StatusForbidden,
+	models.KindValidation:   http.StatusBadRequest,
+	models.KindUnauthorized: http.StatusUnauthorized,
+	models.KindRateLimited:  http.StatusTooManyRequests,
+}
+
+// Error 错误响应，按业务错误类别决定状态码；其他错误一律视为内部错误，不向客户端暴露细节
+func (c *BaseController) Error(err error) {
+	var localized models.LocalizedError
+	if errors.As(err, &localized) {
+		if status, ok := kindStatus[localized.ErrorKind()]; ok {
+			c.ErrorWithCode(status, localized.ErrorCode(), localized.Localize(c.Lang()))
+			return
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		logs.Error("Request timed out on %s %s: %v", c.Ctx.Input.Method(), c.Ctx.Input.URL(), err)
+		c.ErrorWithCode(http.StatusServiceUnavailable, "request_timeout")
+		return
+	}
+
+	logs.Error("Internal error on %s %s: %v", c.Ctx.Input.Method(), c.Ctx.Input.URL(), err)
+	c.ErrorWithCode(http.StatusInternalServerError, "internal_error")
+}
+
+// ErrorWithCode 错误响应，errorCode为消息码，未指定message时按消息码渲染
+func (c *BaseController) ErrorWithCode(status int, errorCode string, message ...string) {
+	msg := c.T(errorCode)
+	if len(message) > 0 {
+		msg = message[0]
+	}
+	c.Ctx.Output.SetStatus(status)
 	c.Data["json"] = Response{
-		Code:    code,
-		Message: message,
+		Code:      status,
+		Message:   msg,
+		ErrorCode: errorCode,
+	}
+	c.ServeJSON()
+}
+
+// ValidationError 参数校验失败响应
+func (c *BaseController) ValidationError(errors []FieldError) {
+	c.Ctx.Output.SetStatus(http.StatusUnprocessableEntity)
+	c.Data["json"] = Response{
+		Code:      http.StatusUnprocessableEntity,
+		Message:   c.T("validation_failed"),
+		ErrorCode: "validation_failed",
+		Errors:    errors,
 	}
 	c.ServeJSON()
 }
@@ -62,9 +189,29 @@ func (c *BaseController) Error(code int, message string) {
 func (c *BaseController) ParseAndValidate(v interface{}) error {
 	err := json.Unmarshal(c.Ctx.Input.RequestBody, v)
 	if err != nil {
-		c.Error(http.StatusBadRequest, "请求参数格式错误")
+		// 字段类型不匹配时返回字段级错误，其余按JSON格式错误处理
+		if fieldError, ok := unmarshalFieldError(c.Ctx.Input.RequestBody, v, err, c.Lang()); ok {
+			c.ValidationError([]FieldError{fieldError})
+			return err
+		}
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_request_body")
+		return err
+	}
+	return c.Validate(v)
+}
+
+// Validate 按valid标签校验参数，失败时返回422及字段级错误
+func (c *BaseController) Validate(v interface{}) error {
+	fieldErrors, err := validateStruct(v, c.Lang())
+	if err != nil {
+		logs.Error("Invalid validation rules on %T: %v", v, err)
+		c.ErrorWithCode(http.StatusInternalServerError, "internal_error")
 		return err
 	}
+	if len(fieldErrors) > 0 {
+		c.ValidationError(fieldErrors)
+		return errValidation
+	}
 	return nil
 }
 
@@ -2,8 +2,14 @@ package controllers
 
 import (
 	"blog/models"
+	"blog/notify"
+	"bytes"
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // BillController 账单控制器
@@ -20,23 +26,112 @@ type BillController struct {
 // @Param category_id query int false "分类ID"
 // @Param min_amount query number false "最小金额"
 // @Param max_amount query number false "最大金额"
+// @Param q query string false "搜索描述、商户、分类名称和标签，多个关键词以空格分隔，需全部命中；结果带highlights高亮片段"
+// @Param view_id query int false "使用保存的视图作为筛选条件，请求中的同名参数优先"
 // @Param page query int false "页码，默认1"
 // @Param page_size query int false "每页条数，默认10"
+// @Param cursor query string false "游标分页：首页传空值，之后传上一页返回的next_cursor；传入时忽略page"
+// @Param sort query string false "排序：date、amount、created_at，前加-表示降序，默认-date"
+// @Param with_total query bool false "是否统计总数，页码分页默认true，游标分页默认false"
 // @Success 200 {object} map[string]interface{} 账单列表和分页信息
 // @Failure 401 未授权
+// @Failure 404 视图不存在
+// @Failure 422 请求参数校验失败
 // @Failure 500 服务器内部错误
 // @Router /api/bills [get]
 func (c *BillController) List() {
 	userID := c.GetUserID()
 	page, pageSize := c.GetPagination()
 	
+	// 请求中带有cursor参数（包括空值）时使用游标分页
+	_, useCursor := c.Ctx.Request.URL.Query()["cursor"]
+	withTotal, err := c.GetBool("with_total", !useCursor)
+	if err != nil {
+		withTotal = !useCursor
+	}
+	
 	// 构建查询参数
-	params := &models.BillQueryParams{
-		StartDate:  c.Ctx.Input.Query("start_date"),
-		EndDate:    c.Ctx.Input.Query("end_date"),
-		Type:       c.Ctx.Input.Query("type"),
-		Page:       page,
+	params, err := c.queryParams()
+	if err != nil {
+		return
+	}
+	params.Page = page
+	params.PageSize = pageSize
+	params.Cursor = c.Ctx.Input.Query("cursor")
+	params.UseCursor = useCursor
+	params.WithTotal = withTotal
+	
+	if err := c.Validate(params); err != nil {
+		return
+	}
+	
+	// 查询账单
+	result, err := models.GetBills(c.Context(), userID, params)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	// 构建分页信息
+	pagination := Pagination{
 		PageSize:   pageSize,
+		HasMore:    result.HasMore,
+		NextCursor: result.NextCursor,
+	}
+	if !useCursor {
+		pagination.Page = page
+	}
+	if withTotal {
+		// 计算总页数
+		totalPages := (result.Total + pageSize - 1) / pageSize
+		pagination.TotalItems = &result.Total
+		pagination.TotalPages = &totalPages
+	}
+	
+	c.SuccessWithPagination(result.Bills, pagination)
+}
+
+// queryParams 解析账单筛选参数，传入view_id时以保存的视图为基础，请求中的同名参数优先
+func (c *BillController) queryParams() (*models.BillQueryParams, error) {
+	userID := c.GetUserID()
+	params := &models.BillQueryParams{}
+	
+	if viewIDStr := c.Ctx.Input.Query("view_id"); viewIDStr != "" {
+		viewID, err := strconv.ParseUint(viewIDStr, 10, 64)
+		if err != nil {
+			c.ErrorWithCode(http.StatusBadRequest, "invalid_view_id")
+			return nil, err
+		}
+		
+		view, err := models.GetView(c.Context(), uint(viewID), userID)
+		if err != nil {
+			c.Error(err)
+			return nil, err
+		}
+		
+		// 相对日期按用户时区下的当天计算
+		loc, err := models.UserLocation(c.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return nil, err
+		}
+		params = view.Filter.Params(models.Today(loc))
+	}
+	
+	if startDate := c.Ctx.Input.Query("start_date"); startDate != "" {
+		params.StartDate = startDate
+	}
+	if endDate := c.Ctx.Input.Query("end_date"); endDate != "" {
+		params.EndDate = endDate
+	}
+	if billType := c.Ctx.Input.Query("type"); billType != "" {
+		params.Type = billType
+	}
+	if q := c.Ctx.Input.Query("q"); q != "" {
+		params.Q = q
+	}
+	if sort := c.Ctx.Input.Query("sort"); sort != "" {
+		params.Sort = sort
 	}
 	
 	// 处理数字类型的查询参数
@@ -48,38 +143,20 @@ func (c *BillController) List() {
 	}
 	
 	if minAmountStr := c.Ctx.Input.Query("min_amount"); minAmountStr != "" {
-		minAmount, err := strconv.ParseFloat(minAmountStr, 64)
+		minAmount, err := models.ParseMoney(minAmountStr)
 		if err == nil {
 			params.MinAmount = minAmount
 		}
 	}
 	
 	if maxAmountStr := c.Ctx.Input.Query("max_amount"); maxAmountStr != "" {
-		maxAmount, err := strconv.ParseFloat(maxAmountStr, 64)
+		maxAmount, err := models.ParseMoney(maxAmountStr)
 		if err == nil {
 			params.MaxAmount = maxAmount
 		}
 	}
 	
-	// 查询账单
-	bills, total, err := models.GetBills(userID, params)
-	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
-		return
-	}
-	
-	// 计算总页数
-	totalPages := (total + pageSize - 1) / pageSize
-	
-	// 构建分页信息
-	pagination := Pagination{
-		Page:       page,
-		PageSize:   pageSize,
-		TotalItems: total,
-		TotalPages: totalPages,
-	}
-	
-	c.SuccessWithPagination(bills, pagination)
+	return params, nil
 }
 
 // Create 创建账单
@@ -88,6 +165,7 @@ func (c *BillController) List() {
 // @Param body body models.BillRequest true "账单信息"
 // @Success 200 {object} models.Bill 创建的账单
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
 // @Failure 500 服务器内部错误
 // @Router /api/bills [post]
@@ -99,12 +177,15 @@ func (c *BillController) Create() {
 		return
 	}
 	
-	bill, err := models.CreateBill(userID, &req)
+	bill, err := models.CreateBill(c.Context(), userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventBillCreated, userID, bill))
+	notify.CheckBudgetAlerts(c.Context(), userID, bill.Date.Format("2006-01"))
+	
 	c.Success(bill)
 }
 
@@ -123,13 +204,13 @@ func (c *BillController) Get() {
 	
 	billID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "账单ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_bill_id")
 		return
 	}
 	
-	bill, err := models.GetBill(billID, userID)
+	bill, err := models.GetBill(c.Context(), billID, userID)
 	if err != nil {
-		c.Error(http.StatusNotFound, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -143,6 +224,7 @@ func (c *BillController) Get() {
 // @Param body body models.BillRequest true "账单信息"
 // @Success 200 {object} models.Bill 更新后的账单
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
 // @Failure 404 账单不存在
 // @Failure 500 服务器内部错误
@@ -152,7 +234,7 @@ func (c *BillController) Update() {
 	
 	billID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "账单ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_bill_id")
 		return
 	}
 	
@@ -161,12 +243,17 @@ func (c *BillController) Update() {
 		return
 	}
 	
-	bill, err := models.UpdateBill(billID, userID, &req)
+	bill, err := models.UpdateBill(c.Context(), billID, userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventBillUpdated, userID, bill))
+	
+	// 账单移出原月份或改为收入只会降低原月份的使用额，只需检查账单现在所在的月份
+	notify.CheckBudgetAlerts(c.Context(), userID, bill.Date.Format("2006-01"))
+	
 	c.Success(bill)
 }
 
@@ -185,24 +272,135 @@ func (c *BillController) Delete() {
 	
 	billID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "账单ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_bill_id")
 		return
 	}
 	
-	err = models.DeleteBill(billID, userID)
+	// 删除账单只会降低使用额，不会触发新的预算告警
+	err = models.DeleteBill(c.Context(), billID, userID)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventBillDeleted, userID, map[string]uint{"id": billID}))
+	
 	c.Success(nil)
 }
 
+// Stats 按筛选条件统计
+// @Title 按筛选条件统计
+// @Description 统计符合筛选条件的账单收支合计和分类汇总，筛选参数与账单列表相同
+// @Param view_id query int false "使用保存的视图作为筛选条件，请求中的同名参数优先"
+// @Param start_date query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end_date query string false "结束日期，格式：YYYY-MM-DD"
+// @Param type query string false "账单类型：income/expense"
+// @Param category_id query int false "分类ID"
+// @Param min_amount query number false "最小金额"
+// @Param max_amount query number false "最大金额"
+// @Param q query string false "搜索关键词"
+// @Success 200 {object} map[string]interface{} 统计数据
+// @Failure 401 未授权
+// @Failure 404 视图不存在
+// @Failure 422 请求参数校验失败
+// @Failure 500 服务器内部错误
+// @Router /api/bills/stats [get]
+func (c *BillController) Stats() {
+	userID := c.GetUserID()
+	
+	params, err := c.queryParams()
+	if err != nil {
+		return
+	}
+	if err := c.Validate(params); err != nil {
+		return
+	}
+	
+	stats, err := models.GetBillStats(c.Context(), userID, params)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(stats)
+}
+
+// maxExportRows 单次导出的最大账单数
+const maxExportRows = 10000
+
+// Export 导出账单
+// @Title 导出账单
+// @Description 将符合筛选条件的账单导出为CSV，筛选参数与账单列表相同；超过上限时只导出前10000条并设置X-Export-Truncated响应头
+// @Param view_id query int false "使用保存的视图作为筛选条件，请求中的同名参数优先"
+// @Param start_date query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end_date query string false "结束日期，格式：YYYY-MM-DD"
+// @Param type query string false "账单类型：income/expense"
+// @Param category_id query int false "分类ID"
+// @Param min_amount query number false "最小金额"
+// @Param max_amount query number false "最大金额"
+// @Param q query string false "搜索关键词"
+// @Param sort query string false "排序：date、amount、created_at，前加-表示降序，默认-date"
+// @Success 200 {file} CSV文件
+// @Failure 401 未授权
+// @Failure 404 视图不存在
+// @Failure 422 请求参数校验失败
+// @Failure 500 服务器内部错误
+// @Router /api/bills/export [get]
+func (c *BillController) Export() {
+	userID := c.GetUserID()
+	
+	params, err := c.queryParams()
+	if err != nil {
+		return
+	}
+	params.PageSize = maxExportRows
+	if err := c.Validate(params); err != nil {
+		return
+	}
+	
+	result, err := models.GetBills(c.Context(), userID, params)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	// 带BOM以便Excel正确识别UTF-8编码的中文
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "date", "type", "category", "amount", "merchant", "description", "tags"})
+	for _, bill := range result.Bills {
+		w.Write([]string{
+			strconv.FormatUint(uint64(bill.ID), 10),
+			bill.Date.Format("2006-01-02"),
+			bill.Type,
+			bill.CategoryName,
+			bill.Amount.String(),
+			bill.Merchant,
+			bill.Description,
+			strings.Join(bill.Tags, ";"),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		c.Error(err)
+		return
+	}
+	
+	filename := fmt.Sprintf("bills-%s.csv", time.Now().Format("20060102"))
+	c.Ctx.Output.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if result.HasMore {
+		c.Ctx.Output.Header("X-Export-Truncated", "true")
+	}
+	c.Ctx.Output.Body(buf.Bytes())
+}
+
 // MonthlyStats 获取月度统计
 // @Title 获取月度统计
 // @Description 获取指定月份的账单统计数据
-// @Param year query int true "年份"
-// @Param month query int true "月份 (1-12)"
+// @Param year query int false "年份，默认为用户时区下的当前年份"
+// @Param month query int false "月份 (1-12)，默认为用户时区下的当前月份"
 // @Success 200 {object} map[string]interface{} 月度统计数据
 // @Failure 400 参数错误
 // @Failure 401 未授权
@@ -215,22 +413,38 @@ func (c *BillController) MonthlyStats() {
 	yearStr := c.Ctx.Input.Query("year")
 	monthStr := c.Ctx.Input.Query("month")
 	
+	// 未指定时使用用户时区下的当前年月
+	if yearStr == "" || monthStr == "" {
+		loc, err := models.UserLocation(c.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		now := time.Now().In(loc)
+		if yearStr == "" {
+			yearStr = strconv.Itoa(now.Year())
+		}
+		if monthStr == "" {
+			monthStr = strconv.Itoa(int(now.Month()))
+		}
+	}
+	
 	year, err := strconv.Atoi(yearStr)
 	if err != nil || year < 1900 || year > 2100 {
-		c.Error(http.StatusBadRequest, "年份格式错误或超出范围")
+		c.ErrorWithCode(http.StatusBadRequest, "year_out_of_range")
 		return
 	}
 	
 	month, err := strconv.Atoi(monthStr)
 	if err != nil || month < 1 || month > 12 {
-		c.Error(http.StatusBadRequest, "月份格式错误或超出范围")
+		c.ErrorWithCode(http.StatusBadRequest, "month_out_of_range")
 		return
 	}
 	
 	// 获取统计数据
-	stats, err := models.GetMonthlyStats(userID, year, month)
+	stats, err := models.GetMonthlyStats(c.Context(), userID, year, month)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"blog/models"
+	"blog/notify"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -13,8 +15,9 @@ type BudgetController struct {
 
 // List 获取预算列表
 // @Title 获取预算列表
-// @Description 获取指定月份的预算列表
-// @Param month query string true "月份，格式：YYYY-MM"
+// @Description 获取周期与指定月份重叠的预算列表，包括跨月的周预算和季度、年度、自定义周期预算
+// @Param month query string false "月份，格式：YYYY-MM，默认为用户时区下的当前月份"
+// @Param period query string false "周期类型：weekly、monthly、quarterly、yearly、custom，为空时返回所有类型"
 // @Success 200 {array} models.Budget 预算列表
 // @Failure 400 参数错误
 // @Failure 401 未授权
@@ -25,22 +28,36 @@ func (c *BudgetController) List() {
 	
 	month := c.Ctx.Input.Query("month")
 	if month == "" {
-		// 默认使用当前月份
-		month = time.Now().Format("2006-01")
+		// 默认使用用户时区下的当前月份
+		loc, err := models.UserLocation(c.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		month = models.CurrentMonth(loc)
 	}
 	
 	// 验证月份格式
 	_, err := time.Parse("2006-01", month)
 	if err != nil {
-		c.Error(http.StatusBadRequest, "月份格式错误，正确格式为：YYYY-MM")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_month")
+		return
+	}
+	
+	period := c.Ctx.Input.Query("period")
+	if period != "" && !models.IsBudgetPeriod(period) {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_budget_period")
 		return
 	}
 	
-	budgets, err := models.GetBudgets(userID, month)
+	budgets, err := models.GetBudgets(c.Context(), userID, month)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
+	if period != "" {
+		budgets = models.FilterBudgets(budgets, period)
+	}
 	
 	c.Success(budgets)
 }
@@ -51,7 +68,9 @@ func (c *BudgetController) List() {
 // @Param body body models.BudgetRequest true "预算信息"
 // @Success 200 {object} models.Budget 创建的预算
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
+// @Failure 409 当月已有相同预算
 // @Failure 500 服务器内部错误
 // @Router /api/budgets [post]
 func (c *BudgetController) Create() {
@@ -62,15 +81,48 @@ func (c *BudgetController) Create() {
 		return
 	}
 	
-	budget, err := models.CreateBudget(userID, &req)
+	budget, err := models.CreateBudget(c.Context(), userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventBudgetCreated, userID, budget))
+	
 	c.Success(budget)
 }
 
+// Copy 复制预算
+// @Description 把源月份(默认为目标月份的上一个月)的预算复制到目标月份，可按比例或按源预算调整金额，目标月份已有的同类预算跳过，调整后金额无效的预算不复制；所有预算在同一事务中创建
+// @Description 把源月份(默认为目标月份的上一个月)的预算复制到目标月份，可按比例或按分类调整金额，目标月份已有的同类预算跳过
+// @Param body body models.BudgetCopyRequest true "复制参数"
+// @Success 200 {object} models.BudgetCopyResult 复制结果
+// @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/budgets/copy [post]
+func (c *BudgetController) Copy() {
+	userID := c.GetUserID()
+	
+	var req models.BudgetCopyRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	
+	result, err := models.CopyBudgets(c.Context(), userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	for _, budget := range result.Created {
+		notify.Publish(notify.NewEvent(notify.EventBudgetCreated, userID, budget))
+	}
+	
+	c.Success(result)
+}
+
 // Get 获取单个预算
 // @Title 获取预算详情
 // @Description 获取单个预算的详细信息
@@ -86,13 +138,13 @@ func (c *BudgetController) Get() {
 	
 	budgetID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "预算ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_budget_id")
 		return
 	}
 	
-	budget, err := models.GetBudget(budgetID, userID)
+	budget, err := models.GetBudget(c.Context(), budgetID, userID)
 	if err != nil {
-		c.Error(http.StatusNotFound, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -106,8 +158,10 @@ func (c *BudgetController) Get() {
 // @Param body body models.BudgetRequest true "预算信息"
 // @Success 200 {object} models.Budget 更新后的预算
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
 // @Failure 404 预算不存在
+// @Failure 409 当月已有相同预算
 // @Failure 500 服务器内部错误
 // @Router /api/budgets/{id} [put]
 func (c *BudgetController) Update() {
@@ -115,7 +169,7 @@ func (c *BudgetController) Update() {
 	
 	budgetID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "预算ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_budget_id")
 		return
 	}
 	
@@ -124,12 +178,17 @@ func (c *BudgetController) Update() {
 		return
 	}
 	
-	budget, err := models.UpdateBudget(budgetID, userID, &req)
+	budget, err := models.UpdateBudget(c.Context(), budgetID, userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventBudgetUpdated, userID, budget))
+	
+	// 调低预算金额可能使已有告警达到阈值
+	notify.CheckBudgetAlerts(c.Context(), userID, budget.Month.Format("2006-01"))
+	
 	c.Success(budget)
 }
 
@@ -148,16 +207,18 @@ func (c *BudgetController) Delete() {
 	
 	budgetID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "预算ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_budget_id")
 		return
 	}
 	
-	err = models.DeleteBudget(budgetID, userID)
+	err = models.DeleteBudget(c.Context(), budgetID, userID)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventBudgetDeleted, userID, map[string]uint{"id": budgetID}))
+	
 	c.Success(nil)
 }
 
@@ -167,7 +228,9 @@ func (c *BudgetController) Delete() {
 // @Param body body models.BudgetAlertRequest true "预算告警信息"
 // @Success 200 {object} models.BudgetAlert 创建的预算告警
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
+// @Failure 409 已存在相同阈值的告警
 // @Failure 500 服务器内部错误
 // @Router /api/budget-alerts [post]
 func (c *BudgetController) CreateAlert() {
@@ -178,12 +241,15 @@ func (c *BudgetController) CreateAlert() {
 		return
 	}
 	
-	alert, err := models.CreateBudgetAlert(userID, &req)
+	alert, err := models.CreateBudgetAlert(c.Context(), userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventAlertCreated, userID, alert))
+	notify.CheckBudget(c.Context(), userID, alert.BudgetID)
+	
 	c.Success(alert)
 }
 
@@ -208,9 +274,9 @@ func (c *BudgetController) ListAlerts() {
 		}
 	}
 	
-	alerts, err := models.GetBudgetAlerts(userID, budgetID)
+	alerts, err := models.GetBudgetAlerts(c.Context(), userID, budgetID)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -224,8 +290,10 @@ func (c *BudgetController) ListAlerts() {
 // @Param body body models.BudgetAlertRequest true "预算告警信息"
 // @Success 200 {object} models.BudgetAlert 更新后的预算告警
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
 // @Failure 404 预算告警不存在
+// @Failure 409 已存在相同阈值的告警
 // @Failure 500 服务器内部错误
 // @Router /api/budget-alerts/{id} [put]
 func (c *BudgetController) UpdateAlert() {
@@ -233,7 +301,7 @@ func (c *BudgetController) UpdateAlert() {
 	
 	alertID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "预算告警ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_alert_id")
 		return
 	}
 	
@@ -242,12 +310,15 @@ func (c *BudgetController) UpdateAlert() {
 		return
 	}
 	
-	alert, err := models.UpdateBudgetAlert(alertID, userID, &req)
+	alert, err := models.UpdateBudgetAlert(c.Context(), alertID, userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventAlertUpdated, userID, alert))
+	notify.CheckBudget(c.Context(), userID, alert.BudgetID)
+	
 	c.Success(alert)
 }
 
@@ -266,16 +337,18 @@ func (c *BudgetController) DeleteAlert() {
 	
 	alertID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "预算告警ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_alert_id")
 		return
 	}
 	
-	err = models.DeleteBudgetAlert(alertID, userID)
+	err = models.DeleteBudgetAlert(c.Context(), alertID, userID)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	notify.Publish(notify.NewEvent(notify.EventAlertDeleted, userID, map[string]uint{"id": alertID}))
+	
 	c.Success(nil)
 }
 
@@ -289,11 +362,46 @@ func (c *BudgetController) DeleteAlert() {
 func (c *BudgetController) CheckAlerts() {
 	userID := c.GetUserID()
 	
-	alerts, err := models.CheckBudgetAlerts(userID)
+	alerts, err := models.CheckBudgetAlerts(c.Context(), userID)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
 	
 	c.Success(alerts)
-} 
\ No newline at end of file
+} 
+
+// AlertEvents 获取告警触发记录
+// @Title 获取告警触发记录
+// @Description 获取预算告警的触发记录，每个告警的每个阈值每月只在首次达到时记录并通知一次
+// @Param month query string false "月份，格式：YYYY-MM，为空时返回最近的记录"
+// @Param limit query int false "返回条数，默认20，最大100"
+// @Success 200 {array} models.BudgetAlertEvent 触发记录
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/budget-alerts/events [get]
+func (c *BudgetController) AlertEvents() {
+	userID := c.GetUserID()
+	
+	month := c.Ctx.Input.Query("month")
+	if month != "" {
+		if _, err := time.Parse("2006-01", month); err != nil {
+			c.ErrorWithCode(http.StatusBadRequest, "invalid_month")
+			return
+		}
+	}
+	
+	limit, _ := strconv.Atoi(c.Ctx.Input.Query("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	
+	events, err := models.GetBudgetAlertEvents(c.Context(), userID, month, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(events)
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"blog/models"
+	"blog/notify"
+	"net/http"
+)
+
+// BudgetTemplateController 预算模板控制器
+type BudgetTemplateController struct {
+	BaseController
+}
+
+// List 获取预算模板列表
+// @Title 获取预算模板列表
+// @Description 获取当前用户的预算模板
+// @Success 200 {array} models.BudgetTemplate 预算模板列表
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/budget-templates [get]
+func (c *BudgetTemplateController) List() {
+	userID := c.GetUserID()
+	
+	templates, err := models.GetBudgetTemplates(c.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(templates)
+}
+
+// Create 创建预算模板
+// @Title 创建预算模板
+// @Description 创建预算模板，在开始月份到结束月份之间的每个月自动生成预算；模板覆盖当前月份时立即生成当月预算
+// @Param body body models.BudgetTemplateRequest true "预算模板信息"
+// @Success 200 {object} models.BudgetTemplate 创建的预算模板
+// @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 409 同一分类已有时间范围重叠的模板
+// @Failure 500 服务器内部错误
+// @Router /api/budget-templates [post]
+func (c *BudgetTemplateController) Create() {
+	userID := c.GetUserID()
+	
+	var req models.BudgetTemplateRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	
+	template, err := models.CreateBudgetTemplate(c.Context(), userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	// 当月已有同类预算时不生成
+	budget, err := models.GenerateTemplateBudget(c.Context(), template)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if budget != nil {
+		notify.Publish(notify.NewEvent(notify.EventBudgetCreated, userID, budget))
+	}
+	
+	c.Success(template)
+}
+
+// Update 更新预算模板
+// @Title 更新预算模板
+// @Description 更新预算模板，只影响之后生成的预算
+// @Param id path int true "预算模板ID"
+// @Param body body models.BudgetTemplateRequest true "预算模板信息"
+// @Success 200 {object} models.BudgetTemplate 更新后的预算模板
+// @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 404 预算模板不存在
+// @Failure 409 同一分类已有时间范围重叠的模板
+// @Failure 500 服务器内部错误
+// @Router /api/budget-templates/{id} [put]
+func (c *BudgetTemplateController) Update() {
+	userID := c.GetUserID()
+	
+	templateID, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_template_id")
+		return
+	}
+	
+	var req models.BudgetTemplateRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	
+	template, err := models.UpdateBudgetTemplate(c.Context(), templateID, userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(template)
+}
+
+// Delete 删除预算模板
+// @Title 删除预算模板
+// @Description 删除预算模板，已生成的预算保留
+// @Param id path int true "预算模板ID"
+// @Success 200 {object} Response 删除成功
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 预算模板不存在
+// @Failure 500 服务器内部错误
+// @Router /api/budget-templates/{id} [delete]
+func (c *BudgetTemplateController) Delete() {
+	userID := c.GetUserID()
+	
+	templateID, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_template_id")
+		return
+	}
+	
+	if err := models.DeleteBudgetTemplate(c.Context(), templateID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+}
@@ -22,9 +22,9 @@ func (c *CategoryController) List() {
 	userID := c.GetUserID()
 	categoryType := c.Ctx.Input.Query("type")
 	
-	categories, err := models.GetCategories(userID, categoryType)
+	categories, err := models.GetCategories(c.Context(), userID, categoryType)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -37,7 +37,9 @@ func (c *CategoryController) List() {
 // @Param body body models.CategoryRequest true "分类信息"
 // @Success 200 {object} models.Category 创建的分类
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
+// @Failure 409 已存在同名同类型的分类
 // @Failure 500 服务器内部错误
 // @Router /api/categories [post]
 func (c *CategoryController) Create() {
@@ -48,9 +50,9 @@ func (c *CategoryController) Create() {
 		return
 	}
 	
-	category, err := models.CreateCategory(userID, &req)
+	category, err := models.CreateCategory(c.Context(), userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -72,13 +74,13 @@ func (c *CategoryController) Get() {
 	
 	categoryID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "分类ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_category_id")
 		return
 	}
 	
-	category, err := models.GetCategory(categoryID, userID)
+	category, err := models.GetCategory(c.Context(), categoryID, userID)
 	if err != nil {
-		c.Error(http.StatusNotFound, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -92,8 +94,10 @@ func (c *CategoryController) Get() {
 // @Param body body models.CategoryRequest true "分类信息"
 // @Success 200 {object} models.Category 更新后的分类
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
 // @Failure 404 分类不存在
+// @Failure 409 已存在同名同类型的分类
 // @Failure 500 服务器内部错误
 // @Router /api/categories/{id} [put]
 func (c *CategoryController) Update() {
@@ -101,7 +105,7 @@ func (c *CategoryController) Update() {
 	
 	categoryID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "分类ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_category_id")
 		return
 	}
 	
@@ -110,9 +114,9 @@ func (c *CategoryController) Update() {
 		return
 	}
 	
-	category, err := models.UpdateCategory(categoryID, userID, &req)
+	category, err := models.UpdateCategory(c.Context(), categoryID, userID, &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -127,6 +131,7 @@ func (c *CategoryController) Update() {
 // @Failure 400 参数错误
 // @Failure 401 未授权
 // @Failure 404 分类不存在
+// @Failure 409 分类已被账单或预算使用
 // @Failure 500 服务器内部错误
 // @Router /api/categories/{id} [delete]
 func (c *CategoryController) Delete() {
@@ -134,13 +139,13 @@ func (c *CategoryController) Delete() {
 	
 	categoryID, err := c.GetUintParam("id")
 	if err != nil {
-		c.Error(http.StatusBadRequest, "分类ID格式错误")
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_category_id")
 		return
 	}
 	
-	err = models.DeleteCategory(categoryID, userID)
+	err = models.DeleteCategory(c.Context(), categoryID, userID)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"blog/models"
+	"net/http"
+	"strconv"
+)
+
+// DigestController 报告邮件订阅控制器
+type DigestController struct {
+	BaseController
+}
+
+// List 获取订阅列表
+// @Title 获取报告邮件订阅
+// @Description 获取当前用户订阅的周报、月报邮件
+// @Success 200 {array} models.DigestSubscription 订阅列表
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/digests [get]
+func (c *DigestController) List() {
+	userID := c.GetUserID()
+	
+	subscriptions, err := models.GetDigestSubscriptions(c.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(subscriptions)
+}
+
+// Create 订阅报告邮件
+// @Title 订阅报告邮件
+// @Description 订阅周报(weekly)或月报(monthly)，在用户时区下每周一或每月1日发送上一周期的收支汇总、分类排行和预算执行情况
+// @Param body body models.DigestRequest true "发送频率"
+// @Success 200 {object} models.DigestSubscription 创建的订阅
+// @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 409 已订阅该频率
+// @Failure 500 服务器内部错误
+// @Router /api/digests [post]
+func (c *DigestController) Create() {
+	userID := c.GetUserID()
+	
+	var req models.DigestRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	
+	subscription, err := models.CreateDigestSubscription(c.Context(), userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(subscription)
+}
+
+// Delete 取消订阅
+// @Title 取消订阅
+// @Description 取消报告邮件订阅，发送记录会保留
+// @Param id path int true "订阅ID"
+// @Success 200 {object} Response 取消成功
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 订阅不存在
+// @Failure 500 服务器内部错误
+// @Router /api/digests/{id} [delete]
+func (c *DigestController) Delete() {
+	userID := c.GetUserID()
+	
+	id, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_digest_id")
+		return
+	}
+	
+	if err := models.DeleteDigestSubscription(c.Context(), id, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+}
+
+// History 获取发送记录
+// @Title 获取报告邮件发送记录
+// @Description 获取当前用户最近的报告邮件发送记录，包括失败原因
+// @Param limit query int false "返回条数，默认20，最大100"
+// @Success 200 {array} models.DigestDelivery 发送记录
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/digests/history [get]
+func (c *DigestController) History() {
+	userID := c.GetUserID()
+	
+	limit, _ := strconv.Atoi(c.Ctx.Input.Query("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	
+	deliveries, err := models.GetDigestDeliveries(c.Context(), userID, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(deliveries)
+}
+
+// UnsubscribePage 退订确认页面
+// @Title 退订确认页面
+// @Description 报告邮件中的退订链接打开的确认页面，无需登录。GET请求只显示确认按钮而不退订，避免邮件安全扫描和链接预取误退订
+// @Param token query string true "退订令牌"
+// @Success 200 {string} string HTML确认页面
+// @Router /api/digests/unsubscribe [get]
+func (c *DigestController) UnsubscribePage() {
+	token := c.Ctx.Input.Query("token")
+	if token == "" {
+		c.Ctx.Output.SetStatus(http.StatusBadRequest)
+		c.renderUnsubscribe(token, false, models.ErrUnsubscribeTokenInvalid.Localize(c.Lang()))
+		return
+	}
+	
+	c.renderUnsubscribe(token, false, "")
+}
+
+// Unsubscribe 退订报告邮件
+// @Title 退订报告邮件
+// @Description 通过退订令牌取消订阅，无需登录。支持RFC 8058一键退订（邮件客户端POST List-Unsubscribe=One-Click）；浏览器提交确认页面的表单时返回HTML页面，其他请求返回JSON
+// @Param token query string true "退订令牌，也可以放在表单中"
+// @Success 200 {object} Response 退订成功
+// @Failure 400 令牌无效
+// @Router /api/digests/unsubscribe [post]
+func (c *DigestController) Unsubscribe() {
+	token := c.Ctx.Input.Query("token")
+	
+	err := models.UnsubscribeDigest(c.Context(), token)
+	if c.Ctx.Input.AcceptsHTML() && (err == nil || err == models.ErrUnsubscribeTokenInvalid) {
+		if err != nil {
+			c.Ctx.Output.SetStatus(http.StatusBadRequest)
+			c.renderUnsubscribe(token, false, models.ErrUnsubscribeTokenInvalid.Localize(c.Lang()))
+			return
+		}
+		c.renderUnsubscribe(token, true, "")
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+}
+
+// renderUnsubscribe 渲染退订页面：确认表单、退订成功或错误信息
+func (c *DigestController) renderUnsubscribe(token string, done bool, message string) {
+	c.TplName = "unsubscribe.tpl"
+	c.Data["Lang"] = c.Lang()
+	c.Data["Token"] = token
+	c.Data["Done"] = done
+	c.Data["Error"] = message
+	
+	body, err := c.RenderBytes()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+	c.Ctx.Output.Header("Cache-Control", "no-store")
+	c.Ctx.Output.Body(body)
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"blog/models"
+	"blog/realtime"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// heartbeatInterval 事件流的心跳间隔，防止代理因空闲断开连接
+const heartbeatInterval = 15 * time.Second
+
+// EventController 实时事件控制器
+type EventController struct {
+	BaseController
+}
+
+// Ticket 获取事件流票据
+// @Title 获取事件流票据
+// @Description 获取建立实时事件流使用的一次性票据，30秒内有效，供无法设置请求头的EventSource通过ticket查询参数认证
+// @Success 200 {object} models.StreamTicket 事件流票据
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/events/ticket [post]
+func (c *EventController) Ticket() {
+	userID := c.GetUserID()
+	
+	ticket, err := models.CreateStreamTicket(c.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(ticket)
+}
+
+// Stream 订阅实时事件
+// @Title 订阅实时事件
+// @Description 以Server-Sent Events推送当前用户的账单、预算和告警变更，事件名为事件类型（如bill.created），数据为JSON。浏览器的EventSource无法设置请求头，可先通过POST /api/events/ticket获取一次性票据，再以ticket查询参数连接
+// @Param ticket query string false "一次性事件流票据，未设置Authorization请求头时使用"
+// @Success 200 {string} string text/event-stream事件流
+// @Failure 401 未授权
+// @Failure 429 连接数过多
+// @Router /api/events [get]
+func (c *EventController) Stream() {
+	userID := c.GetUserID()
+	
+	sub, err := realtime.Default.Subscribe(userID)
+	if err != nil {
+		c.ErrorWithCode(http.StatusTooManyRequests, "too_many_connections")
+		return
+	}
+	defer sub.Close()
+	
+	c.EnableRender = false
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("retry: 5000\n\n"))
+	w.Flush()
+	
+	// 事件流不受请求超时限制，只在客户端断开时结束
+	done := c.Ctx.Request.Context().Done()
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	
+	var id uint64
+	for {
+		select {
+		case <-done:
+			return
+		case <-heartbeat.C:
+			if _, err := w.Write([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		case msg, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			id++
+			if err := realtime.WriteEvent(w, id, msg); err != nil {
+				logs.Error("Error writing event to user %d: %v", userID, err)
+				return
+			}
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"blog/models"
+	"net/http"
+	"strconv"
+)
+
+// NotificationController 站内通知控制器
+type NotificationController struct {
+	BaseController
+}
+
+// List 获取站内通知
+// @Title 获取站内通知
+// @Description 按时间倒序获取站内通知，使用游标分页，同时返回未读数
+// @Param unread query bool false "只返回未读通知"
+// @Param type query string false "通知类型，如budget.threshold_crossed"
+// @Param cursor query string false "上一页返回的next_cursor"
+// @Param page_size query int false "每页数量，默认10，最大100"
+// @Success 200 {object} map[string]interface{} 通知列表、分页信息和未读数
+// @Failure 400 游标无效
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/notifications [get]
+func (c *NotificationController) List() {
+	userID := c.GetUserID()
+	_, pageSize := c.GetPagination()
+	
+	unread, _ := strconv.ParseBool(c.Ctx.Input.Query("unread"))
+	
+	page, err := models.GetNotifications(c.Context(), userID, &models.NotificationQuery{
+		Unread:   unread,
+		Type:     c.Ctx.Input.Query("type"),
+		Cursor:   c.Ctx.Input.Query("cursor"),
+		PageSize: pageSize,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(map[string]interface{}{
+		"items": page.Notifications,
+		"pagination": Pagination{
+			PageSize:   pageSize,
+			HasMore:    page.HasMore,
+			NextCursor: page.NextCursor,
+		},
+		"unread_count": page.UnreadCount,
+	})
+}
+
+// UnreadCount 获取未读通知数
+// @Title 获取未读通知数
+// @Description 获取当前用户的未读通知数，用于显示角标
+// @Success 200 {object} map[string]int 未读数
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/notifications/unread-count [get]
+func (c *NotificationController) UnreadCount() {
+	userID := c.GetUserID()
+	
+	count, err := models.CountUnreadNotifications(c.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(map[string]int{"unread_count": count})
+}
+
+// Read 标记通知为已读
+// @Title 标记通知为已读
+// @Description 标记单条通知为已读
+// @Param id path int true "通知ID"
+// @Success 200 {object} Response 标记成功
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 通知不存在
+// @Failure 500 服务器内部错误
+// @Router /api/notifications/{id}/read [put]
+func (c *NotificationController) Read() {
+	userID := c.GetUserID()
+	
+	id, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_notification_id")
+		return
+	}
+	
+	if err := models.MarkNotificationRead(c.Context(), id, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+}
+
+// ReadAll 标记所有通知为已读
+// @Title 全部标记为已读
+// @Description 标记当前用户的所有未读通知为已读
+// @Success 200 {object} map[string]int64 标记的数量
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/notifications/read-all [post]
+func (c *NotificationController) ReadAll() {
+	userID := c.GetUserID()
+	
+	marked, err := models.MarkAllNotificationsRead(c.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(map[string]int64{"marked": marked})
+}
+
+// Delete 删除通知
+// @Title 删除通知
+// @Description 删除单条通知
+// @Param id path int true "通知ID"
+// @Success 200 {object} Response 删除成功
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 通知不存在
+// @Failure 500 服务器内部错误
+// @Router /api/notifications/{id} [delete]
+func (c *NotificationController) Delete() {
+	userID := c.GetUserID()
+	
+	id, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_notification_id")
+		return
+	}
+	
+	if err := models.DeleteNotification(c.Context(), id, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+}
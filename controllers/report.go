@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"blog/models"
+	"net/http"
+)
+
+// ReportController 报表控制器
+type ReportController struct {
+	BaseController
+}
+
+// Get 获取报表
+// @Title 获取报表
+// @Description 统计任意周期或自定义范围的收支合计、分类汇总和时间序列，并与上一周期及去年同期对比
+// @Param period query string false "周期：week、month、quarter、year，默认month；不能与start_date、end_date同时使用"
+// @Param date query string false "周期内的任意一天，格式：YYYY-MM-DD，默认为用户时区下的今天"
+// @Param start_date query string false "自定义范围开始日期，格式：YYYY-MM-DD"
+// @Param end_date query string false "自定义范围结束日期，格式：YYYY-MM-DD"
+// @Param bucket query string false "时间序列分桶：day、week、month，默认按范围长度选择"
+// @Success 200 {object} models.Report 报表
+// @Failure 401 未授权
+// @Failure 422 请求参数校验失败
+// @Failure 500 服务器内部错误
+// @Router /api/reports [get]
+func (c *ReportController) Get() {
+	userID := c.GetUserID()
+	
+	params := &models.ReportParams{
+		Period:    c.Ctx.Input.Query("period"),
+		Date:      c.Ctx.Input.Query("date"),
+		StartDate: c.Ctx.Input.Query("start_date"),
+		EndDate:   c.Ctx.Input.Query("end_date"),
+		Bucket:    c.Ctx.Input.Query("bucket"),
+	}
+	if err := c.Validate(params); err != nil {
+		return
+	}
+	
+	report, err := models.GetReport(c.Context(), userID, params)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(report)
+}
+
+// Trend 获取收支趋势
+// @Title 获取收支趋势
+// @Description 获取连续多个月的收支和各分类的逐月合计，用于趋势图
+// @Param months query int false "月数，1-60，默认12"
+// @Param end_month query string false "最后一个月，格式：YYYY-MM，默认为用户时区下的本月"
+// @Param type query string false "分类序列的账单类型：income/expense，默认expense"
+// @Success 200 {object} models.Trend 收支趋势
+// @Failure 401 未授权
+// @Failure 422 请求参数校验失败
+// @Failure 500 服务器内部错误
+// @Router /api/reports/trend [get]
+func (c *ReportController) Trend() {
+	userID := c.GetUserID()
+	
+	months, err := c.GetInt("months", models.DefaultTrendMonths)
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_months")
+		return
+	}
+	
+	params := &models.TrendParams{
+		Months:   months,
+		EndMonth: c.Ctx.Input.Query("end_month"),
+		Type:     c.Ctx.Input.Query("type"),
+	}
+	if err := c.Validate(params); err != nil {
+		return
+	}
+	
+	trend, err := models.GetTrend(c.Context(), userID, params)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(trend)
+}
+
+// CategoryShare 获取分类占比
+// @Title 获取分类占比
+// @Description 获取范围内各分类的金额占比，前top个分类单独列出，其余合并为"其他"，统计范围参数与报表相同
+// @Param period query string false "周期：week、month、quarter、year，默认month"
+// @Param date query string false "周期内的任意一天，格式：YYYY-MM-DD，默认为用户时区下的今天"
+// @Param start_date query string false "自定义范围开始日期，格式：YYYY-MM-DD"
+// @Param end_date query string false "自定义范围结束日期，格式：YYYY-MM-DD"
+// @Param type query string false "账单类型：income/expense，默认expense"
+// @Param top query int false "单独列出的分类数，1-20，默认5"
+// @Success 200 {object} models.CategoryShare 分类占比
+// @Failure 401 未授权
+// @Failure 422 请求参数校验失败
+// @Failure 500 服务器内部错误
+// @Router /api/reports/category-share [get]
+func (c *ReportController) CategoryShare() {
+	userID := c.GetUserID()
+	
+	top, err := c.GetInt("top", models.DefaultShareTop)
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_top")
+		return
+	}
+	
+	rangeParams := &models.ReportParams{
+		Period:    c.Ctx.Input.Query("period"),
+		Date:      c.Ctx.Input.Query("date"),
+		StartDate: c.Ctx.Input.Query("start_date"),
+		EndDate:   c.Ctx.Input.Query("end_date"),
+	}
+	if err := c.Validate(rangeParams); err != nil {
+		return
+	}
+	
+	params := &models.ShareParams{
+		Type: c.Ctx.Input.Query("type"),
+		Top:  top,
+	}
+	if err := c.Validate(params); err != nil {
+		return
+	}
+	
+	share, err := models.GetCategoryShare(c.Context(), userID, rangeParams, params)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	// "其他"按请求语言命名
+	for _, item := range share.Items {
+		if item.Other {
+			item.Name = c.T("share_other")
+		}
+	}
+	
+	c.Success(share)
+}
@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"blog/i18n"
+	"blog/models"
+	"blog/pdf"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+func init() {
+	// 模板中按语言渲染文本：{{t .Lang "code"}}
+	web.AddFuncMap("t", i18n.T)
+}
+
+// statementView 对账单模板数据
+type statementView struct {
+	*models.Statement
+	Lang           string
+	Expenses       []*models.ReportCategory // 支出分类
+	Daily          []*statementBar
+	PreviousChange string // 支出较上月变化
+	LastYearChange string // 支出较去年同月变化
+}
+
+// statementBar 每日支出柱状图的一根柱子，Height为相对当月最大值的百分比
+type statementBar struct {
+	Date    string
+	Expense models.Money
+	Height  float64
+}
+
+// newStatementView 由对账单数据生成模板数据
+func newStatementView(s *models.Statement, lang string) *statementView {
+	view := &statementView{
+		Statement:      s,
+		Lang:           lang,
+		Expenses:       make([]*models.ReportCategory, 0),
+		PreviousChange: models.FormatChange(s.Report.Previous.ExpenseChange),
+		LastYearChange: models.FormatChange(s.Report.LastYear.ExpenseChange),
+	}
+	for _, category := range s.Report.Categories {
+		if category.Type == "expense" {
+			view.Expenses = append(view.Expenses, category)
+		}
+	}
+
+	var max models.Money
+	for _, bucket := range s.Report.Series {
+		if bucket.Expense > max {
+			max = bucket.Expense
+		}
+	}
+	for _, bucket := range s.Report.Series {
+		view.Daily = append(view.Daily, &statementBar{
+			Date:    bucket.StartDate,
+			Expense: bucket.Expense,
+			Height:  bucket.Expense.Percent(max),
+		})
+	}
+	return view
+}
+
+// Statement 获取月度对账单
+// @Title 获取月度对账单
+// @Description 生成可打印的月度对账单，包括收支合计、分类明细与每日支出图、预算执行情况和最大支出
+// @Param month query string false "月份，格式：YYYY-MM，默认为用户时区下的本月"
+// @Param format query string false "输出格式：html、pdf，默认html"
+// @Success 200 {file} HTML或PDF文件
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/reports/statement [get]
+func (c *ReportController) Statement() {
+	userID := c.GetUserID()
+
+	format := c.Ctx.Input.Query("format")
+	if format == "" {
+		format = "html"
+	}
+	if format != "html" && format != "pdf" {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_format")
+		return
+	}
+
+	month := c.Ctx.Input.Query("month")
+	if month == "" {
+		loc, err := models.UserLocation(c.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		month = models.CurrentMonth(loc)
+	}
+
+	statement, err := models.GetStatement(c.Context(), userID, month)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	view := newStatementView(statement, c.Lang())
+	filename := fmt.Sprintf("statement-%s.%s", month, format)
+
+	if format == "pdf" {
+		c.Ctx.Output.Header("Content-Type", "application/pdf")
+		c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Ctx.Output.Body(renderStatementPDF(view))
+		return
+	}
+
+	c.TplName = "statement.tpl"
+	c.Data["Lang"] = view.Lang
+	for key, value := range map[string]interface{}{
+		"Statement":      view.Statement,
+		"User":           view.User,
+		"Month":          view.Month,
+		"Report":         view.Report,
+		"Budgets":        view.Budgets,
+		"TopBills":       view.TopBills,
+		"GeneratedAt":    view.GeneratedAt,
+		"Expenses":       view.Expenses,
+		"Daily":          view.Daily,
+		"PreviousChange": view.PreviousChange,
+		"LastYearChange": view.LastYearChange,
+	} {
+		c.Data[key] = value
+	}
+	body, err := c.RenderBytes()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Ctx.Output.Body(body)
+}
+
+// statementLayout PDF对账单的版面，内容超出页面时自动换页
+type statementLayout struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+// 版面尺寸
+const (
+	pdfMargin  = 48.0
+	pdfContent = pdf.PageWidth - 2*pdfMargin
+	pdfRow     = 18.0
+)
+
+// ensure 剩余空间不足height时换页
+func (l *statementLayout) ensure(height float64) {
+	if l.page == nil || l.y+height > pdf.PageHeight-pdfMargin {
+		l.page = l.doc.AddPage()
+		l.y = pdfMargin
+	}
+}
+
+// heading 输出小节标题
+func (l *statementLayout) heading(text string) {
+	l.ensure(pdfRow * 3)
+	l.y += pdfRow
+	l.page.Text(pdfMargin, l.y, 13, pdf.Black, text)
+	l.y += 6
+	l.page.Line(pdfMargin, l.y, pdfMargin+pdfContent, l.y, 0.5, pdf.Gray)
+	l.y += pdfRow
+}
+
+// row 输出一行表格，columns为各列右边界相对左边距的位置，首列左对齐其余右对齐
+func (l *statementLayout) row(columns []float64, cells []string, color pdf.Color) {
+	l.ensure(pdfRow)
+	for i, cell := range cells {
+		if i == 0 {
+			l.page.Text(pdfMargin, l.y, 10, color, pdf.Truncate(cell, 10, columns[0]))
+			continue
+		}
+		l.page.TextRight(pdfMargin+columns[i], l.y, 10, color, cell)
+	}
+	l.y += pdfRow
+}
+
+// renderStatementPDF 将对账单绘制为PDF
+func renderStatementPDF(v *statementView) []byte {
+	t := func(code string) string { return i18n.T(v.Lang, code) }
+	l := &statementLayout{doc: pdf.New()}
+	l.ensure(0)
+
+	// 标题
+	l.y += 10
+	l.page.Text(pdfMargin, l.y, 20, pdf.Black, t("statement_title")+" "+v.Month)
+	l.y += pdfRow
+	l.page.Text(pdfMargin, l.y, 9, pdf.Gray, fmt.Sprintf("%s · %s ~ %s · %s %s",
+		v.User.Username, v.Report.StartDate, v.Report.EndDate, t("statement_generated_at"), v.GeneratedAt.Format("2006-01-02 15:04")))
+	l.y += 6
+
+	// 收支合计
+	l.heading(t("statement_summary"))
+	summary := v.Report.Summary
+	cells := [][2]string{
+		{t("statement_income"), summary.Income.String()},
+		{t("statement_expense"), summary.Expense.String()},
+		{t("statement_balance"), summary.Balance.String()},
+		{t("statement_count"), fmt.Sprint(summary.Count)},
+	}
+	boxWidth := (pdfContent - 3*8) / 4
+	for i, cell := range cells {
+		x := pdfMargin + float64(i)*(boxWidth+8)
+		l.page.Rect(x, l.y-12, boxWidth, 44, pdf.LightGray)
+		l.page.Text(x+8, l.y+2, 9, pdf.Gray, cell[0])
+		l.page.Text(x+8, l.y+22, 14, pdf.Black, cell[1])
+	}
+	l.y += 44
+	l.page.Text(pdfMargin, l.y, 9, pdf.Gray, fmt.Sprintf("%s %s · %s %s",
+		t("statement_expense_vs_previous"), v.PreviousChange, t("statement_expense_vs_last_year"), v.LastYearChange))
+	l.y += 6
+
+	// 每日支出柱状图
+	l.heading(t("statement_daily_expense"))
+	const chartHeight = 100.0
+	l.ensure(chartHeight + pdfRow)
+	if n := len(v.Daily); n > 0 {
+		barWidth := pdfContent / float64(n)
+		for i, bar := range v.Daily {
+			height := chartHeight * bar.Height / 100
+			if height > 0 {
+				l.page.Rect(pdfMargin+float64(i)*barWidth+1, l.y+chartHeight-height, barWidth-2, height, pdf.Color{R: 0.29, G: 0.56, B: 0.85})
+			}
+		}
+	}
+	l.y += chartHeight
+	l.page.Line(pdfMargin, l.y, pdfMargin+pdfContent, l.y, 0.5, pdf.Gray)
+	l.y += 12
+	l.page.Text(pdfMargin, l.y, 8, pdf.Gray, v.Report.StartDate)
+	l.page.TextRight(pdfMargin+pdfContent, l.y, 8, pdf.Gray, v.Report.EndDate)
+	l.y += 6
+
+	// 支出分类
+	l.heading(t("statement_categories"))
+	columns := []float64{200, 280, 400, pdfContent}
+	if len(v.Expenses) == 0 {
+		l.row(columns, []string{t("statement_no_data")}, pdf.Gray)
+	} else {
+		l.row(columns, []string{t("statement_category"), t("statement_count"), t("statement_amount"), t("statement_share")}, pdf.Gray)
+		for _, category := range v.Expenses {
+			l.row(columns, []string{category.Name, fmt.Sprint(category.Count), category.Total.String(), fmt.Sprintf("%.2f%%", category.Percent)}, pdf.Black)
+		}
+	}
+
+	// 预算执行
+	l.heading(t("statement_budgets"))
+	if len(v.Budgets) == 0 {
+		l.row(columns, []string{t("statement_no_data")}, pdf.Gray)
+	} else {
+		l.row(columns, []string{t("statement_budget"), t("statement_available"), t("statement_used"), "%"}, pdf.Gray)
+		for _, budget := range v.Budgets {
+			name := budget.DisplayName()
+			if name == "" {
+				name = t("statement_total_budget")
+			}
+			color := pdf.Black
+			if budget.Percentage >= 100 {
+				color = pdf.Color{R: 0.85, G: 0.33, B: 0.31}
+			}
+			l.row(columns, []string{name, budget.Available.String(), budget.UsedAmount.String(), fmt.Sprintf("%.2f%%", budget.Percentage)}, color)
+		}
+	}
+
+	// 最大支出
+	l.heading(t("statement_top_bills"))
+	if len(v.TopBills) == 0 {
+		l.row(columns, []string{t("statement_no_data")}, pdf.Gray)
+	} else {
+		billColumns := []float64{80, 180, 400, pdfContent}
+		l.row(billColumns, []string{t("statement_date"), t("statement_category"), t("statement_description"), t("statement_amount")}, pdf.Gray)
+		for _, bill := range v.TopBills {
+			description := bill.Description
+			if bill.Merchant != "" {
+				description = bill.Merchant + " " + description
+			}
+			l.ensure(pdfRow)
+			l.page.Text(pdfMargin, l.y, 10, pdf.Black, bill.Date.Format("2006-01-02"))
+			l.page.Text(pdfMargin+billColumns[0], l.y, 10, pdf.Black, pdf.Truncate(bill.CategoryName, 10, billColumns[1]-billColumns[0]-8))
+			l.page.Text(pdfMargin+billColumns[1], l.y, 10, pdf.Black, pdf.Truncate(description, 10, billColumns[2]-billColumns[1]-8))
+			l.page.TextRight(pdfMargin+billColumns[3], l.y, 10, pdf.Black, bill.Amount.String())
+			l.y += pdfRow
+		}
+	}
+
+	return l.doc.Bytes()
+}
@@ -1,9 +1,19 @@
 package controllers
 
 import (
+	"blog/i18n"
+	"blog/mail"
 	"blog/middleware"
 	"blog/models"
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
 )
 
 // UserController 用户控制器
@@ -17,6 +27,8 @@ type UserController struct {
 // @Param body body models.RegisterRequest true "用户注册信息"
 // @Success 200 {object} models.User 注册成功
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
+// @Failure 409 用户名或邮箱已被注册
 // @Failure 500 服务器内部错误
 // @Router /api/user/register [post]
 func (c *UserController) Register() {
@@ -25,16 +37,21 @@ func (c *UserController) Register() {
 		return
 	}
 	
-	user, err := models.CreateUser(&req)
+	// 未指定语言时使用请求的语言，默认分类和之后的消息与注册时的界面一致
+	if req.Language == "" {
+		req.Language = c.Lang()
+	}
+	
+	user, err := models.CreateUser(c.Context(), &req)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
 	// 生成JWT令牌
-	token, err := middleware.GenerateToken(user.ID)
+	token, err := middleware.GenerateToken(user.ID, user.Language)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, "生成令牌失败")
+		c.ErrorWithCode(http.StatusInternalServerError, "token_generation_failed")
 		return
 	}
 	
@@ -50,7 +67,9 @@ func (c *UserController) Register() {
 // @Param body body models.LoginRequest true "登录信息"
 // @Success 200 {object} map[string]interface{} 登录成功
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 认证失败
+// @Failure 429 登录尝试过于频繁或账户已锁定
 // @Failure 500 服务器内部错误
 // @Router /api/user/login [post]
 func (c *UserController) Login() {
@@ -59,16 +78,33 @@ func (c *UserController) Login() {
 		return
 	}
 	
-	user, err := models.AuthenticateUser(&req)
+	meta := &models.LoginMeta{
+		IP:        middleware.ClientIP(c.Ctx.Request),
+		UserAgent: c.Ctx.Input.UserAgent(),
+	}
+	
+	user, err := models.AuthenticateUser(c.Context(), &req, meta)
 	if err != nil {
-		c.Error(http.StatusUnauthorized, err.Error())
+		var locked *models.AccountLockedError
+		var throttled *models.LoginThrottledError
+		switch {
+		case errors.As(err, &locked):
+			go sendUnlockEmail(locked.User, locked.UnlockToken, c.Lang())
+			c.loginThrottled(&locked.LoginThrottledError)
+		case errors.As(err, &throttled):
+			c.loginThrottled(throttled)
+		case err == models.ErrInvalidCredentials:
+			c.Error(err)
+		default:
+			c.ErrorWithCode(http.StatusInternalServerError, "login_failed")
+		}
 		return
 	}
 	
 	// 生成JWT令牌
-	token, err := middleware.GenerateToken(user.ID)
+	token, err := middleware.GenerateToken(user.ID, user.Language)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, "生成令牌失败")
+		c.ErrorWithCode(http.StatusInternalServerError, "token_generation_failed")
 		return
 	}
 	
@@ -78,6 +114,81 @@ func (c *UserController) Login() {
 	})
 }
 
+// loginThrottled 登录被限制时返回429并告知重试时间
+func (c *UserController) loginThrottled(err *models.LoginThrottledError) {
+	c.Ctx.Output.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	c.Error(err)
+}
+
+// sendUnlockEmail 发送账户解锁邮件，用户未设置语言时使用登录请求的语言
+func sendUnlockEmail(user *models.User, token, lang string) {
+	if user.Language != "" {
+		lang = user.Language
+	}
+	
+	siteURL, _ := web.AppConfig.String("siteurl")
+	if siteURL == "" {
+		siteURL = "http://localhost:8080"
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	
+	err := mail.Send(ctx, &mail.Message{
+		To:      []string{user.Email},
+		Subject: i18n.T(lang, "unlock_email_subject"),
+		Body: i18n.T(lang, "unlock_email_body",
+			user.Username, int(models.LoginLockout.Minutes()), siteURL, token),
+	})
+	if err != nil {
+		logs.Error("Error sending unlock email to user %d: %v", user.ID, err)
+	}
+}
+
+// Unlock 解锁账户
+// @Title 解锁账户
+// @Description 通过解锁邮件中的令牌解除账户锁定
+// @Param token query string true "解锁令牌"
+// @Success 200 {object} Response 解锁成功
+// @Failure 400 令牌无效
+// @Router /api/user/unlock [get,post]
+func (c *UserController) Unlock() {
+	token := c.Ctx.Input.Query("token")
+	
+	err := models.UnlockAccount(c.Context(), token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+}
+
+// LoginHistory 获取登录记录
+// @Title 获取登录记录
+// @Description 获取当前用户最近的登录尝试记录
+// @Param limit query int false "返回条数，默认20，最大100"
+// @Success 200 {array} models.LoginAttempt 登录记录
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/user/login-history [get]
+func (c *UserController) LoginHistory() {
+	userID := c.GetUserID()
+	
+	limit, _ := strconv.Atoi(c.Ctx.Input.Query("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	
+	attempts, err := models.GetLoginHistory(c.Context(), userID, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(attempts)
+}
+
 // Profile 获取当前用户信息
 // @Title 获取用户信息
 // @Description 获取当前登录用户信息
@@ -88,9 +199,9 @@ func (c *UserController) Login() {
 func (c *UserController) Profile() {
 	userID := c.GetUserID()
 	
-	user, err := models.GetUserByID(userID)
+	user, err := models.GetUserByID(c.Context(), userID)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -101,8 +212,9 @@ func (c *UserController) Profile() {
 // @Title 更新用户信息
 // @Description 更新当前登录用户信息
 // @Param body body models.UserProfileResponse true "用户信息"
-// @Success 200 {object} models.UserProfileResponse 更新后的用户信息
+// @Success 200 {object} models.UserProfileResponse 更新后的用户信息，语言变化时X-Auth-Token响应头返回新令牌
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
 // @Failure 500 服务器内部错误
 // @Router /api/user/profile [put]
@@ -117,19 +229,31 @@ func (c *UserController) UpdateProfile() {
 	// 确保只能更新当前用户
 	profile.ID = userID
 	
-	err := models.UpdateUser(userID, profile.Username, profile.Email, profile.Phone, profile.Avatar)
+	err := models.UpdateUser(c.Context(), userID, &profile)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
 	
 	// 获取更新后的用户信息
-	user, err := models.GetUserByID(userID)
+	user, err := models.GetUserByID(c.Context(), userID)
 	if err != nil {
-		c.Error(http.StatusInternalServerError, err.Error())
+		c.Error(err)
 		return
 	}
 	
+	// 令牌中携带用户语言，语言变化时签发新令牌
+	if lang, _ := c.Ctx.Input.GetData("lang").(string); lang != user.Language {
+		token, err := middleware.GenerateToken(userID, user.Language)
+		if err != nil {
+			c.ErrorWithCode(http.StatusInternalServerError, "token_generation_failed")
+			return
+		}
+		c.Ctx.Output.Header("X-Auth-Token", token)
+		c.Ctx.Input.SetData("lang", user.Language)
+		c.lang = ""
+	}
+	
 	c.Success(user)
 }
 
@@ -139,6 +263,7 @@ func (c *UserController) UpdateProfile() {
 // @Param body body object true "密码信息"
 // @Success 200 {object} Response 修改成功
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 401 未授权
 // @Failure 500 服务器内部错误
 // @Router /api/user/password [put]
@@ -154,9 +279,9 @@ func (c *UserController) ChangePassword() {
 		return
 	}
 	
-	err := models.UpdatePassword(userID, req.OldPassword, req.NewPassword)
+	err := models.UpdatePassword(c.Context(), userID, req.OldPassword, req.NewPassword)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
@@ -169,6 +294,7 @@ func (c *UserController) ChangePassword() {
 // @Param body body object true "邮箱和新密码"
 // @Success 200 {object} Response 重置成功
 // @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
 // @Failure 500 服务器内部错误
 // @Router /api/user/forgot-password [post]
 func (c *UserController) ForgotPassword() {
@@ -183,9 +309,9 @@ func (c *UserController) ForgotPassword() {
 	
 	// 注意：实际应用中应该发送验证码到邮箱，用户验证后才能重置密码
 	// 这里简化处理，直接通过邮箱重置密码
-	err := models.ResetPassword(req.Email, req.NewPassword)
+	err := models.ResetPassword(c.Context(), req.Email, req.NewPassword)
 	if err != nil {
-		c.Error(http.StatusBadRequest, err.Error())
+		c.Error(err)
 		return
 	}
 	
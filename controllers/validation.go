@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"blog/i18n"
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/validation"
+)
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ruleMessages 内置校验规则对应的消息码
+var ruleMessages = map[string]string{
+	"Required": "rule_required",
+	"Min":      "rule_min",
+	"Max":      "rule_max",
+	"Range":    "rule_range",
+	"MinSize":  "rule_min_size",
+	"MaxSize":  "rule_max_size",
+	"Length":   "rule_length",
+	"Match":    "rule_match",
+	"Email":    "rule_email",
+	"Mobile":   "rule_mobile",
+	"Phone":    "rule_phone",
+}
+
+// validateStruct 按valid标签校验结构体，返回按lang渲染的字段级错误
+//
+// 结构体类型的字段会递归校验，错误字段名形如 filter.start_date。
+func validateStruct(v interface{}, lang string) ([]FieldError, error) {
+	valid := validation.Validation{}
+	ok, err := valid.Valid(v)
+	if err != nil {
+		return nil, err
+	}
+
+	names := jsonFieldNames(v)
+	fieldErrors := make([]FieldError, 0, len(valid.Errors))
+	if !ok {
+		for _, e := range valid.Errors {
+			field := e.Field
+			if name, ok := names[field]; ok {
+				field = name
+			}
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   field,
+				Rule:    e.Name,
+				Message: ruleMessage(e, lang),
+			})
+		}
+	}
+
+	nested, err := validateNested(v, names, lang)
+	if err != nil {
+		return nil, err
+	}
+	fieldErrors = append(fieldErrors, nested...)
+
+	if len(fieldErrors) == 0 {
+		return nil, nil
+	}
+	return fieldErrors, nil
+}
+
+// validateNested 校验结构体类型的字段
+func validateNested(v interface{}, names map[string]string, lang string) ([]FieldError, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	fieldErrors := make([]FieldError, 0)
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.PkgPath != "" || f.Anonymous || f.Type.Kind() != reflect.Struct || f.Type == reflect.TypeOf(time.Time{}) {
+			continue
+		}
+
+		// 取地址以便调用指针接收者的Valid方法
+		field := reflect.New(f.Type)
+		field.Elem().Set(rv.Field(i))
+		errs, err := validateStruct(field.Interface(), lang)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := f.Name
+		if name, ok := names[f.Name]; ok {
+			prefix = name
+		}
+		for _, e := range errs {
+			e.Field = prefix + "." + e.Field
+			fieldErrors = append(fieldErrors, e)
+		}
+	}
+	return fieldErrors, nil
+}
+
+// ruleMessage 生成校验错误的提示信息，自定义规则的错误信息本身就是消息码
+func ruleMessage(e *validation.Error, lang string) string {
+	code, ok := ruleMessages[e.Name]
+	if !ok {
+		return i18n.T(lang, strings.TrimSpace(strings.TrimPrefix(e.Message, e.Field)))
+	}
+	switch limit := e.LimitValue.(type) {
+	case nil:
+		return i18n.T(lang, code)
+	case []int:
+		args := make([]interface{}, len(limit))
+		for i, l := range limit {
+			args[i] = l
+		}
+		return i18n.T(lang, code, args...)
+	default:
+		return i18n.T(lang, code, limit)
+	}
+}
+
+// unmarshalFieldError 将JSON字段类型错误转换为字段级错误
+func unmarshalFieldError(body []byte, v interface{}, err error, lang string) (FieldError, bool) {
+	var typeError *json.UnmarshalTypeError
+	if !errors.As(err, &typeError) {
+		return FieldError{}, false
+	}
+
+	// 自定义类型的UnmarshalJSON返回的错误不带字段信息，需要逐个字段重新解析定位
+	field := typeError.Field
+	if field == "" {
+		field = locateInvalidField(body, v)
+	}
+	if field == "" {
+		return FieldError{}, false
+	}
+
+	if typeError.Type == reflect.TypeOf(models.Money(0)) {
+		return FieldError{Field: field, Rule: "Money", Message: i18n.T(lang, "money_invalid")}, true
+	}
+	return FieldError{Field: field, Rule: "Type", Message: i18n.T(lang, "rule_type")}, true
+}
+
+// locateInvalidField 逐个解析顶层字段，返回第一个无法解析的JSON字段名
+func locateInvalidField(body []byte, v interface{}) string {
+	var raw map[string]json.RawMessage
+	if json.Unmarshal(body, &raw) != nil {
+		return ""
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		value, ok := raw[name]
+		if name == "" || !ok {
+			continue
+		}
+		if json.Unmarshal(value, reflect.New(f.Type).Interface()) != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// jsonFieldNames 结构体字段名到JSON字段名的映射
+func jsonFieldNames(v interface{}) map[string]string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := make(map[string]string)
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[f.Name] = name
+		}
+	}
+	return names
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"blog/models"
+	"net/http"
+)
+
+// ViewController 账单视图控制器
+type ViewController struct {
+	BaseController
+}
+
+// List 获取视图列表
+// @Title 获取视图列表
+// @Description 获取当前用户保存的所有账单视图
+// @Success 200 {array} models.SavedView 视图列表
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/views [get]
+func (c *ViewController) List() {
+	userID := c.GetUserID()
+	
+	views, err := models.GetViews(c.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(views)
+}
+
+// Create 创建视图
+// @Title 创建视图
+// @Description 保存账单筛选条件，可通过view_id在账单列表、统计和导出接口中使用；date_range支持today、yesterday、last_7_days、last_30_days、last_90_days、this_week、last_week、this_month、last_month、this_quarter、last_quarter、this_year、last_year
+// @Param body body models.ViewRequest true "视图名称和筛选条件"
+// @Success 200 {object} models.SavedView 创建的视图
+// @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 409 已存在同名视图
+// @Failure 500 服务器内部错误
+// @Router /api/views [post]
+func (c *ViewController) Create() {
+	userID := c.GetUserID()
+	
+	var req models.ViewRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	
+	view, err := models.CreateView(c.Context(), userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(view)
+}
+
+// Get 获取单个视图
+// @Title 获取视图详情
+// @Description 获取单个视图的详细信息
+// @Param id path int true "视图ID"
+// @Success 200 {object} models.SavedView 视图信息
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 视图不存在
+// @Failure 500 服务器内部错误
+// @Router /api/views/{id} [get]
+func (c *ViewController) Get() {
+	userID := c.GetUserID()
+	
+	viewID, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_view_id")
+		return
+	}
+	
+	view, err := models.GetView(c.Context(), viewID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(view)
+}
+
+// Update 更新视图
+// @Title 更新视图
+// @Description 更新视图信息
+// @Param id path int true "视图ID"
+// @Param body body models.ViewRequest true "视图名称和筛选条件"
+// @Success 200 {object} models.SavedView 更新后的视图
+// @Failure 400 参数错误
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 404 视图不存在
+// @Failure 409 已存在同名视图
+// @Failure 500 服务器内部错误
+// @Router /api/views/{id} [put]
+func (c *ViewController) Update() {
+	userID := c.GetUserID()
+	
+	viewID, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_view_id")
+		return
+	}
+	
+	var req models.ViewRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	
+	view, err := models.UpdateView(c.Context(), viewID, userID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(view)
+}
+
+// Delete 删除视图
+// @Title 删除视图
+// @Description 删除视图
+// @Param id path int true "视图ID"
+// @Success 200 {object} Response 删除成功
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 视图不存在
+// @Failure 500 服务器内部错误
+// @Router /api/views/{id} [delete]
+func (c *ViewController) Delete() {
+	userID := c.GetUserID()
+	
+	viewID, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_view_id")
+		return
+	}
+	
+	err = models.DeleteView(c.Context(), viewID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+} 
\ No newline at end of file
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"blog/models"
+	"blog/notify"
+	"blog/webhook"
+	"net/http"
+	"strconv"
+)
+
+// WebhookController Webhook控制器
+type WebhookController struct {
+	BaseController
+}
+
+// List 获取Webhook列表
+// @Title 获取Webhook列表
+// @Description 获取当前用户注册的Webhook，包括签名密钥
+// @Success 200 {array} models.Webhook Webhook列表
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/webhooks [get]
+func (c *WebhookController) List() {
+	userID := c.GetUserID()
+	
+	webhooks, err := models.GetWebhooks(c.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(webhooks)
+}
+
+// parseRequest 解析并校验请求参数，事件类型必须是已知的类型
+func (c *WebhookController) parseRequest() (*models.WebhookRequest, bool) {
+	var req models.WebhookRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return nil, false
+	}
+	
+	for _, event := range req.Events {
+		if !notify.IsEventType(event) {
+			c.ErrorWithCode(http.StatusBadRequest, "invalid_webhook_event", c.T("invalid_webhook_event", event))
+			return nil, false
+		}
+	}
+	
+	return &req, true
+}
+
+// Create 注册Webhook
+// @Title 注册Webhook
+// @Description 注册接收事件的地址，事件以POST请求发送，请求头X-FinWise-Signature为HMAC-SHA256签名，失败后按指数退避重试
+// @Param body body models.WebhookRequest true "Webhook信息"
+// @Success 200 {object} models.Webhook 创建的Webhook，包括签名密钥
+// @Failure 400 参数错误或未知的事件类型
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 500 服务器内部错误
+// @Router /api/webhooks [post]
+func (c *WebhookController) Create() {
+	userID := c.GetUserID()
+	
+	req, ok := c.parseRequest()
+	if !ok {
+		return
+	}
+	
+	hook, err := models.CreateWebhook(c.Context(), userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(hook)
+}
+
+// Update 更新Webhook
+// @Title 更新Webhook
+// @Description 修改Webhook的地址、订阅的事件和启用状态，签名密钥不变
+// @Param id path int true "Webhook ID"
+// @Param body body models.WebhookRequest true "Webhook信息"
+// @Success 200 {object} models.Webhook 更新后的Webhook
+// @Failure 400 参数错误或未知的事件类型
+// @Failure 422 请求参数校验失败
+// @Failure 401 未授权
+// @Failure 404 Webhook不存在
+// @Failure 500 服务器内部错误
+// @Router /api/webhooks/{id} [put]
+func (c *WebhookController) Update() {
+	userID := c.GetUserID()
+	
+	id, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_webhook_id")
+		return
+	}
+	
+	req, ok := c.parseRequest()
+	if !ok {
+		return
+	}
+	
+	hook, err := models.UpdateWebhook(c.Context(), id, userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(hook)
+}
+
+// Delete 删除Webhook
+// @Title 删除Webhook
+// @Description 删除Webhook及其投递记录，未完成的重试也会取消
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} Response 删除成功
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 Webhook不存在
+// @Failure 500 服务器内部错误
+// @Router /api/webhooks/{id} [delete]
+func (c *WebhookController) Delete() {
+	userID := c.GetUserID()
+	
+	id, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_webhook_id")
+		return
+	}
+	
+	if err := models.DeleteWebhook(c.Context(), id, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(nil)
+}
+
+// Deliveries 获取投递记录
+// @Title 获取Webhook投递记录
+// @Description 获取Webhook最近的投递记录，包括请求内容、尝试次数、响应状态码和下次重试时间
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "返回条数，默认20，最大100"
+// @Success 200 {array} models.WebhookDelivery 投递记录
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 Webhook不存在
+// @Failure 500 服务器内部错误
+// @Router /api/webhooks/{id}/deliveries [get]
+func (c *WebhookController) Deliveries() {
+	userID := c.GetUserID()
+	
+	id, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_webhook_id")
+		return
+	}
+	
+	limit, _ := strconv.Atoi(c.Ctx.Input.Query("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	
+	deliveries, err := models.GetWebhookDeliveries(c.Context(), id, userID, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(deliveries)
+}
+
+// Redeliver 重新投递
+// @Title 重新投递
+// @Description 以原投递的内容创建新的投递记录并立即发送一次，返回新记录及其结果，失败后同样会自动重试
+// @Param id path int true "投递记录ID"
+// @Success 200 {object} models.WebhookDelivery 新的投递记录
+// @Failure 400 参数错误
+// @Failure 401 未授权
+// @Failure 404 投递记录不存在
+// @Failure 500 服务器内部错误
+// @Router /api/webhooks/deliveries/{id}/redeliver [post]
+func (c *WebhookController) Redeliver() {
+	userID := c.GetUserID()
+	
+	id, err := c.GetUintParam("id")
+	if err != nil {
+		c.ErrorWithCode(http.StatusBadRequest, "invalid_delivery_id")
+		return
+	}
+	
+	delivery, err := webhook.Default.Redeliver(c.Context(), id, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.Success(delivery)
+}
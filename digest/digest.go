@@ -0,0 +1,192 @@
+// Package digest 定期生成并发送周报、月报邮件
+//
+// 调度器按固定间隔检查所有订阅，用户时区下上一个完整的周或月结束后发送该周期的报告。
+// 发送前在数据库中占用周期，多个实例同时运行时每个周期只发送一次。
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"blog/i18n"
+	"blog/mail"
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 调度默认值
+const (
+	DefaultInterval = 10 * time.Minute
+	sendTimeout     = 30 * time.Second
+)
+
+// Scheduler 报告邮件调度器
+type Scheduler struct {
+	Mailer   mail.Mailer
+	SiteURL  string
+	Interval time.Duration
+	tpl      *template.Template
+}
+
+// New 创建调度器，viewsPath为模板目录
+func New(mailer mail.Mailer, siteURL, viewsPath string) (*Scheduler, error) {
+	tpl, err := template.New("digest.tpl").Funcs(template.FuncMap{"t": i18n.T}).ParseFiles(filepath.Join(viewsPath, "digest.tpl"))
+	if err != nil {
+		logs.Error("Error parsing digest template: %v", err)
+		return nil, err
+	}
+
+	return &Scheduler{
+		Mailer:   mailer,
+		SiteURL:  siteURL,
+		Interval: DefaultInterval,
+		tpl:      tpl,
+	}, nil
+}
+
+// Init 根据digest*配置启动报告邮件调度器，使用mail.Default发送
+func Init() {
+	if enabled, _ := web.AppConfig.Bool("digestenabled"); !enabled {
+		logs.Info("digest scheduler disabled")
+		return
+	}
+
+	siteURL, _ := web.AppConfig.String("siteurl")
+	if siteURL == "" {
+		siteURL = "http://localhost:8080"
+	}
+
+	s, err := New(mail.Default, siteURL, web.BConfig.WebConfig.ViewsPath)
+	if err != nil {
+		logs.Error("Failed to init digest scheduler: %v", err)
+		return
+	}
+	if interval, _ := web.AppConfig.String("digestinterval"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			logs.Error("Invalid digestinterval %q, using %v", interval, DefaultInterval)
+		} else {
+			s.Interval = d
+		}
+	}
+
+	go s.Start(context.Background())
+}
+
+// Start 按间隔检查并发送到期的报告，直到ctx取消
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			logs.Error("Error running digest scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 发送所有到期的报告，返回成功发送的数量；单个报告失败不影响其他报告
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	due, err := models.GetDueDigests(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		ok, err := s.send(ctx, d)
+		if err != nil {
+			logs.Error("Error sending %s digest to user %d: %v", d.Subscription.Frequency, d.User.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// send 占用周期后生成并发送报告，周期已被占用时返回false
+func (s *Scheduler) send(ctx context.Context, d *models.DueDigest) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	claimed, err := models.ClaimDigest(ctx, d)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	sendErr := s.deliver(ctx, d)
+	if sendErr != nil {
+		// 发送失败时释放周期，下次检查时重试
+		models.ReleaseDigest(context.Background(), d)
+	}
+	models.RecordDigestDelivery(context.Background(), d, sendErr)
+	return sendErr == nil, sendErr
+}
+
+// deliver 生成报告并发送邮件
+func (s *Scheduler) deliver(ctx context.Context, d *models.DueDigest) error {
+	digest, err := models.BuildDigest(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	msg, err := s.Render(digest, d.Subscription.UnsubscribeToken)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{d.User.Email}
+
+	return s.Mailer.Send(ctx, msg)
+}
+
+// Render 生成报告邮件，语言使用用户设置，未设置时使用默认语言
+func (s *Scheduler) Render(digest *models.Digest, unsubscribeToken string) (*mail.Message, error) {
+	lang := digest.User.Language
+	if lang == "" {
+		lang = i18n.Default
+	}
+
+	unsubscribeURL := fmt.Sprintf("%s/api/digests/unsubscribe?token=%s", s.SiteURL, url.QueryEscape(unsubscribeToken))
+
+	var body bytes.Buffer
+	err := s.tpl.Execute(&body, map[string]interface{}{
+		"Lang":           lang,
+		"Digest":         digest,
+		"Report":         digest.Report,
+		"PreviousChange": models.FormatChange(digest.Report.Previous.ExpenseChange),
+		"SiteURL":        s.SiteURL,
+		"UnsubscribeURL": unsubscribeURL,
+	})
+	if err != nil {
+		logs.Error("Error rendering digest: %v", err)
+		return nil, err
+	}
+
+	return &mail.Message{
+		Subject: i18n.T(lang, "digest_subject_"+digest.Frequency, digest.Report.StartDate, digest.Report.EndDate),
+		Body:    body.String(),
+		HTML:    true,
+		// RFC 8058一键退订：邮件客户端直接POST该地址，链接本身的GET请求只显示确认页面
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}, nil
+}
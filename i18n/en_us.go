@@ -0,0 +1,192 @@
+package i18n
+
+// enUS 英文消息目录
+var enUS = map[string]string{
+	// 通用
+	"success":              "OK",
+	"invalid_request_body": "Malformed request body",
+	"validation_failed":    "Request validation failed",
+	"internal_error":       "Internal server error",
+	"request_timeout":      "Request timed out, please try again later",
+	"unauthorized":         "Unauthorized, please log in",
+	"invalid_auth_format":  "Malformed Authorization header",
+	"invalid_token":        "Invalid token",
+	"rate_limited":         "Too many requests, please try again later",
+
+	// 参数
+	"invalid_bill_id":     "Invalid bill ID",
+	"invalid_category_id": "Invalid category ID",
+	"invalid_budget_id":   "Invalid budget ID",
+	"invalid_alert_id":    "Invalid budget alert ID",
+	"invalid_cursor":      "Invalid pagination cursor, please reload from the first page",
+	"year_out_of_range":   "Invalid or out of range year",
+	"month_out_of_range":  "Invalid or out of range month",
+	"invalid_date":        "Invalid date, expected YYYY-MM-DD or RFC3339",
+	"invalid_month":       "Invalid month, expected YYYY-MM",
+
+	// 校验规则
+	"rule_required":        "is required",
+	"rule_min":             "must be at least %v",
+	"rule_max":             "must be at most %v",
+	"rule_range":           "must be between %v and %v",
+	"rule_min_size":        "must be at least %v characters",
+	"rule_max_size":        "must be at most %v characters",
+	"rule_length":          "must be exactly %v characters",
+	"rule_match":           "has an invalid format",
+	"rule_email":           "must be a valid email address",
+	"rule_mobile":          "must be a valid mobile number",
+	"rule_phone":           "must be a valid phone number",
+	"rule_type":            "has the wrong type",
+	"money_invalid":        "Invalid amount, at most two decimal places are allowed",
+	"money_out_of_range":   "Amount must be greater than 0 and at most 1000000000000.00",
+	"date_invalid":         "Invalid date, expected YYYY-MM-DD",
+	"datetime_invalid":     "Invalid date, expected YYYY-MM-DD or RFC3339",
+	"month_invalid":        "Invalid month, expected YYYY-MM",
+	"date_out_of_range":    "Date is out of the allowed range",
+	"timezone_invalid":     "Invalid timezone, use an IANA name such as Asia/Shanghai",
+	"language_invalid":     "Unsupported language, use zh-CN or en-US",
+	"date_range_invalid":   "End date must not be before start date",
+	"min_amount_negative":  "Minimum amount must not be negative",
+	"max_amount_negative":  "Maximum amount must not be negative",
+	"amount_range_invalid": "Maximum amount must not be less than minimum amount",
+	"tags_too_many":        "A bill can have at most 10 tags",
+	"tag_too_long":         "Each tag must be at most 32 characters",
+
+	// 用户
+	"username_taken":          "Username is already taken",
+	"email_taken":             "Email is already registered",
+	"user_not_found":          "User not found",
+	"wrong_password":          "Current password is incorrect",
+	"email_not_found":         "Email not found",
+	"invalid_credentials":     "Invalid username or password",
+	"login_throttled":         "Too many login attempts, please retry in %d seconds",
+	"login_failed":            "Login failed, please try again later",
+	"token_generation_failed": "Failed to generate token",
+	"unlock_token_invalid":    "Unlock link is invalid or has expired",
+	"unlock_email_subject":    "Your FinWise account has been temporarily locked",
+	"unlock_email_body":       "Hi %s,\n\nYour account has been locked for %d minutes after too many failed login attempts. If this was you, you can unlock it right away with the link below:\n\n%s/api/user/unlock?token=%s\n\nIf this was not you, please change your password after unlocking.",
+
+	// 分类
+	"category_not_found":  "Category not found",
+	"category_not_owned":  "Category does not exist or does not belong to you",
+	"category_exists":     "A category with the same name and type already exists",
+	"category_in_use":     "Category is used by bills and cannot be deleted",
+	"category_has_budget": "Category has budgets and cannot be deleted",
+	"category_food":       "Food",
+	"category_shopping":   "Shopping",
+	"category_transport":  "Transport",
+	"category_home":       "Housing",
+	"category_salary":     "Salary",
+	"category_bonus":      "Bonus",
+	"category_investment": "Investment",
+
+	// 账单
+	"bill_not_found":     "Bill not found",
+	"bill_type_mismatch": "Bill type does not match the category type",
+
+	// 预算
+	"budget_not_found":       "Budget not found",
+	"budget_expense_only":    "Budgets can only be set on expense categories",
+	"budget_category_exists": "This category already has a budget of the same period type overlapping these dates",
+	"budget_total_exists":    "A total budget of the same period type already overlaps these dates",
+	"threshold_out_of_range": "Threshold must be between 1 and 100",
+	"alert_threshold_exists": "An alert with threshold %d%% already exists",
+	"alert_not_found":        "Budget alert not found",
+	"alert_event_not_found":  "Budget alert event not found",
+
+	"date_range_unknown":  "Unsupported relative date range",
+	"date_range_conflict": "Relative date range cannot be combined with start or end date",
+	"invalid_view_id":     "Invalid view ID",
+	"view_not_found":      "View not found",
+	"view_exists":         "A view with this name already exists",
+
+	"report_range_conflict":   "Period cannot be combined with start or end date",
+	"report_range_incomplete": "A custom range requires both start and end date",
+	"report_range_too_long":   "Report range cannot exceed ten years",
+	"report_too_many_buckets": "Too many time buckets, use a coarser bucket",
+
+	"share_other":    "Other",
+	"invalid_months": "Invalid number of months",
+	"invalid_top":    "Invalid number of categories",
+
+	"invalid_format": "Unsupported output format",
+
+	"statement_title":                "Monthly Statement",
+	"statement_generated_at":         "Generated at",
+	"statement_summary":              "Summary",
+	"statement_income":               "Income",
+	"statement_expense":              "Expense",
+	"statement_balance":              "Balance",
+	"statement_count":                "Count",
+	"statement_expense_vs_previous":  "Expense vs. last month",
+	"statement_expense_vs_last_year": "Expense vs. same month last year",
+	"statement_daily_expense":        "Daily expense",
+	"statement_categories":           "Expense by category",
+	"statement_category":             "Category",
+	"statement_amount":               "Amount",
+	"statement_share":                "Share",
+	"statement_no_data":              "No data",
+	"statement_budgets":              "Budgets",
+	"statement_budget":               "Budget",
+	"statement_used":                 "Used",
+	"statement_available":            "Available",
+	"statement_total_budget":         "Total budget",
+	"statement_top_bills":            "Largest expenses",
+	"statement_date":                 "Date",
+	"statement_description":          "Description",
+
+	"digest_not_found":          "Subscription not found",
+	"digest_exists":             "Already subscribed to this digest",
+	"unsubscribe_token_invalid": "Unsubscribe link is invalid or already used",
+	"unsubscribe_title":         "Unsubscribe from FinWise digests",
+	"unsubscribe_confirm":       "Stop receiving this FinWise digest email?",
+	"unsubscribe_done":          "You have been unsubscribed and will no longer receive this digest.",
+	"invalid_digest_id":         "Invalid subscription ID",
+
+	"digest_subject_weekly":      "FinWise weekly digest (%s ~ %s)",
+	"digest_subject_monthly":     "FinWise monthly digest (%s ~ %s)",
+	"digest_title_weekly":        "Weekly Digest",
+	"digest_title_monthly":       "Monthly Digest",
+	"digest_greeting":            "Hi %s",
+	"digest_expense_vs_previous": "Expense vs. previous period",
+	"digest_open_app":            "Open FinWise for details",
+	"digest_footer":              "You received this email because you subscribed to FinWise digests.",
+	"digest_unsubscribe":         "Unsubscribe",
+
+	"notify_budget_threshold_subject": "Budget alert: %s is %d%% used",
+	"notify_budget_threshold_body":    "Your %s budget (%s) has reached the %d%% alert threshold.\nUsed %s of %s (%.2f%%).",
+	"notify_budget_exceeded_subject":  "Budget exceeded: %s (%s)",
+	"notify_budget_exceeded_body":     "Your %s budget (%s) has been used up.\nUsed %s of %s (%.2f%%).",
+
+	"notification_not_found":  "Notification not found",
+	"invalid_notification_id": "Invalid notification ID",
+
+	"too_many_connections":  "Too many open event streams, close another device or tab and retry",
+	"invalid_stream_ticket": "Event stream ticket is invalid or has expired, request a new one",
+
+	"webhook_not_found":          "Webhook not found",
+	"webhook_url_invalid":        "Webhook URL must be an http or https address",
+	"webhook_limit":              "You can register at most %d webhooks",
+	"webhook_delivery_not_found": "Webhook delivery not found",
+	"invalid_webhook_id":         "Invalid webhook ID",
+	"invalid_delivery_id":        "Invalid delivery ID",
+	"invalid_webhook_event":      "Unknown webhook event: %s",
+
+	"budget_template_not_found":        "Budget template not found",
+	"budget_template_exists":           "This category already has a budget template with an overlapping month range",
+	"budget_template_end_before_start": "End month cannot be before the start month",
+	"invalid_template_id":              "Invalid budget template ID",
+	"budget_copy_same_month":           "Source and target months must differ",
+	"adjust_percent_out_of_range":      "Adjustment percentage must be between -99.99 and 1000",
+	"adjust_budget_required":           "Each adjustment must specify a source budget_id",
+
+	"budget_start_required": "Specify the budget month or start date",
+	"budget_end_required":   "Custom period budgets require an end date",
+	"budget_period_invalid": "Budget end date cannot be before the start date",
+	"invalid_budget_period": "Invalid budget period",
+
+	"budget_scope_overlap":       "A selected category or tag is already covered by another budget of the same period type with overlapping dates",
+	"budget_scope_conflict":      "Group budgets use category_ids and tags and cannot also set category_id",
+	"budget_name_required":       "Group budgets require a name",
+	"budget_categories_too_many": "A group budget can include at most 20 categories",
+}
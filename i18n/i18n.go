@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 支持的语言
+const (
+	ZhCN = "zh-CN"
+	EnUS = "en-US"
+)
+
+// Default 默认语言，请求与用户都未指定语言时使用
+var Default = ZhCN
+
+// catalogs 各语言的消息目录，键为稳定的错误码或消息码
+var catalogs = map[string]map[string]string{
+	ZhCN: zhCN,
+	EnUS: enUS,
+}
+
+// T 按语言渲染消息，缺失时依次回退到默认语言和消息码本身
+func T(lang, code string, args ...interface{}) string {
+	format, ok := catalogs[lang][code]
+	if !ok {
+		format, ok = catalogs[Default][code]
+	}
+	if !ok {
+		return code
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Supported 将语言标签规范化为支持的语言，不支持时返回空字符串
+//
+// 只比较主语言，如 en、en-GB、en_US 都对应 en-US。
+func Supported(tag string) string {
+	tag = strings.TrimSpace(strings.Replace(tag, "_", "-", -1))
+	if tag == "" {
+		return ""
+	}
+	primary := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+	for lang := range catalogs {
+		if strings.ToLower(strings.SplitN(lang, "-", 2)[0]) == primary {
+			return lang
+		}
+	}
+	return ""
+}
+
+// Negotiate 根据Accept-Language请求头选择语言，没有匹配时返回默认语言
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+
+	candidates := make([]candidate, 0)
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		lang := Supported(fields[0])
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+	return candidates[0].lang
+}
+
+// Missing 返回默认语言中存在但指定语言缺失的消息码，用于检查目录是否完整
+func Missing(lang string) []string {
+	missing := make([]string, 0)
+	for code := range catalogs[Default] {
+		if _, ok := catalogs[lang][code]; !ok {
+			missing = append(missing, code)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
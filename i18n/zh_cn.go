@@ -0,0 +1,192 @@
+package i18n
+
+// zhCN 简体中文消息目录
+var zhCN = map[string]string{
+	// 通用
+	"success":              "成功",
+	"invalid_request_body": "请求参数格式错误",
+	"validation_failed":    "请求参数校验失败",
+	"internal_error":       "服务器内部错误",
+	"request_timeout":      "请求处理超时，请稍后再试",
+	"unauthorized":         "未授权，请登录",
+	"invalid_auth_format":  "认证格式有误",
+	"invalid_token":        "无效的令牌",
+	"rate_limited":         "请求过于频繁，请稍后再试",
+
+	// 参数
+	"invalid_bill_id":     "账单ID格式错误",
+	"invalid_category_id": "分类ID格式错误",
+	"invalid_budget_id":   "预算ID格式错误",
+	"invalid_alert_id":    "预算告警ID格式错误",
+	"invalid_cursor":      "分页游标无效，请从第一页重新加载",
+	"year_out_of_range":   "年份格式错误或超出范围",
+	"month_out_of_range":  "月份格式错误或超出范围",
+	"invalid_date":        "日期格式错误，正确格式为：YYYY-MM-DD 或 RFC3339",
+	"invalid_month":       "月份格式错误，正确格式为：YYYY-MM",
+
+	// 校验规则
+	"rule_required":        "不能为空",
+	"rule_min":             "不能小于%v",
+	"rule_max":             "不能大于%v",
+	"rule_range":           "必须在%v到%v之间",
+	"rule_min_size":        "长度不能小于%v",
+	"rule_max_size":        "长度不能大于%v",
+	"rule_length":          "长度必须为%v",
+	"rule_match":           "格式不正确",
+	"rule_email":           "必须是有效的邮箱地址",
+	"rule_mobile":          "必须是有效的手机号码",
+	"rule_phone":           "必须是有效的电话号码",
+	"rule_type":            "类型不正确",
+	"money_invalid":        "金额格式错误，最多保留两位小数",
+	"money_out_of_range":   "金额必须大于0且不超过1000000000000.00",
+	"date_invalid":         "日期格式错误，正确格式为：YYYY-MM-DD",
+	"datetime_invalid":     "日期格式错误，正确格式为：YYYY-MM-DD 或 RFC3339",
+	"month_invalid":        "月份格式错误，正确格式为：YYYY-MM",
+	"date_out_of_range":    "日期超出允许范围",
+	"timezone_invalid":     "时区无效，请使用IANA时区名称，如Asia/Shanghai",
+	"language_invalid":     "不支持的语言，可选值为zh-CN、en-US",
+	"date_range_invalid":   "结束日期不能早于开始日期",
+	"min_amount_negative":  "最小金额不能为负数",
+	"max_amount_negative":  "最大金额不能为负数",
+	"amount_range_invalid": "最大金额不能小于最小金额",
+	"tags_too_many":        "最多只能添加10个标签",
+	"tag_too_long":         "单个标签不能超过32个字符",
+
+	// 用户
+	"username_taken":          "用户名已存在",
+	"email_taken":             "邮箱已被注册",
+	"user_not_found":          "用户不存在",
+	"wrong_password":          "原密码错误",
+	"email_not_found":         "邮箱不存在",
+	"invalid_credentials":     "用户名或密码错误",
+	"login_throttled":         "登录尝试过于频繁，请在%d秒后重试",
+	"login_failed":            "登录失败，请稍后再试",
+	"token_generation_failed": "生成令牌失败",
+	"unlock_token_invalid":    "解锁链接无效或已过期",
+	"unlock_email_subject":    "FinWise 账户已被临时锁定",
+	"unlock_email_body":       "%s，您好：\n\n您的账户因多次登录失败已被临时锁定%d分钟。如果是您本人操作，可以通过以下链接立即解锁：\n\n%s/api/user/unlock?token=%s\n\n如果不是您本人操作，建议在解锁后尽快修改密码。",
+
+	// 分类
+	"category_not_found":  "分类不存在",
+	"category_not_owned":  "分类不存在或不属于当前用户",
+	"category_exists":     "已存在同名同类型的分类",
+	"category_in_use":     "该分类已被使用，无法删除",
+	"category_has_budget": "该分类已设置预算，无法删除",
+	"category_food":       "餐饮",
+	"category_shopping":   "购物",
+	"category_transport":  "交通",
+	"category_home":       "住房",
+	"category_salary":     "工资",
+	"category_bonus":      "奖金",
+	"category_investment": "投资",
+
+	// 账单
+	"bill_not_found":     "账单不存在",
+	"bill_type_mismatch": "账单类型与分类类型不一致",
+
+	// 预算
+	"budget_not_found":       "预算不存在",
+	"budget_expense_only":    "只能为支出分类设置预算",
+	"budget_category_exists": "该分类已有时间重叠的同类周期预算",
+	"budget_total_exists":    "已有时间重叠的同类周期总预算",
+	"threshold_out_of_range": "阈值必须在1-100之间",
+	"alert_threshold_exists": "已存在相同阈值(%d%%)的告警",
+	"alert_not_found":        "预算告警不存在",
+	"alert_event_not_found":  "预算告警触发记录不存在",
+
+	"date_range_unknown":  "不支持的相对日期范围",
+	"date_range_conflict": "相对日期范围不能与开始、结束日期同时使用",
+	"invalid_view_id":     "无效的视图ID",
+	"view_not_found":      "视图不存在",
+	"view_exists":         "同名视图已存在",
+
+	"report_range_conflict":   "周期不能与开始、结束日期同时使用",
+	"report_range_incomplete": "自定义范围需要同时指定开始日期和结束日期",
+	"report_range_too_long":   "报表范围不能超过十年",
+	"report_too_many_buckets": "时间序列分桶过多，请使用更大的分桶粒度",
+
+	"share_other":    "其他",
+	"invalid_months": "无效的月数",
+	"invalid_top":    "无效的分类数",
+
+	"invalid_format": "不支持的输出格式",
+
+	"statement_title":                "月度对账单",
+	"statement_generated_at":         "生成于",
+	"statement_summary":              "收支概览",
+	"statement_income":               "收入",
+	"statement_expense":              "支出",
+	"statement_balance":              "结余",
+	"statement_count":                "笔数",
+	"statement_expense_vs_previous":  "支出较上月",
+	"statement_expense_vs_last_year": "支出较去年同月",
+	"statement_daily_expense":        "每日支出",
+	"statement_categories":           "支出分类",
+	"statement_category":             "分类",
+	"statement_amount":               "金额",
+	"statement_share":                "占比",
+	"statement_no_data":              "暂无数据",
+	"statement_budgets":              "预算执行",
+	"statement_budget":               "预算",
+	"statement_used":                 "已使用",
+	"statement_available":            "可用",
+	"statement_total_budget":         "总预算",
+	"statement_top_bills":            "最大支出",
+	"statement_date":                 "日期",
+	"statement_description":          "描述",
+
+	"digest_not_found":          "订阅不存在",
+	"digest_exists":             "已订阅该频率的报告邮件",
+	"unsubscribe_token_invalid": "退订链接无效或已使用",
+	"unsubscribe_title":         "退订 FinWise 定期报告",
+	"unsubscribe_confirm":       "确定不再接收这份 FinWise 定期报告邮件吗？",
+	"unsubscribe_done":          "已退订，您将不再收到这份定期报告。",
+	"invalid_digest_id":         "无效的订阅ID",
+
+	"digest_subject_weekly":      "FinWise 周报（%s ~ %s）",
+	"digest_subject_monthly":     "FinWise 月报（%s ~ %s）",
+	"digest_title_weekly":        "收支周报",
+	"digest_title_monthly":       "收支月报",
+	"digest_greeting":            "%s，您好",
+	"digest_expense_vs_previous": "支出较上一周期",
+	"digest_open_app":            "打开 FinWise 查看详情",
+	"digest_footer":              "您收到这封邮件是因为订阅了 FinWise 定期报告。",
+	"digest_unsubscribe":         "退订",
+
+	"notify_budget_threshold_subject": "预算提醒：%s已使用%d%%",
+	"notify_budget_threshold_body":    "您的%s预算（%s）已达到%d%%的告警阈值。\n已使用 %s / %s（%.2f%%）。",
+	"notify_budget_exceeded_subject":  "预算超支：%s（%s）",
+	"notify_budget_exceeded_body":     "您的%s预算（%s）已用完。\n已使用 %s / %s（%.2f%%）。",
+
+	"notification_not_found":  "通知不存在",
+	"invalid_notification_id": "无效的通知ID",
+
+	"too_many_connections":  "实时连接数过多，请关闭其他设备或页面后重试",
+	"invalid_stream_ticket": "实时连接票据无效或已过期，请重新获取",
+
+	"webhook_not_found":          "Webhook不存在",
+	"webhook_url_invalid":        "Webhook地址必须是http或https地址",
+	"webhook_limit":              "最多只能注册%d个Webhook",
+	"webhook_delivery_not_found": "投递记录不存在",
+	"invalid_webhook_id":         "无效的Webhook ID",
+	"invalid_delivery_id":        "无效的投递记录ID",
+	"invalid_webhook_event":      "未知的Webhook事件：%s",
+
+	"budget_template_not_found":        "预算模板不存在",
+	"budget_template_exists":           "该分类已有时间范围重叠的预算模板",
+	"budget_template_end_before_start": "结束月份不能早于开始月份",
+	"invalid_template_id":              "无效的预算模板ID",
+	"budget_copy_same_month":           "源月份和目标月份不能相同",
+	"adjust_percent_out_of_range":      "调整百分比必须在-99.99到1000之间",
+	"adjust_budget_required":           "每项调整都必须指定源预算budget_id",
+
+	"budget_start_required": "请指定预算月份或开始日期",
+	"budget_end_required":   "自定义周期预算必须指定结束日期",
+	"budget_period_invalid": "预算结束日期不能早于开始日期",
+	"invalid_budget_period": "无效的预算周期类型",
+
+	"budget_scope_overlap":       "所选分类或标签已被时间重叠的同类周期预算统计",
+	"budget_scope_conflict":      "组合预算使用category_ids和tags，不能同时指定category_id",
+	"budget_name_required":       "组合预算必须填写名称",
+	"budget_categories_too_many": "组合预算最多包含20个分类",
+}
@@ -0,0 +1,214 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// Message 邮件内容
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+	HTML    bool
+	Headers map[string]string // 额外的邮件头，如List-Unsubscribe
+}
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Default 默认使用的邮件发送器，未配置SMTP时仅记录日志
+var Default Mailer = LogMailer{}
+
+// Init 根据mail*配置初始化默认邮件发送器，配置maildir时邮件写入该目录而不发送
+func Init() {
+	if dir, _ := web.AppConfig.String("maildir"); dir != "" {
+		logs.Info("maildir configured, emails will be written to %s", dir)
+		Default = &FileMailer{Dir: dir}
+		return
+	}
+
+	host, _ := web.AppConfig.String("mailhost")
+	if host == "" {
+		logs.Warn("mailhost not configured, emails will only be logged")
+		return
+	}
+
+	port, _ := web.AppConfig.String("mailport")
+	if port == "" {
+		port = "587"
+	}
+	user, _ := web.AppConfig.String("mailuser")
+	password, _ := web.AppConfig.String("mailpassword")
+	from, _ := web.AppConfig.String("mailfrom")
+	if from == "" {
+		from = user
+	}
+
+	Default = &SMTPMailer{
+		Addr:     net.JoinHostPort(host, port),
+		Username: user,
+		Password: password,
+		From:     from,
+	}
+}
+
+// Send 使用默认发送器发送邮件
+func Send(ctx context.Context, msg *Message) error {
+	return Default.Send(ctx, msg)
+}
+
+// SMTPMailer 通过SMTP发送邮件
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send 发送邮件，服务器支持时自动启用STARTTLS
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Addr, auth, envelopeAddress(m.From), msg.To, m.build(msg))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// build 生成符合RFC 5322的邮件内容
+func (m *SMTPMailer) build(msg *Message) []byte {
+	contentType := "text/plain"
+	if msg.HTML {
+		contentType = "text/html"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: =?UTF-8?B?%s?=\r\n", base64.StdEncoding.EncodeToString([]byte(msg.Subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	for _, name := range headerNames(msg.Headers) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, msg.Headers[name])
+	}
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: %s; charset=UTF-8\r\n", contentType)
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(body) > 76 {
+		b.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	b.WriteString(body + "\r\n")
+
+	return []byte(b.String())
+}
+
+// headerNames 按名称排序的额外邮件头，去掉含换行的头以防注入
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name, value := range headers {
+		if strings.ContainsAny(name+value, "\r\n") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envelopeAddress 从 "Name <addr>" 格式中提取邮箱地址
+func envelopeAddress(from string) string {
+	if i := strings.LastIndexByte(from, '<'); i >= 0 {
+		if j := strings.LastIndexByte(from, '>'); j > i {
+			return from[i+1 : j]
+		}
+	}
+	return strings.TrimSpace(from)
+}
+
+// LogMailer 仅把邮件写入日志，用于开发环境
+type LogMailer struct{}
+
+// Send 记录邮件内容
+func (LogMailer) Send(_ context.Context, msg *Message) error {
+	logs.Info("Mail to %v: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// MemoryMailer 把邮件保存在内存中，用于测试
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+// Send 保存邮件
+func (m *MemoryMailer) Send(_ context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages 已发送的邮件
+func (m *MemoryMailer) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Message(nil), m.messages...)
+}
+
+// FileMailer 把每封邮件写入目录中的单独文件，用于测试环境查看邮件内容
+type FileMailer struct {
+	Dir string
+}
+
+// Send 写入邮件文件，文件名包含发送时间和收件人
+func (m *FileMailer) Send(_ context.Context, msg *Message) error {
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+
+	ext := ".txt"
+	if msg.HTML {
+		ext = ".html"
+	}
+	name := fmt.Sprintf("%s-%s%s", time.Now().Format("20060102-150405.000000000"), strings.Join(msg.To, ","), ext)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\nSubject: %s\n", strings.Join(msg.To, ", "), msg.Subject)
+	for _, name := range headerNames(msg.Headers) {
+		fmt.Fprintf(&b, "%s: %s\n", name, msg.Headers[name])
+	}
+	fmt.Fprintf(&b, "\n%s", msg.Body)
+	return os.WriteFile(filepath.Join(m.Dir, filepath.Base(name)), []byte(b.String()), 0644)
+}
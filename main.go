@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+
 	_ "blog/routers"
+	"blog/digest"
+	"blog/mail"
 	"blog/models"
 	"blog/middleware"
+	"blog/notify"
+	"blog/recurring"
+	"blog/webhook"
 
 	beego "github.com/beego/beego/v2/server/web"
 	"github.com/beego/beego/v2/core/logs"
@@ -17,7 +24,39 @@ func main() {
 	logs.SetLogger(logs.AdapterFile, `{"filename":"logs/finwise.log","level":7,"maxlines":0,"maxsize":0,"daily":true,"maxdays":10}`)
 	logs.Async()
 	
+	// 初始化邮件发送
+	mail.Init()
+	
+	// 启动报告邮件调度
+	digest.Init()
+	
+	// 启动Webhook重试
+	webhook.Init()
+	
+	// 启动预算模板调度
+	recurring.Init()
+	
+	// 注册通知渠道
+	notify.Init()
+	
+	// 初始化链路追踪
+	shutdownTracing, err := middleware.InitTracing()
+	if err != nil {
+		logs.Error("Failed to init tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+	
+	// 初始化跨域策略
+	middleware.InitCors()
+	
+	// 初始化限流器
+	if err := middleware.InitRateLimiter(); err != nil {
+		logs.Error("Failed to init rate limiter: %v", err)
+		panic(err)
+	}
+	
 	// 添加中间件
+	beego.InsertFilterChain("/*", middleware.TracingChain)
 	beego.InsertFilter("/*", beego.BeforeRouter, middleware.CorsHandler)
 	beego.InsertFilter("/api/*", beego.BeforeRouter, middleware.RateLimiter)
 	beego.InsertFilter("/api/*", beego.BeforeRouter, middleware.JwtFilter)
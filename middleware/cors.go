@@ -1,20 +1,155 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
 	"github.com/beego/beego/v2/server/web/context"
 )
 
+// CorsConfig 跨域策略
+type CorsConfig struct {
+	AllowOrigins     []string // 允许的来源，支持 * 和 https://*.example.com 形式的通配子域名；携带凭证时 * 不生效
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           int // 预检结果缓存秒数
+}
+
+var corsConfig = CorsConfig{
+	AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+	AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", "Accept-Language"},
+	ExposeHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+	MaxAge:        600,
+}
+
+// InitCors 根据当前运行模式的配置初始化跨域策略
+//
+// 配置项（可在 [dev]/[prod] 等分区中分别设置）：
+//
+//	corsalloworigins     = https://app.example.com,https://*.example.com
+//	corsallowmethods     = GET,POST,PUT,DELETE,OPTIONS
+//	corsallowheaders     = Origin,Content-Type,Accept,Authorization
+//	corsexposeheaders    = RateLimit-Limit,RateLimit-Remaining
+//	corsallowcredentials = true
+//	corsmaxage           = 600
+func InitCors() {
+	if origins := splitConfig("corsalloworigins"); origins != nil {
+		corsConfig.AllowOrigins = origins
+	}
+	if methods := splitConfig("corsallowmethods"); methods != nil {
+		corsConfig.AllowMethods = methods
+	}
+	if headers := splitConfig("corsallowheaders"); headers != nil {
+		corsConfig.AllowHeaders = headers
+	}
+	if headers := splitConfig("corsexposeheaders"); headers != nil {
+		corsConfig.ExposeHeaders = headers
+	}
+	corsConfig.AllowCredentials, _ = web.AppConfig.Bool("corsallowcredentials")
+	if maxAge, err := web.AppConfig.Int("corsmaxage"); err == nil {
+		corsConfig.MaxAge = maxAge
+	}
+
+	if corsConfig.AllowCredentials {
+		for _, allowed := range corsConfig.AllowOrigins {
+			if allowed == "*" {
+				logs.Warn("corsalloworigins contains * but corsallowcredentials is enabled, * will not match any origin")
+				break
+			}
+		}
+	}
+}
+
+func splitConfig(key string) []string {
+	value, _ := web.AppConfig.String(key)
+	if value == "" {
+		return nil
+	}
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// matchOrigin 检查来源是否在允许列表中
+func (cfg *CorsConfig) matchOrigin(origin string) bool {
+	for _, allowed := range cfg.AllowOrigins {
+		// 携带凭证时回显任意来源等于允许所有网站读取用户数据，* 只在不携带凭证时生效
+		if allowed == "*" {
+			if !cfg.AllowCredentials {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+		// https://*.example.com 匹配任意子域名，但不匹配 example.com 本身
+		if i := strings.Index(allowed, "://*."); i >= 0 {
+			scheme, suffix := allowed[:i+3], allowed[i+4:]
+			if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, suffix) && len(origin) > len(scheme)+len(suffix) {
+				host := origin[len(scheme) : len(origin)-len(suffix)]
+				if !strings.ContainsAny(host, "/:") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// allowsAnyOrigin 配置了 * 且不携带凭证时可直接返回 *
+func (cfg *CorsConfig) allowsAnyOrigin() bool {
+	if cfg.AllowCredentials {
+		return false
+	}
+	for _, allowed := range cfg.AllowOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 // CorsHandler 处理跨域请求
 func CorsHandler(ctx *context.Context) {
-	ctx.Output.Header("Access-Control-Allow-Origin", "*")
-	ctx.Output.Header("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-	ctx.Output.Header("Access-Control-Allow-Headers", "Origin,Content-Type,Accept,Authorization")
-	ctx.Output.Header("Access-Control-Allow-Credentials", "true")
-	
-	// 处理预检请求
-	if ctx.Input.Method() == "OPTIONS" {
-		ctx.Output.SetStatus(200)
-		ctx.ResponseWriter.WriteHeader(200)
-		return
-	}
-} 
\ No newline at end of file
+	origin := ctx.Input.Header("Origin")
+	preflight := ctx.Input.Method() == http.MethodOptions && ctx.Input.Header("Access-Control-Request-Method") != ""
+
+	ctx.Output.Header("Vary", "Origin")
+
+	if origin != "" && corsConfig.matchOrigin(origin) {
+		if corsConfig.allowsAnyOrigin() {
+			ctx.Output.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			ctx.Output.Header("Access-Control-Allow-Origin", origin)
+		}
+		if corsConfig.AllowCredentials {
+			ctx.Output.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			ctx.Output.Header("Access-Control-Allow-Methods", strings.Join(corsConfig.AllowMethods, ","))
+			ctx.Output.Header("Access-Control-Allow-Headers", strings.Join(corsConfig.AllowHeaders, ","))
+			if corsConfig.MaxAge > 0 {
+				ctx.Output.Header("Access-Control-Max-Age", strconv.Itoa(corsConfig.MaxAge))
+			}
+		} else if len(corsConfig.ExposeHeaders) > 0 {
+			ctx.Output.Header("Access-Control-Expose-Headers", strings.Join(corsConfig.ExposeHeaders, ","))
+		}
+	}
+
+	// 预检请求在此直接结束，不再进入限流和JWT认证
+	if preflight {
+		ctx.Output.SetStatus(http.StatusNoContent)
+		ctx.ResponseWriter.WriteHeader(http.StatusNoContent)
+	}
+}
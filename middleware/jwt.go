@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"net/http"
 	"strings"
 	"time"
 
+	"blog/models"
+
 	"github.com/beego/beego/v2/server/web/context"
 	"github.com/dgrijalva/jwt-go"
 )
@@ -14,6 +17,7 @@ var JwtSecret = []byte("WalletWise_Secret_Key")
 // Claims 自定义声明结构体
 type Claims struct {
 	UserID uint `json:"user_id"`
+	Lang   string `json:"lang,omitempty"` // 用户设置的语言，为空时按Accept-Language选择
 	jwt.StandardClaims
 }
 
@@ -22,15 +26,25 @@ var whitelist = map[string]bool{
 	"/api/user/register": true,
 	"/api/user/login":    true,
 	"/api/user/forgot-password": true,
+	"/api/user/unlock": true,
+	"/api/digests/unsubscribe": true,
+}
+
+// 允许通过ticket查询参数认证的路径，浏览器的EventSource无法设置请求头
+//
+// 查询参数会出现在代理和访问日志中，因此只接受POST /api/events/ticket获取的一次性短期票据，不接受JWT
+var ticketPaths = map[string]bool{
+	"/api/events": true,
 }
 
-// GenerateToken 生成JWT令牌
-func GenerateToken(userID uint) (string, error) {
+// GenerateToken 生成JWT令牌，lang为用户设置的语言，避免每个请求都查询数据库
+func GenerateToken(userID uint, lang string) (string, error) {
 	nowTime := time.Now()
 	expireTime := nowTime.Add(24 * time.Hour)
 
 	claims := Claims{
 		userID,
+		lang,
 		jwt.StandardClaims{
 			ExpiresAt: expireTime.Unix(),
 			Issuer:    "walletwise",
@@ -58,6 +72,17 @@ func ParseToken(token string) (*Claims, error) {
 	return nil, err
 }
 
+// removeQueryParam 从请求URL中移除查询参数，参数已使用完毕时调用，避免写入访问日志
+func removeQueryParam(r *http.Request, name string) {
+	query := r.URL.Query()
+	if _, ok := query[name]; !ok {
+		return
+	}
+	query.Del(name)
+	r.URL.RawQuery = query.Encode()
+	r.RequestURI = r.URL.RequestURI()
+}
+
 // JwtFilter JWT中间件
 func JwtFilter(ctx *context.Context) {
 	// 检查是否在白名单中
@@ -71,37 +96,43 @@ func JwtFilter(ctx *context.Context) {
 	}
 
 	authHeader := ctx.Input.Header("Authorization")
+	if authHeader == "" && ticketPaths[ctx.Request.URL.Path] {
+		if ticket := ctx.Input.Query("ticket"); ticket != "" {
+			userID, err := models.ConsumeStreamTicket(ctx.Request.Context(), ticket)
+			if err == models.ErrStreamTicketInvalid {
+				abort(ctx, 401, "invalid_stream_ticket")
+				return
+			}
+			if err != nil {
+				abort(ctx, 500, "internal_error")
+				return
+			}
+			// 票据已使用，从URL中移除，避免写入访问日志
+			removeQueryParam(ctx.Request, "ticket")
+			ctx.Input.SetData("user_id", userID)
+			return
+		}
+	}
 	if authHeader == "" {
-		ctx.Output.SetStatus(401)
-		ctx.Output.JSON(map[string]interface{}{
-			"code":    401,
-			"message": "未授权，请登录",
-		}, true, false)
+		abort(ctx, 401, "unauthorized")
 		return
 	}
 
 	// Bearer Token格式验证
 	parts := strings.SplitN(authHeader, " ", 2)
 	if !(len(parts) == 2 && parts[0] == "Bearer") {
-		ctx.Output.SetStatus(401)
-		ctx.Output.JSON(map[string]interface{}{
-			"code":    401,
-			"message": "认证格式有误",
-		}, true, false)
+		abort(ctx, 401, "invalid_auth_format")
 		return
 	}
 
 	// 解析Token
 	claims, err := ParseToken(parts[1])
 	if err != nil || claims == nil {
-		ctx.Output.SetStatus(401)
-		ctx.Output.JSON(map[string]interface{}{
-			"code":    401,
-			"message": "无效的令牌",
-		}, true, false)
+		abort(ctx, 401, "invalid_token")
 		return
 	}
 
-	// 将用户ID存储在上下文中
+	// 将用户ID和语言存储在上下文中
 	ctx.Input.SetData("user_id", claims.UserID)
+	ctx.Input.SetData("lang", claims.Lang)
 } 
\ No newline at end of file
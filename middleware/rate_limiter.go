@@ -1,69 +1,482 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/beego/beego/v2/server/web/context"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	beecontext "github.com/beego/beego/v2/server/web/context"
 )
 
-// RateLimiter 简单的限流实现
-type IPRateLimiter struct {
+// RateLimitPolicy 令牌桶限流策略
+type RateLimitPolicy struct {
+	Name  string
+	Rate  float64 // 每秒补充的令牌数
+	Burst int     // 桶容量
+}
+
+// RateLimitResult 一次取令牌的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // 被拒绝时距离下一个可用令牌的时间
+	Reset      time.Duration // 令牌桶恢复满的时间
+}
+
+// RateLimitStore 令牌桶状态存储，多副本部署时使用共享存储
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+// LimiterConfig 限流配置
+type LimiterConfig struct {
+	Default        RateLimitPolicy            // 未登录请求按客户端IP限流
+	User           RateLimitPolicy            // 已登录请求按用户ID限流
+	Routes         map[string]RateLimitPolicy // 按路由分组的策略，以*结尾表示前缀匹配
+	TrustedProxies []string                   // 可信代理的IP或CIDR
+}
+
+type routePolicy struct {
+	pattern string
+	prefix  bool
+	policy  RateLimitPolicy
+}
+
+// Limiter 基于令牌桶的限流器
+type Limiter struct {
+	store          RateLimitStore
+	defaultPolicy  RateLimitPolicy
+	userPolicy     RateLimitPolicy
+	routes         []routePolicy
+	trustedProxies []*net.IPNet
+}
+
+// 未配置时使用的默认策略
+var (
+	defaultIPPolicy   = RateLimitPolicy{Name: "ip", Rate: 10, Burst: 20}
+	defaultUserPolicy = RateLimitPolicy{Name: "user", Rate: 20, Burst: 40}
+)
+
+// limiter 由InitRateLimiter创建，未初始化时不限流
+var limiter *Limiter
+
+// NewLimiter 创建限流器
+func NewLimiter(store RateLimitStore, cfg LimiterConfig) *Limiter {
+	l := &Limiter{
+		store:         store,
+		defaultPolicy: cfg.Default,
+		userPolicy:    cfg.User,
+	}
+
+	for pattern, policy := range cfg.Routes {
+		if policy.Name == "" {
+			policy.Name = pattern
+		}
+		rp := routePolicy{pattern: pattern, policy: policy}
+		if strings.HasSuffix(pattern, "*") {
+			rp.pattern = strings.TrimSuffix(pattern, "*")
+			rp.prefix = true
+		}
+		l.routes = append(l.routes, rp)
+	}
+	// 精确匹配优先，其次是更长的前缀
+	sort.Slice(l.routes, func(i, j int) bool {
+		if l.routes[i].prefix != l.routes[j].prefix {
+			return !l.routes[i].prefix
+		}
+		return len(l.routes[i].pattern) > len(l.routes[j].pattern)
+	})
+
+	for _, proxy := range cfg.TrustedProxies {
+		if network := parseIPNet(proxy); network != nil {
+			l.trustedProxies = append(l.trustedProxies, network)
+		} else {
+			logs.Warn("Ignoring invalid trusted proxy %q", proxy)
+		}
+	}
+
+	return l
+}
+
+// InitRateLimiter 根据配置初始化限流器，重复调用时关闭之前的存储
+//
+// 配置项：
+//
+//	ratelimitbackend = memory | redis
+//	ratelimitredis   = localhost:6379
+//	ratelimitdefault = 10/s:20
+//	ratelimituser    = 20/s:40
+//	ratelimitroutes  = /api/user/login=5/m:5;/api/reports/*=2/s:5
+//	trustedproxies   = 127.0.0.1,10.0.0.0/8
+func InitRateLimiter() error {
+	cfg := LimiterConfig{
+		Default: defaultIPPolicy,
+		User:    defaultUserPolicy,
+		Routes:  make(map[string]RateLimitPolicy),
+	}
+
+	if value, _ := web.AppConfig.String("ratelimitdefault"); value != "" {
+		policy, err := ParseRateLimitPolicy("ip", value)
+		if err != nil {
+			return err
+		}
+		cfg.Default = policy
+	}
+
+	if value, _ := web.AppConfig.String("ratelimituser"); value != "" {
+		policy, err := ParseRateLimitPolicy("user", value)
+		if err != nil {
+			return err
+		}
+		cfg.User = policy
+	}
+
+	if value, _ := web.AppConfig.String("ratelimitroutes"); value != "" {
+		for _, item := range strings.Split(value, ";") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			parts := strings.SplitN(item, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid ratelimitroutes entry %q", item)
+			}
+			route := strings.TrimSpace(parts[0])
+			policy, err := ParseRateLimitPolicy(route, parts[1])
+			if err != nil {
+				return err
+			}
+			cfg.Routes[route] = policy
+		}
+	}
+
+	if value, _ := web.AppConfig.String("trustedproxies"); value != "" {
+		for _, proxy := range strings.Split(value, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+			}
+		}
+	}
+
+	var store RateLimitStore
+	backend, _ := web.AppConfig.String("ratelimitbackend")
+	switch backend {
+	case "", "memory":
+		store = NewMemoryRateLimitStore()
+	case "redis":
+		addr, _ := web.AppConfig.String("ratelimitredis")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+		password, _ := web.AppConfig.String("ratelimitredispassword")
+		store = NewRedisRateLimitStore(addr, password)
+	default:
+		return fmt.Errorf("unknown ratelimitbackend %q", backend)
+	}
+
+	previous := limiter
+	limiter = NewLimiter(store, cfg)
+	if previous != nil {
+		if closer, ok := previous.store.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return nil
+}
+
+// ParseRateLimitPolicy 解析形如 "10/s:20" 的策略，表示每秒10个请求、突发容量20
+func ParseRateLimitPolicy(name, value string) (RateLimitPolicy, error) {
+	policy := RateLimitPolicy{Name: name}
+	value = strings.TrimSpace(value)
+
+	ratePart, burstPart := value, ""
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		ratePart, burstPart = value[:i], value[i+1:]
+	}
+
+	parts := strings.SplitN(ratePart, "/", 2)
+	if len(parts) != 2 {
+		return policy, fmt.Errorf("invalid rate limit policy %q", value)
+	}
+	count, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || count <= 0 {
+		return policy, fmt.Errorf("invalid rate limit policy %q", value)
+	}
+
+	var per time.Duration
+	switch parts[1] {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return policy, fmt.Errorf("invalid rate limit unit in %q", value)
+	}
+	policy.Rate = count / per.Seconds()
+
+	policy.Burst = int(math.Ceil(count))
+	if burstPart != "" {
+		policy.Burst, err = strconv.Atoi(burstPart)
+		if err != nil || policy.Burst < 1 {
+			return policy, fmt.Errorf("invalid rate limit burst in %q", value)
+		}
+	}
+
+	return policy, nil
+}
+
+// RateLimiter 限流中间件
+func RateLimiter(ctx *beecontext.Context) {
+	if limiter == nil {
+		return
+	}
+	limiter.Filter(ctx)
+}
+
+// Filter 对请求执行限流并写入RateLimit响应头
+func (l *Limiter) Filter(ctx *beecontext.Context) {
+	if ctx.Input.Method() == http.MethodOptions {
+		return
+	}
+
+	path := ctx.Request.URL.Path
+	policy, identity := l.resolve(ctx, path)
+
+	result, err := l.store.Take(ctx.Request.Context(), policy.Name+":"+identity, policy)
+	if err != nil {
+		// 存储不可用时放行，避免限流组件故障导致整体不可用
+		logs.Error("Rate limit store error: %v", err)
+		return
+	}
+
+	ctx.Output.Header("RateLimit-Limit", strconv.Itoa(policy.Burst))
+	ctx.Output.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Output.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	ctx.Output.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Burst, ceilSeconds(time.Duration(float64(policy.Burst)/policy.Rate*float64(time.Second)))))
+
+	if !result.Allowed {
+		ctx.Output.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		abort(ctx, http.StatusTooManyRequests, "rate_limited")
+		return
+	}
+}
+
+// resolve 选择适用的策略和限流主体：路由策略优先，其次按登录用户，最后按客户端IP
+func (l *Limiter) resolve(ctx *beecontext.Context, path string) (RateLimitPolicy, string) {
+	identity := "ip:" + l.ClientIP(ctx.Request)
+	userIdentity := ""
+	if userID := l.userID(ctx); userID > 0 {
+		userIdentity = "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+
+	for _, rp := range l.routes {
+		if (rp.prefix && strings.HasPrefix(path, rp.pattern)) || (!rp.prefix && path == rp.pattern) {
+			if userIdentity != "" {
+				return rp.policy, userIdentity
+			}
+			return rp.policy, identity
+		}
+	}
+
+	if userIdentity != "" {
+		return l.userPolicy, userIdentity
+	}
+	return l.defaultPolicy, identity
+}
+
+// userID 限流在JWT过滤器之前执行，这里直接解析令牌获取用户
+func (l *Limiter) userID(ctx *beecontext.Context) uint {
+	parts := strings.SplitN(ctx.Input.Header("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return 0
+	}
+	claims, err := ParseToken(parts[1])
+	if err != nil || claims == nil {
+		return 0
+	}
+	return claims.UserID
+}
+
+// ClientIP 按当前限流器的可信代理配置获取客户端IP
+func ClientIP(r *http.Request) string {
+	if limiter == nil {
+		// 未初始化时不信任任何代理
+		return (&Limiter{}).ClientIP(r)
+	}
+	return limiter.ClientIP(r)
+}
+
+// ClientIP 获取客户端真实IP，仅当直连地址为可信代理时才采信X-Forwarded-For
+func (l *Limiter) ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !l.isTrusted(remote) {
+		return remote
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	// 从右向左跳过可信代理，第一个不可信的地址即为客户端
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if !l.isTrusted(ip) {
+			return ip
+		}
+		remote = ip
+	}
+	return remote
+}
+
+func (l *Limiter) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range l.trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseIPNet(value string) *net.IPNet {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil
+		}
+		return network
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	bits := 32
+	if ip.To4() == nil {
+		bits = 128
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// bucketResult 根据取令牌后的剩余数量计算结果
+func bucketResult(tokens float64, allowed bool, policy RateLimitPolicy) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(policy.Burst) - tokens) / policy.Rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / policy.Rate * float64(time.Second))
+	}
+	return result
+}
+
+// MemoryRateLimitStore 单实例内存令牌桶存储
+type MemoryRateLimitStore struct {
 	sync.Mutex
-	ipRequestCount map[string]int
-	ipLastRequest  map[string]time.Time
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+	done    chan struct{}
+	once    sync.Once
 }
 
-var limiter = &IPRateLimiter{
-	ipRequestCount: make(map[string]int),
-	ipLastRequest:  make(map[string]time.Time),
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	idle   time.Duration
 }
 
-// 清理过期的IP请求记录
-func init() {
+// NewMemoryRateLimitStore 创建内存存储，并定期清理已恢复满的令牌桶，不再使用时需调用Close
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+		done:    make(chan struct{}),
+	}
 	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
 		for {
-			time.Sleep(5 * time.Minute)
-			limiter.Lock()
-			for ip, lastTime := range limiter.ipLastRequest {
-				if time.Since(lastTime) > 5*time.Minute {
-					delete(limiter.ipRequestCount, ip)
-					delete(limiter.ipLastRequest, ip)
-				}
+			select {
+			case <-ticker.C:
+				s.cleanup()
+			case <-s.done:
+				return
 			}
-			limiter.Unlock()
 		}
 	}()
+	return s
 }
 
-// RateLimiter 限流中间件
-func RateLimiter(ctx *context.Context) {
-	ip := ctx.Input.IP()
-	
-	limiter.Lock()
-	defer limiter.Unlock()
-	
-	// 检查IP的请求频率
-	now := time.Now()
-	if lastTime, exists := limiter.ipLastRequest[ip]; exists {
-		if now.Sub(lastTime) < time.Second { // 1秒内
-			count := limiter.ipRequestCount[ip]
-			if count > 10 { // 单个IP 1秒内最多10个请求
-				ctx.Output.SetStatus(429)
-				ctx.Output.JSON(map[string]interface{}{
-					"code":    429,
-					"message": "请求过于频繁，请稍后再试",
-				}, true, false)
-				return
-			}
-			limiter.ipRequestCount[ip] = count + 1
-		} else {
-			// 重置计数
-			limiter.ipRequestCount[ip] = 1
+// Close 停止后台清理，可重复调用
+func (s *MemoryRateLimitStore) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	return nil
+}
+
+func (s *MemoryRateLimitStore) cleanup() {
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.last) > bucket.idle {
+			delete(s.buckets, key)
 		}
-	} else {
-		limiter.ipRequestCount[ip] = 1
 	}
-	
-	limiter.ipLastRequest[ip] = now
-} 
\ No newline at end of file
+}
+
+// Take 从令牌桶中取一个令牌
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: float64(policy.Burst),
+			last:   now,
+			idle:   time.Duration(float64(policy.Burst) / policy.Rate * float64(time.Second)),
+		}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.last).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(float64(policy.Burst), bucket.tokens+elapsed*policy.Rate)
+	}
+	bucket.last = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	return bucketResult(bucket.tokens, allowed, policy), nil
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"blog/i18n"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// abort 以统一格式返回错误并结束过滤器链，消息按Accept-Language渲染
+func abort(ctx *context.Context, status int, errorCode string) {
+	lang := i18n.Negotiate(ctx.Input.Header("Accept-Language"))
+	ctx.Output.SetStatus(status)
+	ctx.Output.JSON(map[string]interface{}{
+		"code":       status,
+		"message":    i18n.T(lang, errorCode),
+		"error_code": errorCode,
+	}, true, false)
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	beecontext "github.com/beego/beego/v2/server/web/context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName HTTP与SQL追踪使用的Tracer名称
+const TracerName = "finwise"
+
+// InitTracing 根据配置初始化OTLP链路追踪，返回的函数用于在退出时刷新并关闭导出器
+//
+// 配置项：
+//
+//	tracingenabled = true
+//	otlpendpoint   = localhost:4318
+//	otlpinsecure   = true
+//	tracesampleratio = 1.0
+func InitTracing() (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	enabled, _ := web.AppConfig.Bool("tracingenabled")
+	if !enabled {
+		return noop, nil
+	}
+
+	endpoint, _ := web.AppConfig.String("otlpendpoint")
+	if endpoint == "" {
+		endpoint = "localhost:4318"
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if insecure, _ := web.AppConfig.Bool("otlpinsecure"); insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return noop, fmt.Errorf("create otlp exporter: %w", err)
+	}
+
+	ratio, err := web.AppConfig.Float("tracesampleratio")
+	if err != nil {
+		ratio = 1
+	}
+
+	tp := NewTracerProvider(exporter, sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))))
+	logs.Info("Tracing enabled, exporting to %s", endpoint)
+
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider 使用指定导出器创建并注册全局TracerProvider，测试中可传入内存导出器
+func NewTracerProvider(exporter sdktrace.SpanExporter, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	serviceName, _ := web.AppConfig.String("appname")
+	if serviceName == "" {
+		serviceName = "finwise"
+	}
+
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(5*time.Second)),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String(serviceName))),
+	}, opts...)
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp
+}
+
+// sensitiveQueryParams 记录到span前需要隐藏的查询参数，如事件流票据和邮件链接中的令牌
+var sensitiveQueryParams = []string{"ticket", "token", "access_token"}
+
+// redactRequest 返回隐藏了敏感查询参数的请求副本，只用于生成span属性
+func redactRequest(r *http.Request) *http.Request {
+	query := r.URL.Query()
+	redacted := false
+	for _, name := range sensitiveQueryParams {
+		if _, ok := query[name]; ok {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return r
+	}
+
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	clone := r.Clone(r.Context())
+	clone.URL = &u
+	clone.RequestURI = u.RequestURI()
+	return clone
+}
+
+// TracingChain 为每个HTTP请求创建span，需通过InsertFilterChain注册以包裹整个过滤器链
+func TracingChain(next web.FilterFunc) web.FilterFunc {
+	return func(ctx *beecontext.Context) {
+		r := ctx.Request
+		parent := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		// http.target包含完整的查询参数，会随span导出
+		attrReq := redactRequest(r)
+		spanCtx, span := otel.Tracer(TracerName).Start(parent, "HTTP "+r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", attrReq)...),
+			trace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(attrReq)...),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", "", attrReq)...),
+		)
+		defer span.End()
+
+		ctx.Request = r.WithContext(spanCtx)
+		next(ctx)
+
+		// 路由匹配和JWT认证都在next中完成，结束时再补充属性
+		if pattern, ok := ctx.Input.GetData("RouterPattern").(string); ok && pattern != "" {
+			span.SetName("HTTP " + r.Method + " " + pattern)
+			span.SetAttributes(semconv.HTTPRouteKey.String(pattern))
+		}
+		if userID, ok := ctx.Input.GetData("user_id").(uint); ok {
+			span.SetAttributes(attribute.Int64("enduser.id", int64(userID)))
+		}
+
+		status := ctx.ResponseWriter.Status
+		if status == 0 {
+			status = ctx.Output.Status
+		}
+		if status == 0 {
+			status = 200
+		}
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+		if code, _ := semconv.SpanStatusFromHTTPStatusCode(status); code == codes.Error {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+	}
+}
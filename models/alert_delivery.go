@@ -0,0 +1,117 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 预算告警通知的投递状态
+const (
+	AlertDeliveryPending   = "pending"
+	AlertDeliverySucceeded = "succeeded"
+	AlertDeliveryFailed    = "failed"
+)
+
+// AlertDelivery 预算告警触发记录在一个通知渠道上的投递状态
+//
+// 投递记录与触发记录在同一事务中写入，进程在投递前退出或渠道暂时失败时由重试循环补发，
+// 因此每个阈值在每个渠道上至少通知一次；渠道在记录结果前失败时可能重复通知。
+type AlertDelivery struct {
+	ID            uint
+	EventID       uint
+	Channel       string
+	Status        string
+	Attempts      int
+	Error         string
+	NextAttemptAt *time.Time
+}
+
+// ClaimAlertDelivery 开始一次投递：增加尝试次数并把下次尝试时间推迟lease，
+// 多个实例同时处理时只有一个能占用；投递中途退出的记录在lease之后会被重试
+func ClaimAlertDelivery(ctx context.Context, d *AlertDelivery, lease time.Duration) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	result, err := dbExec(ctx, `
+		UPDATE budget_alert_deliveries SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND attempts = ?
+	`, now.Add(lease), now, d.ID, AlertDeliveryPending, d.Attempts)
+	if err != nil {
+		logs.Error("Error claiming alert delivery: %v", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	d.Attempts++
+	return true, nil
+}
+
+// RecordAlertDelivery 保存投递结果，d的状态、错误和下次尝试时间由调用方设置
+func RecordAlertDelivery(ctx context.Context, d *AlertDelivery) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := dbExec(ctx, `
+		UPDATE budget_alert_deliveries
+		SET status = ?, error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`, d.Status, d.Error, d.NextAttemptAt, time.Now(), d.ID)
+	if err != nil {
+		logs.Error("Error recording alert delivery: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetDueAlertDeliveries 获取到达重试时间的投递记录，按触发记录分组的顺序返回
+func GetDueAlertDeliveries(ctx context.Context, now time.Time, limit int) ([]*AlertDelivery, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT id, event_id, channel, status, attempts, error, next_attempt_at
+		FROM budget_alert_deliveries
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY event_id, id
+		LIMIT ?
+	`, AlertDeliveryPending, now, limit)
+	if err != nil {
+		logs.Error("Error querying due alert deliveries: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*AlertDelivery, 0)
+	for rows.Next() {
+		d := &AlertDelivery{}
+		var nextAttemptAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.EventID, &d.Channel, &d.Status, &d.Attempts, &d.Error, &nextAttemptAt); err != nil {
+			logs.Error("Error scanning alert delivery row: %v", err)
+			return nil, err
+		}
+		if nextAttemptAt.Valid {
+			d.NextAttemptAt = &nextAttemptAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating alert delivery rows: %v", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
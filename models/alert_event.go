@@ -0,0 +1,272 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// BudgetAlertEvent 预算告警触发记录，每个告警的每个阈值在预算周期内只记录一次
+type BudgetAlertEvent struct {
+	ID           uint      `json:"id"`
+	UserID       uint      `json:"user_id"`
+	AlertID      uint      `json:"alert_id"`
+	BudgetID     uint      `json:"budget_id"`
+	CategoryID   uint      `json:"category_id,omitempty"`
+	CategoryName string    `json:"category_name,omitempty"` // 分类名称，组合预算为预算名称
+	Threshold    int       `json:"threshold"`
+	Month        string    `json:"month"`        // 预算周期开始的月份
+	PeriodLabel  string    `json:"period_label"` // 预算周期，月度预算为YYYY-MM，其他为起止日期
+	UsedAmount   Money     `json:"used_amount"`
+	BudgetAmount Money     `json:"budget_amount"` // 预算可用金额，包含从上月结转的金额
+	UsedPercent  float64   `json:"used_percent"`
+	TriggeredAt  time.Time `json:"triggered_at"` // 首次触发时间
+
+	Deliveries []*AlertDelivery `json:"-"` // 本次新触发时各通知渠道的投递记录
+}
+
+// Exceeded 是否为100%阈值，即预算已用完
+func (e *BudgetAlertEvent) Exceeded() bool {
+	return e.Threshold >= 100
+}
+
+// Period 预算周期的显示名称，没有记录周期时为月份
+func (e *BudgetAlertEvent) Period() string {
+	if e.PeriodLabel != "" {
+		return e.PeriodLabel
+	}
+	return e.Month
+}
+
+// EvaluateBudgetAlerts 检查周期与月份重叠的预算上激活的告警，记录首次达到的阈值并返回本次新触发的事件
+//
+// 已记录过的阈值不会再次返回，因此每个阈值只通知一次；使用额回落后再次超过也不会重复触发。
+// 触发记录与channels中每个通知渠道的待投递记录在同一事务中写入，投递失败时可以重试。
+func EvaluateBudgetAlerts(ctx context.Context, userID uint, month string, channels []string) ([]*BudgetAlertEvent, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	budgets, err := GetBudgets(ctx, userID, month)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*Budget, len(budgets))
+	for _, budget := range budgets {
+		byID[budget.ID] = budget
+	}
+
+	parsed, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, ErrInvalidMonth
+	}
+	monthStart, monthEnd := monthRange(parsed.Year(), parsed.Month())
+
+	// 尚未记录触发的激活告警
+	rows, err := dbQuery(ctx, `
+		SELECT ba.id, ba.budget_id, ba.threshold
+		FROM budget_alerts ba
+		JOIN budgets b ON ba.budget_id = b.id
+		LEFT JOIN budget_alert_events e ON e.alert_id = ba.id AND e.threshold = ba.threshold AND e.month = b.month
+		WHERE ba.user_id = ? AND ba.is_active = 1 AND b.start_date <= ? AND b.end_date >= ? AND e.id IS NULL
+		ORDER BY ba.threshold
+	`, userID, monthEnd, monthStart)
+	if err != nil {
+		logs.Error("Error querying pending alerts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]*BudgetAlertEvent, 0)
+	for rows.Next() {
+		event := &BudgetAlertEvent{UserID: userID}
+		if err := rows.Scan(&event.AlertID, &event.BudgetID, &event.Threshold); err != nil {
+			logs.Error("Error scanning pending alert: %v", err)
+			return nil, err
+		}
+
+		budget, ok := byID[event.BudgetID]
+		// 用整数比较，避免百分比舍入导致临界值误判
+		if !ok || budget.UsedAmount*100 < budget.Available*Money(event.Threshold) {
+			continue
+		}
+		event.Month = budget.Month.Format("2006-01")
+		event.PeriodLabel = budget.PeriodLabel()
+		event.CategoryID = budget.CategoryID
+		event.CategoryName = budget.DisplayName()
+		event.UsedAmount = budget.UsedAmount
+		event.BudgetAmount = budget.Available
+		event.UsedPercent = budget.Percentage
+		candidates = append(candidates, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating pending alerts: %v", err)
+		return nil, err
+	}
+
+	// 唯一键保证并发检查时同一阈值只有一次插入成功
+	events := make([]*BudgetAlertEvent, 0, len(candidates))
+	for _, event := range candidates {
+		recorded, err := recordBudgetAlertEvent(ctx, event, channels)
+		if err != nil {
+			return nil, err
+		}
+		if recorded {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// recordBudgetAlertEvent 在一个事务中写入触发记录和各渠道的待投递记录，阈值已记录过时返回false
+func recordBudgetAlertEvent(ctx context.Context, event *BudgetAlertEvent, channels []string) (bool, error) {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Error("Error starting transaction: %v", err)
+		return false, err
+	}
+	defer tx.Rollback()
+
+	event.TriggeredAt = time.Now()
+	result, err := txExec(ctx, tx, `
+		INSERT INTO budget_alert_events
+			(user_id, alert_id, budget_id, threshold, month, used_amount, budget_amount, triggered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, event.UserID, event.AlertID, event.BudgetID, event.Threshold, event.Month+"-01",
+		event.UsedAmount, event.BudgetAmount, event.TriggeredAt)
+	if isDuplicateEntry(err) {
+		return false, nil
+	}
+	if err != nil {
+		logs.Error("Error recording alert event: %v", err)
+		return false, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logs.Error("Error getting alert event ID: %v", err)
+		return false, err
+	}
+	event.ID = uint(id)
+
+	event.Deliveries = make([]*AlertDelivery, 0, len(channels))
+	for _, channel := range channels {
+		now := event.TriggeredAt
+		result, err := txExec(ctx, tx, `
+			INSERT INTO budget_alert_deliveries (event_id, channel, status, next_attempt_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, event.ID, channel, AlertDeliveryPending, now, now, now)
+		if err != nil {
+			logs.Error("Error creating alert delivery: %v", err)
+			return false, err
+		}
+		deliveryID, err := result.LastInsertId()
+		if err != nil {
+			logs.Error("Error getting alert delivery ID: %v", err)
+			return false, err
+		}
+		event.Deliveries = append(event.Deliveries, &AlertDelivery{
+			ID:            uint(deliveryID),
+			EventID:       event.ID,
+			Channel:       channel,
+			Status:        AlertDeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		logs.Error("Error committing alert event: %v", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetBudgetAlertEvents 获取周期与月份重叠的预算的告警触发记录，month为空时返回最近的记录
+func GetBudgetAlertEvents(ctx context.Context, userID uint, month string, limit int) ([]*BudgetAlertEvent, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := alertEventSelect + " WHERE e.user_id = ?"
+	args := []interface{}{userID}
+	if month != "" {
+		parsed, err := time.Parse("2006-01", month)
+		if err != nil {
+			return nil, ErrInvalidMonth
+		}
+		start, end := monthRange(parsed.Year(), parsed.Month())
+		query += " AND b.start_date <= ? AND b.end_date >= ?"
+		args = append(args, end, start)
+	}
+	query += " ORDER BY e.triggered_at DESC, e.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := dbQuery(ctx, query, args...)
+	if err != nil {
+		logs.Error("Error querying alert events: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]*BudgetAlertEvent, 0)
+	for rows.Next() {
+		event, err := scanBudgetAlertEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating alert event rows: %v", err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetBudgetAlertEvent 获取告警触发记录，用于重试投递
+func GetBudgetAlertEvent(ctx context.Context, id uint) (*BudgetAlertEvent, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	event, err := scanBudgetAlertEvent(dbQueryRow(ctx, alertEventSelect+" WHERE e.id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAlertEventNotFound
+	}
+	return event, err
+}
+
+// alertEventSelect 查询告警触发记录及其预算名称和周期，与scanBudgetAlertEvent对应
+const alertEventSelect = `
+	SELECT e.id, e.user_id, e.alert_id, e.budget_id, b.category_id, COALESCE(NULLIF(b.name, ''), c.name), e.threshold,
+	       DATE_FORMAT(e.month, '%Y-%m'), b.period, DATE_FORMAT(b.start_date, '%Y-%m-%d'), DATE_FORMAT(b.end_date, '%Y-%m-%d'),
+	       e.used_amount, e.budget_amount, e.triggered_at
+	FROM budget_alert_events e
+	JOIN budgets b ON e.budget_id = b.id
+	LEFT JOIN categories c ON b.category_id = c.id`
+
+// scanBudgetAlertEvent 扫描一行alertEventSelect的结果
+func scanBudgetAlertEvent(row interface{ Scan(...interface{}) error }) (*BudgetAlertEvent, error) {
+	event := &BudgetAlertEvent{}
+	var categoryID sql.NullInt64
+	var categoryName sql.NullString
+	var period, startStr, endStr string
+	err := row.Scan(&event.ID, &event.UserID, &event.AlertID, &event.BudgetID, &categoryID, &categoryName,
+		&event.Threshold, &event.Month, &period, &startStr, &endStr, &event.UsedAmount, &event.BudgetAmount, &event.TriggeredAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logs.Error("Error scanning alert event row: %v", err)
+		}
+		return nil, err
+	}
+	start, _ := time.Parse("2006-01-02", startStr)
+	end, _ := time.Parse("2006-01-02", endStr)
+	event.PeriodLabel = budgetPeriodLabel(period, start, end)
+	event.CategoryID = uint(categoryID.Int64)
+	event.CategoryName = categoryName.String
+	event.UsedPercent = BudgetPercentage(event.UsedAmount, event.BudgetAmount)
+	return event, nil
+}
@@ -1,8 +1,10 @@
 package models
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/beego/beego/v2/core/logs"
@@ -13,76 +15,149 @@ type Bill struct {
 	ID          uint      `json:"id"`
 	UserID      uint      `json:"user_id"`
 	CategoryID  uint      `json:"category_id"`
-	Amount      float64   `json:"amount"`
+	Amount      Money     `json:"amount"`
 	Type        string    `json:"type"` // income or expense
 	Date        time.Time `json:"date"`
 	Description string    `json:"description,omitempty"`
+	Merchant    string    `json:"merchant,omitempty"`
+	Tags        []string  `json:"tags"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	// 关联字段
 	CategoryName string `json:"category_name,omitempty"`
 	CategoryIcon string `json:"category_icon,omitempty"`
+	// 搜索时命中字段的高亮片段，已做HTML转义，命中部分以<em>标记
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
 
 // BillRequest 账单请求参数
 type BillRequest struct {
-	CategoryID  uint    `json:"category_id" valid:"Required"`
-	Amount      float64 `json:"amount" valid:"Required"`
-	Type        string  `json:"type" valid:"Required;Match(income|expense)"`
-	Date        string  `json:"date" valid:"Required"`
-	Description string  `json:"description,omitempty"`
+	CategoryID  uint     `json:"category_id" valid:"Required"`
+	Amount      Money    `json:"amount" valid:"Required;Money"`
+	Type        string   `json:"type" valid:"Required;Match(/^(income|expense)$/)"`
+	Date        string   `json:"date" valid:"Required;DateTime"` // YYYY-MM-DD 或 RFC3339
+	Description string   `json:"description,omitempty" valid:"MaxSize(1000)"`
+	Merchant    string   `json:"merchant,omitempty" valid:"MaxSize(100)"`
+	Tags        []string `json:"tags"` // 为null时更新账单不修改标签
 }
 
 // BillQueryParams 账单查询参数
 type BillQueryParams struct {
-	StartDate  string
-	EndDate    string
-	Type       string
-	CategoryID uint
-	MinAmount  float64
-	MaxAmount  float64
-	Page       int
-	PageSize   int
+	StartDate  string  `json:"start_date" valid:"Date"`
+	EndDate    string  `json:"end_date" valid:"Date"`
+	Type       string  `json:"type" valid:"Match(/^(income|expense)?$/)"`
+	CategoryID uint    `json:"category_id"`
+	MinAmount  Money   `json:"min_amount"`
+	MaxAmount  Money   `json:"max_amount"`
+	Q          string  `json:"q" valid:"MaxSize(100)"` // 搜索描述、商户、分类名称和标签，多个关键词以空格分隔
+	Page       int     `json:"page"`
+	PageSize   int     `json:"page_size"`
+	Sort       string  `json:"sort" valid:"Match(/^(-?(date|amount|created_at))?$/)"` // 排序字段，前加-表示降序，默认-date
+	Cursor     string  `json:"cursor"`     // 上一页返回的游标，为空表示第一页
+	UseCursor  bool    `json:"-"`          // 使用游标分页而不是页码分页
+	WithTotal  bool    `json:"with_total"` // 是否统计总数
+}
+
+// BillPage 账单分页查询结果
+type BillPage struct {
+	Bills      []*Bill
+	Total      int // 仅在WithTotal时有效
+	HasMore    bool
+	NextCursor string // 仅在游标分页时有效
+}
+
+// billSortColumns 支持排序的字段
+var billSortColumns = map[string]string{
+	"date":       "b.date",
+	"amount":     "b.amount",
+	"created_at": "b.created_at",
+}
+
+// sortOrder 解析排序参数，返回排序字段、对应的列和是否降序
+func (p *BillQueryParams) sortOrder() (string, string, bool) {
+	sort := p.Sort
+	if sort == "" {
+		sort = "-date"
+	}
+	desc := strings.HasPrefix(sort, "-")
+	field := strings.TrimPrefix(sort, "-")
+	column, ok := billSortColumns[field]
+	if !ok {
+		return "date", billSortColumns["date"], true
+	}
+	return field, column, desc
+}
+
+// cursorFor 生成指向账单的游标
+func cursorFor(bill *Bill, sort, field string) *Cursor {
+	c := &Cursor{Sort: sort, ID: bill.ID}
+	switch field {
+	case "amount":
+		c.Value = bill.Amount.String()
+	case "created_at":
+		c.Value = bill.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		c.Value = bill.Date.Format("2006-01-02")
+	}
+	return c
 }
 
 // CreateBill 创建账单
-func CreateBill(userID uint, req *BillRequest) (*Bill, error) {
+func CreateBill(ctx context.Context, userID uint, req *BillRequest) (*Bill, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查分类是否存在且属于该用户
 	var categoryExists bool
 	var categoryType string
-	err := DB.QueryRow(
+	err := dbQueryRow(ctx, 
 		"SELECT EXISTS(SELECT 1 FROM categories WHERE id = ? AND user_id = ?), type FROM categories WHERE id = ?",
 		req.CategoryID, userID, req.CategoryID,
 	).Scan(&categoryExists, &categoryType)
 	
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotOwned
+	}
 	if err != nil {
 		logs.Error("Error checking category: %v", err)
 		return nil, err
 	}
 	
 	if !categoryExists {
-		return nil, errors.New("分类不存在或不属于当前用户")
+		return nil, ErrCategoryNotOwned
 	}
 	
 	// 确保账单类型与分类类型一致
 	if categoryType != req.Type {
-		return nil, errors.New("账单类型与分类类型不一致")
+		return nil, ErrBillTypeMismatch
 	}
 	
-	// 解析日期
-	date, err := time.Parse("2006-01-02", req.Date)
+	// 解析日期，时间戳按用户时区换算
+	loc, err := UserLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	date, err := ParseBillDate(req.Date, loc)
 	if err != nil {
 		logs.Error("Error parsing date: %v", err)
-		return nil, errors.New("日期格式错误，正确格式为：YYYY-MM-DD")
+		return nil, err
+	}
+	
+	// 开始事务
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Error("Error starting transaction: %v", err)
+		return nil, err
 	}
 	
 	// 创建账单
-	result, err := DB.Exec(
-		"INSERT INTO bills (user_id, category_id, amount, type, date, description) VALUES (?, ?, ?, ?, ?, ?)",
-		userID, req.CategoryID, req.Amount, req.Type, date, req.Description,
+	result, err := txExec(ctx, tx, 
+		"INSERT INTO bills (user_id, category_id, amount, type, date, description, merchant) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		userID, req.CategoryID, req.Amount, req.Type, date.Format("2006-01-02"), req.Description, req.Merchant,
 	)
 	
 	if err != nil {
+		tx.Rollback()
 		logs.Error("Error creating bill: %v", err)
 		return nil, err
 	}
@@ -90,12 +165,24 @@ func CreateBill(userID uint, req *BillRequest) (*Bill, error) {
 	// 获取账单ID
 	billID, err := result.LastInsertId()
 	if err != nil {
+		tx.Rollback()
 		logs.Error("Error getting bill ID: %v", err)
 		return nil, err
 	}
 	
+	// 保存标签
+	if err := saveBillTags(ctx, tx, uint(billID), req.Tags); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	
+	if err := tx.Commit(); err != nil {
+		logs.Error("Error committing transaction: %v", err)
+		return nil, err
+	}
+	
 	// 获取完整的账单信息
-	bill, err := GetBill(uint(billID), userID)
+	bill, err := GetBill(ctx, uint(billID), userID)
 	if err != nil {
 		logs.Error("Error fetching new bill: %v", err)
 		return nil, err
@@ -105,13 +192,16 @@ func CreateBill(userID uint, req *BillRequest) (*Bill, error) {
 }
 
 // GetBill 获取单个账单
-func GetBill(id, userID uint) (*Bill, error) {
+func GetBill(ctx context.Context, id, userID uint) (*Bill, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	bill := &Bill{}
 	var dateStr string
 	
-	err := DB.QueryRow(`
+	err := dbQueryRow(ctx, `
 		SELECT b.id, b.user_id, b.category_id, b.amount, b.type, 
-		       DATE_FORMAT(b.date, '%Y-%m-%d'), b.description, 
+		       DATE_FORMAT(b.date, '%Y-%m-%d'), b.description, b.merchant, 
 		       b.created_at, b.updated_at, c.name, c.icon 
 		FROM bills b
 		LEFT JOIN categories c ON b.category_id = c.id
@@ -124,6 +214,7 @@ func GetBill(id, userID uint) (*Bill, error) {
 		&bill.Type,
 		&dateStr,
 		&bill.Description,
+		&bill.Merchant,
 		&bill.CreatedAt,
 		&bill.UpdatedAt,
 		&bill.CategoryName,
@@ -132,7 +223,7 @@ func GetBill(id, userID uint) (*Bill, error) {
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("账单不存在")
+			return nil, ErrBillNotFound
 		}
 		logs.Error("Error querying bill: %v", err)
 		return nil, err
@@ -145,90 +236,133 @@ func GetBill(id, userID uint) (*Bill, error) {
 		return nil, err
 	}
 	
+	// 加载标签
+	if err := loadBillTags(ctx, []*Bill{bill}); err != nil {
+		return nil, err
+	}
+	
 	return bill, nil
 }
 
-// GetBills 获取账单列表
-func GetBills(userID uint, params *BillQueryParams) ([]*Bill, int, error) {
-	// 构建查询条件
-	query := `
-		SELECT b.id, b.user_id, b.category_id, b.amount, b.type, 
-		       DATE_FORMAT(b.date, '%Y-%m-%d'), b.description, 
-		       b.created_at, b.updated_at, c.name, c.icon 
-		FROM bills b
-		LEFT JOIN categories c ON b.category_id = c.id
-		WHERE b.user_id = ?
-	`
-	countQuery := "SELECT COUNT(*) FROM bills b WHERE b.user_id = ?"
+// filter 生成账单筛选条件，查询需以b为账单表别名并LEFT JOIN分类表c
+func (p *BillQueryParams) filter(userID uint) (string, []interface{}) {
+	where := " WHERE b.user_id = ?"
 	args := []interface{}{userID}
-	countArgs := []interface{}{userID}
 	
-	// 添加筛选条件
-	if params.StartDate != "" {
-		query += " AND b.date >= ?"
-		countQuery += " AND b.date >= ?"
-		args = append(args, params.StartDate)
-		countArgs = append(countArgs, params.StartDate)
+	if p.StartDate != "" {
+		where += " AND b.date >= ?"
+		args = append(args, p.StartDate)
 	}
 	
-	if params.EndDate != "" {
-		query += " AND b.date <= ?"
-		countQuery += " AND b.date <= ?"
-		args = append(args, params.EndDate)
-		countArgs = append(countArgs, params.EndDate)
+	if p.EndDate != "" {
+		where += " AND b.date <= ?"
+		args = append(args, p.EndDate)
 	}
 	
-	if params.Type != "" {
-		query += " AND b.type = ?"
-		countQuery += " AND b.type = ?"
-		args = append(args, params.Type)
-		countArgs = append(countArgs, params.Type)
+	if p.Type != "" {
+		where += " AND b.type = ?"
+		args = append(args, p.Type)
 	}
 	
-	if params.CategoryID > 0 {
-		query += " AND b.category_id = ?"
-		countQuery += " AND b.category_id = ?"
-		args = append(args, params.CategoryID)
-		countArgs = append(countArgs, params.CategoryID)
+	if p.CategoryID > 0 {
+		where += " AND b.category_id = ?"
+		args = append(args, p.CategoryID)
 	}
 	
-	if params.MinAmount > 0 {
-		query += " AND b.amount >= ?"
-		countQuery += " AND b.amount >= ?"
-		args = append(args, params.MinAmount)
-		countArgs = append(countArgs, params.MinAmount)
+	if p.MinAmount > 0 {
+		where += " AND b.amount >= ?"
+		args = append(args, p.MinAmount)
 	}
 	
-	if params.MaxAmount > 0 {
-		query += " AND b.amount <= ?"
-		countQuery += " AND b.amount <= ?"
-		args = append(args, params.MaxAmount)
-		countArgs = append(countArgs, params.MaxAmount)
+	if p.MaxAmount > 0 {
+		where += " AND b.amount <= ?"
+		args = append(args, p.MaxAmount)
 	}
 	
-	// 添加排序
-	query += " ORDER BY b.date DESC, b.id DESC"
+	// 全文搜索
+	if terms := SplitSearchTerms(p.Q); len(terms) > 0 {
+		condition, conditionArgs := searchCondition(terms)
+		where += condition
+		args = append(args, conditionArgs...)
+	}
 	
-	// 添加分页
-	if params.Page > 0 && params.PageSize > 0 {
-		offset := (params.Page - 1) * params.PageSize
-		query += " LIMIT ? OFFSET ?"
-		args = append(args, params.PageSize, offset)
+	return where, args
+}
+
+// GetBills 获取账单列表，支持页码分页和基于(排序字段, id)的游标分页
+func GetBills(ctx context.Context, userID uint, params *BillQueryParams) (*BillPage, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// 构建筛选条件
+	where, args := params.filter(userID)
+	terms := SplitSearchTerms(params.Q)
+	
+	page := &BillPage{}
+	
+	// 获取总数，游标分页默认不统计
+	if params.WithTotal {
+		err := dbQueryRow(ctx, "SELECT COUNT(*) FROM bills b LEFT JOIN categories c ON b.category_id = c.id"+where, args...).Scan(&page.Total)
+		if err != nil {
+			logs.Error("Error counting bills: %v", err)
+			return nil, err
+		}
 	}
 	
-	// 获取总数
-	var total int
-	err := DB.QueryRow(countQuery, countArgs...).Scan(&total)
-	if err != nil {
-		logs.Error("Error counting bills: %v", err)
-		return nil, 0, err
+	field, column, desc := params.sortOrder()
+	sort := field
+	order, cmp := "ASC", ">"
+	if desc {
+		sort = "-" + field
+		order, cmp = "DESC", "<"
+	}
+	
+	query := `
+		SELECT b.id, b.user_id, b.category_id, b.amount, b.type, 
+		       DATE_FORMAT(b.date, '%Y-%m-%d'), b.description, b.merchant, 
+		       b.created_at, b.updated_at, c.name, c.icon 
+		FROM bills b
+		LEFT JOIN categories c ON b.category_id = c.id
+	` + where
+	queryArgs := append([]interface{}{}, args...)
+	
+	// 游标分页：从上一页最后一条记录之后继续
+	if params.UseCursor && params.Cursor != "" {
+		cursor, err := DecodeCursor(params.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		value, err := cursorValue(field, cursor.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		placeholder := "?"
+		if field == "amount" {
+			placeholder = "CAST(? AS DECIMAL(19,2))"
+		}
+		query += fmt.Sprintf(" AND (%s %s %s OR (%s = %s AND b.id %s ?))", column, cmp, placeholder, column, placeholder, cmp)
+		queryArgs = append(queryArgs, value, value, cursor.ID)
+	}
+	
+	// 添加排序，id保证顺序稳定
+	query += fmt.Sprintf(" ORDER BY %s %s, b.id %s", column, order, order)
+	
+	// 多取一条用于判断是否还有下一页
+	limit := params.PageSize
+	if limit > 0 {
+		query += " LIMIT ?"
+		queryArgs = append(queryArgs, limit+1)
+		if !params.UseCursor && params.Page > 1 {
+			query += " OFFSET ?"
+			queryArgs = append(queryArgs, (params.Page-1)*limit)
+		}
 	}
 	
 	// 执行查询
-	rows, err := DB.Query(query, args...)
+	rows, err := dbQuery(ctx, query, queryArgs...)
 	if err != nil {
 		logs.Error("Error querying bills: %v", err)
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 	
@@ -246,6 +380,7 @@ func GetBills(userID uint, params *BillQueryParams) ([]*Bill, int, error) {
 			&bill.Type,
 			&dateStr,
 			&bill.Description,
+			&bill.Merchant,
 			&bill.CreatedAt,
 			&bill.UpdatedAt,
 			&bill.CategoryName,
@@ -254,14 +389,14 @@ func GetBills(userID uint, params *BillQueryParams) ([]*Bill, int, error) {
 		
 		if err != nil {
 			logs.Error("Error scanning bill row: %v", err)
-			return nil, 0, err
+			return nil, err
 		}
 		
 		// 解析日期
 		bill.Date, err = time.Parse("2006-01-02", dateStr)
 		if err != nil {
 			logs.Error("Error parsing date: %v", err)
-			return nil, 0, err
+			return nil, err
 		}
 		
 		bills = append(bills, bill)
@@ -269,16 +404,54 @@ func GetBills(userID uint, params *BillQueryParams) ([]*Bill, int, error) {
 	
 	if err = rows.Err(); err != nil {
 		logs.Error("Error iterating bill rows: %v", err)
-		return nil, 0, err
+		return nil, err
 	}
 	
-	return bills, total, nil
+	if limit > 0 && len(bills) > limit {
+		bills = bills[:limit]
+		page.HasMore = true
+		if params.UseCursor {
+			page.NextCursor = cursorFor(bills[limit-1], sort, field).Encode()
+		}
+	}
+	
+	// 加载标签并生成搜索高亮
+	if err := loadBillTags(ctx, bills); err != nil {
+		return nil, err
+	}
+	if len(terms) > 0 {
+		for _, bill := range bills {
+			bill.highlight(terms)
+		}
+	}
+	page.Bills = bills
+	
+	return page, nil
+}
+
+// cursorValue 将游标中的排序键转换为查询参数
+func cursorValue(field, value string) (interface{}, error) {
+	switch field {
+	case "amount":
+		return ParseMoney(value)
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		// DATE列直接按字符串比较，避免驱动按连接时区转换
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
 }
 
 // UpdateBill 更新账单
-func UpdateBill(id, userID uint, req *BillRequest) (*Bill, error) {
+func UpdateBill(ctx context.Context, id, userID uint, req *BillRequest) (*Bill, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查账单是否存在
-	_, err := GetBill(id, userID)
+	_, err := GetBill(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -286,45 +459,73 @@ func UpdateBill(id, userID uint, req *BillRequest) (*Bill, error) {
 	// 检查分类是否存在且属于该用户
 	var categoryExists bool
 	var categoryType string
-	err = DB.QueryRow(
+	err = dbQueryRow(ctx, 
 		"SELECT EXISTS(SELECT 1 FROM categories WHERE id = ? AND user_id = ?), type FROM categories WHERE id = ?",
 		req.CategoryID, userID, req.CategoryID,
 	).Scan(&categoryExists, &categoryType)
 	
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotOwned
+	}
 	if err != nil {
 		logs.Error("Error checking category: %v", err)
 		return nil, err
 	}
 	
 	if !categoryExists {
-		return nil, errors.New("分类不存在或不属于当前用户")
+		return nil, ErrCategoryNotOwned
 	}
 	
 	// 确保账单类型与分类类型一致
 	if categoryType != req.Type {
-		return nil, errors.New("账单类型与分类类型不一致")
+		return nil, ErrBillTypeMismatch
 	}
 	
-	// 解析日期
-	date, err := time.Parse("2006-01-02", req.Date)
+	// 解析日期，时间戳按用户时区换算
+	loc, err := UserLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	date, err := ParseBillDate(req.Date, loc)
 	if err != nil {
 		logs.Error("Error parsing date: %v", err)
-		return nil, errors.New("日期格式错误，正确格式为：YYYY-MM-DD")
+		return nil, err
+	}
+	
+	// 开始事务
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Error("Error starting transaction: %v", err)
+		return nil, err
 	}
 	
 	// 更新账单
-	_, err = DB.Exec(
-		"UPDATE bills SET category_id = ?, amount = ?, type = ?, date = ?, description = ? WHERE id = ? AND user_id = ?",
-		req.CategoryID, req.Amount, req.Type, date, req.Description, id, userID,
+	_, err = txExec(ctx, tx, 
+		"UPDATE bills SET category_id = ?, amount = ?, type = ?, date = ?, description = ?, merchant = ? WHERE id = ? AND user_id = ?",
+		req.CategoryID, req.Amount, req.Type, date.Format("2006-01-02"), req.Description, req.Merchant, id, userID,
 	)
 	
 	if err != nil {
+		tx.Rollback()
 		logs.Error("Error updating bill: %v", err)
 		return nil, err
 	}
 	
+	// 未传标签时保留原有标签
+	if req.Tags != nil {
+		if err := saveBillTags(ctx, tx, id, req.Tags); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	
+	if err := tx.Commit(); err != nil {
+		logs.Error("Error committing transaction: %v", err)
+		return nil, err
+	}
+	
 	// 获取更新后的账单
-	bill, err := GetBill(id, userID)
+	bill, err := GetBill(ctx, id, userID)
 	if err != nil {
 		logs.Error("Error fetching updated bill: %v", err)
 		return nil, err
@@ -334,15 +535,18 @@ func UpdateBill(id, userID uint, req *BillRequest) (*Bill, error) {
 }
 
 // DeleteBill 删除账单
-func DeleteBill(id, userID uint) error {
+func DeleteBill(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查账单是否存在
-	_, err := GetBill(id, userID)
+	_, err := GetBill(ctx, id, userID)
 	if err != nil {
 		return err
 	}
 	
 	// 删除账单
-	_, err = DB.Exec("DELETE FROM bills WHERE id = ? AND user_id = ?", id, userID)
+	_, err = dbExec(ctx, "DELETE FROM bills WHERE id = ? AND user_id = ?", id, userID)
 	if err != nil {
 		logs.Error("Error deleting bill: %v", err)
 		return err
@@ -352,16 +556,18 @@ func DeleteBill(id, userID uint) error {
 }
 
 // GetMonthlyStats 获取月度统计
-func GetMonthlyStats(userID uint, year int, month int) (map[string]interface{}, error) {
+func GetMonthlyStats(ctx context.Context, userID uint, year int, month int) (map[string]interface{}, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 构建日期条件
-	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+	startDate, endDate := monthRange(year, time.Month(month))
 	
 	// 获取总收入和总支出
-	var totalIncome, totalExpense float64
-	err := DB.QueryRow(
+	var totalIncome, totalExpense Money
+	err := dbQueryRow(ctx, 
 		"SELECT COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0), COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0) FROM bills WHERE user_id = ? AND date BETWEEN ? AND ?",
-		userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"),
+		userID, startDate, endDate,
 	).Scan(&totalIncome, &totalExpense)
 	
 	if err != nil {
@@ -370,14 +576,14 @@ func GetMonthlyStats(userID uint, year int, month int) (map[string]interface{},
 	}
 	
 	// 获取分类统计
-	rows, err := DB.Query(`
+	rows, err := dbQuery(ctx, `
 		SELECT c.id, c.name, c.type, c.icon, SUM(b.amount) as total
 		FROM bills b
 		JOIN categories c ON b.category_id = c.id
 		WHERE b.user_id = ? AND b.date BETWEEN ? AND ?
 		GROUP BY b.category_id
 		ORDER BY total DESC
-	`, userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	`, userID, startDate, endDate)
 	
 	if err != nil {
 		logs.Error("Error querying category stats: %v", err)
@@ -390,7 +596,7 @@ func GetMonthlyStats(userID uint, year int, month int) (map[string]interface{},
 	for rows.Next() {
 		var id uint
 		var name, catType, icon string
-		var total float64
+		var total Money
 		
 		err := rows.Scan(&id, &name, &catType, &icon, &total)
 		if err != nil {
@@ -413,7 +619,7 @@ func GetMonthlyStats(userID uint, year int, month int) (map[string]interface{},
 	}
 	
 	// 获取每日统计
-	rows, err = DB.Query(`
+	rows, err = dbQuery(ctx, `
 		SELECT DATE_FORMAT(date, '%Y-%m-%d') as day,
 		       SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END) as income,
 		       SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END) as expense
@@ -421,7 +627,7 @@ func GetMonthlyStats(userID uint, year int, month int) (map[string]interface{},
 		WHERE user_id = ? AND date BETWEEN ? AND ?
 		GROUP BY day
 		ORDER BY day
-	`, userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	`, userID, startDate, endDate)
 	
 	if err != nil {
 		logs.Error("Error querying daily stats: %v", err)
@@ -433,7 +639,7 @@ func GetMonthlyStats(userID uint, year int, month int) (map[string]interface{},
 	dailyStats := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		var day string
-		var income, expense float64
+		var income, expense Money
 		
 		err := rows.Scan(&day, &income, &expense)
 		if err != nil {
@@ -464,4 +670,81 @@ func GetMonthlyStats(userID uint, year int, month int) (map[string]interface{},
 		"categories":    categoryStats,
 		"daily":         dailyStats,
 	}, nil
-} 
\ No newline at end of file
+} 
+// GetBillStats 按筛选条件统计收支合计和分类汇总
+func GetBillStats(ctx context.Context, userID uint, params *BillQueryParams) (map[string]interface{}, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	where, args := params.filter(userID)
+	
+	// 获取总收入、总支出和账单数
+	var totalIncome, totalExpense Money
+	var count int
+	err := dbQueryRow(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN b.type = 'income' THEN b.amount ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN b.type = 'expense' THEN b.amount ELSE 0 END), 0),
+		       COUNT(*)
+		FROM bills b
+		LEFT JOIN categories c ON b.category_id = c.id
+	`+where, args...).Scan(&totalIncome, &totalExpense, &count)
+	
+	if err != nil {
+		logs.Error("Error calculating bill totals: %v", err)
+		return nil, err
+	}
+	
+	// 获取分类统计
+	rows, err := dbQuery(ctx, `
+		SELECT c.id, c.name, c.type, c.icon, SUM(b.amount) as total, COUNT(*)
+		FROM bills b
+		LEFT JOIN categories c ON b.category_id = c.id
+	`+where+`
+		GROUP BY b.category_id
+		ORDER BY total DESC
+	`, args...)
+	
+	if err != nil {
+		logs.Error("Error querying category stats: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	
+	categoryStats := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var id uint
+		var name, catType, icon string
+		var total Money
+		var categoryCount int
+		
+		err := rows.Scan(&id, &name, &catType, &icon, &total, &categoryCount)
+		if err != nil {
+			logs.Error("Error scanning category stats row: %v", err)
+			return nil, err
+		}
+		
+		categoryStats = append(categoryStats, map[string]interface{}{
+			"id":    id,
+			"name":  name,
+			"type":  catType,
+			"icon":  icon,
+			"total": total,
+			"count": categoryCount,
+		})
+	}
+	
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating category stats rows: %v", err)
+		return nil, err
+	}
+	
+	return map[string]interface{}{
+		"start_date":    params.StartDate,
+		"end_date":      params.EndDate,
+		"total_income":  totalIncome,
+		"total_expense": totalExpense,
+		"balance":       totalIncome - totalExpense,
+		"count":         count,
+		"categories":    categoryStats,
+	}, nil
+}
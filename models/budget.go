@@ -1,9 +1,8 @@
 package models
 
 import (
+	"context"
 	"database/sql"
-	"errors"
-	"fmt"
 	"time"
 
 	"github.com/beego/beego/v2/core/logs"
@@ -11,25 +10,44 @@ import (
 
 // Budget 预算模型
 type Budget struct {
-	ID         uint      `json:"id"`
-	UserID     uint      `json:"user_id"`
-	CategoryID uint      `json:"category_id,omitempty"`
-	Amount     float64   `json:"amount"`
-	Month      time.Time `json:"month"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID           uint      `json:"id"`
+	UserID       uint      `json:"user_id"`
+	CategoryID   uint      `json:"category_id,omitempty"`
+	Name         string    `json:"name,omitempty"`         // 组合预算的名称
+	CategoryIDs  []uint    `json:"category_ids,omitempty"` // 组合预算统计的分类
+	Tags         []string  `json:"tags,omitempty"`         // 组合预算统计的标签
+	Amount       Money     `json:"amount"`
+	Month        time.Time `json:"month"`                  // 周期开始日期所在的月份
+	Period       string    `json:"period"`                 // 周期类型：weekly、monthly、quarterly、yearly、custom
+	StartDate    time.Time `json:"start_date"`             // 周期开始日期
+	EndDate      time.Time `json:"end_date"`               // 周期结束日期（含）
+	RolloverMode string    `json:"rollover_mode"`          // 上一周期结余或超支如何结转到本周期
+	RolloverCap  Money     `json:"rollover_cap,omitempty"` // 结转金额的绝对值上限，0表示不限
+	TemplateID   uint      `json:"template_id,omitempty"`  // 由预算模板生成时为模板ID
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 	// 关联字段
 	CategoryName string  `json:"category_name,omitempty"`
 	CategoryIcon string  `json:"category_icon,omitempty"`
-	UsedAmount   float64 `json:"used_amount"`
-	Percentage   float64 `json:"percentage"`
+	CarryOver    Money   `json:"carry_over"` // 从上一周期结转的金额，负数表示上一周期超支
+	Available    Money   `json:"available"`  // 本周期可用金额，即预算金额加结转金额
+	UsedAmount   Money   `json:"used_amount"`
+	Percentage   float64 `json:"percentage"` // 已使用金额占可用金额的百分比
 }
 
 // BudgetRequest 预算请求参数
 type BudgetRequest struct {
-	CategoryID uint    `json:"category_id"`
-	Amount     float64 `json:"amount" valid:"Required"`
-	Month      string  `json:"month" valid:"Required"`
+	CategoryID   uint     `json:"category_id"`              // 分类预算的分类，不能与category_ids、tags同时使用
+	Name         string   `json:"name" valid:"MaxSize(50)"` // 组合预算的名称，组合预算必填
+	CategoryIDs  []uint   `json:"category_ids"`             // 组合预算统计的分类
+	Tags         []string `json:"tags"`                     // 组合预算统计的标签，带有任一标签的支出都计入
+	Amount       Money    `json:"amount" valid:"Required;Money"`
+	Period       string   `json:"period" valid:"Match(/^(weekly|monthly|quarterly|yearly|custom)?$/)"` // 默认monthly
+	Month        string   `json:"month" valid:"Month"`                                                 // 月度预算的月份，未指定start_date时作为周期开始
+	StartDate    string   `json:"start_date" valid:"Date"`                                             // 周期内的任意日期，自然周期会对齐到周期开始
+	EndDate      string   `json:"end_date" valid:"Date"`                                               // 自定义周期的结束日期（含）
+	RolloverMode string   `json:"rollover_mode" valid:"Match(/^(none|surplus|deficit|both)?$/)"`       // 默认none
+	RolloverCap  Money    `json:"rollover_cap"`                                                        // 结转金额的绝对值上限，0表示不限
 }
 
 // BudgetAlert 预算告警模型
@@ -51,144 +69,177 @@ type BudgetAlertRequest struct {
 }
 
 // CreateBudget 创建预算
-func CreateBudget(userID uint, req *BudgetRequest) (*Budget, error) {
-	// 解析月份
-	month, err := time.Parse("2006-01", req.Month)
+func CreateBudget(ctx context.Context, userID uint, req *BudgetRequest) (*Budget, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// 开始事务
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
-		logs.Error("Error parsing month: %v", err)
-		return nil, errors.New("月份格式错误，正确格式为：YYYY-MM")
+		logs.Error("Error starting transaction: %v", err)
+		return nil, err
+	}
+	
+	budgetID, err := createBudget(ctx, tx, userID, req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	
+	// 提交事务
+	if err = tx.Commit(); err != nil {
+		logs.Error("Error committing transaction: %v", err)
+		return nil, err
+	}
+	
+	// 获取完整的预算信息
+	budget, err := GetBudget(ctx, budgetID, userID)
+	if err != nil {
+		logs.Error("Error fetching new budget: %v", err)
+		return nil, err
+	}
+	
+	return budget, nil
+}
+
+// createBudget 在事务中校验并创建预算，返回预算ID
+func createBudget(ctx context.Context, tx *sql.Tx, userID uint, req *BudgetRequest) (uint, error) {
+	// 计算周期起止日期
+	startDate, endDate, err := req.periodRange()
+	if err != nil {
+		return 0, err
 	}
+	month := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, time.UTC)
 	
 	// 检查分类是否存在且属于该用户（如果指定了分类）
-	if req.CategoryID > 0 {
-		var exists bool
-		var categoryType string
-		err := DB.QueryRow(
-			"SELECT EXISTS(SELECT 1 FROM categories WHERE id = ? AND user_id = ?), type FROM categories WHERE id = ?",
-			req.CategoryID, userID, req.CategoryID,
-		).Scan(&exists, &categoryType)
-		
-		if err != nil {
-			logs.Error("Error checking category: %v", err)
-			return nil, err
-		}
-		
-		if !exists {
-			return nil, errors.New("分类不存在或不属于当前用户")
-		}
-		
-		// 只能为支出分类设置预算
-		if categoryType != "expense" {
-			return nil, errors.New("只能为支出分类设置预算")
-		}
-		
-		// 检查是否已有同月同分类的预算
-		var count int
-		err = DB.QueryRow(
-			"SELECT COUNT(*) FROM budgets WHERE user_id = ? AND category_id = ? AND DATE_FORMAT(month, '%Y-%m') = ?",
-			userID, req.CategoryID, req.Month,
-		).Scan(&count)
-		
-		if err != nil {
-			logs.Error("Error checking existing budget: %v", err)
-			return nil, err
-		}
-		
-		if count > 0 {
-			return nil, errors.New("该分类在当月已有预算设置")
-		}
-	} else {
-		// 检查是否已有同月的总预算
-		var count int
-		err = DB.QueryRow(
-			"SELECT COUNT(*) FROM budgets WHERE user_id = ? AND category_id IS NULL AND DATE_FORMAT(month, '%Y-%m') = ?",
-			userID, req.Month,
-		).Scan(&count)
-		
-		if err != nil {
-			logs.Error("Error checking existing total budget: %v", err)
-			return nil, err
-		}
-		
-		if count > 0 {
-			return nil, errors.New("当月已有总预算设置")
-		}
+	req.normalizeScope()
+	if err := checkBudgetCategory(ctx, userID, req.CategoryID); err != nil {
+		return 0, err
+	}
+	if err := checkBudgetScope(ctx, userID, req); err != nil {
+		return 0, err
+	}
+	
+	// 检查是否已有时间重叠的同类预算，以及分类或标签是否已被其他预算统计
+	if err := checkBudgetOverlaps(ctx, userID, 0, req, startDate, endDate); err != nil {
+		return 0, err
 	}
 	
 	// 创建预算
-	var result sql.Result
+	var categoryID interface{}
 	if req.CategoryID > 0 {
-		result, err = DB.Exec(
-			"INSERT INTO budgets (user_id, category_id, amount, month) VALUES (?, ?, ?, ?)",
-			userID, req.CategoryID, req.Amount, month,
-		)
-	} else {
-		result, err = DB.Exec(
-			"INSERT INTO budgets (user_id, amount, month) VALUES (?, ?, ?)",
-			userID, req.Amount, month,
-		)
+		categoryID = req.CategoryID
 	}
+	result, err := txExec(ctx, tx, 
+		"INSERT INTO budgets (user_id, category_id, name, scope_key, amount, month, period, start_date, end_date, rollover_mode, rollover_cap) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		userID, categoryID, req.Name, budgetScopeKey(req.CategoryIDs, req.Tags), req.Amount, month, req.period(),
+		startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), orNone(req.RolloverMode), req.RolloverCap,
+	)
 	
+	if isDuplicateEntry(err) {
+		return 0, ErrBudgetCategoryExists
+	}
 	if err != nil {
 		logs.Error("Error creating budget: %v", err)
-		return nil, err
+		return 0, err
 	}
 	
 	// 获取预算ID
 	budgetID, err := result.LastInsertId()
 	if err != nil {
 		logs.Error("Error getting budget ID: %v", err)
-		return nil, err
+		return 0, err
 	}
 	
-	// 获取完整的预算信息
-	budget, err := GetBudget(uint(budgetID), userID)
+	// 保存组合预算的分类和标签
+	if err = saveBudgetScope(ctx, tx, uint(budgetID), req.CategoryIDs, req.Tags); err != nil {
+		return 0, err
+	}
+	
+	return uint(budgetID), nil
+}
+
+// checkBudgetOverlaps 检查预算与已有的同类周期预算是否冲突
+func checkBudgetOverlaps(ctx context.Context, userID, excludeID uint, req *BudgetRequest, startDate, endDate time.Time) error {
+	if !req.isGroup() {
+		if err := checkBudgetOverlap(ctx, userID, excludeID, req.CategoryID, req.period(), startDate, endDate); err != nil {
+			return err
+		}
+	}
+	return checkBudgetScopeOverlap(ctx, userID, excludeID, req, req.period(), startDate, endDate)
+}
+
+// checkBudgetCategory 检查分类是否属于用户且为支出分类，categoryID为0表示总预算
+func checkBudgetCategory(ctx context.Context, userID, categoryID uint) error {
+	if categoryID == 0 {
+		return nil
+	}
+	
+	var categoryType string
+	err := dbQueryRow(ctx, 
+		"SELECT type FROM categories WHERE id = ? AND user_id = ?",
+		categoryID, userID,
+	).Scan(&categoryType)
+	
+	if err == sql.ErrNoRows {
+		return ErrCategoryNotOwned
+	}
 	if err != nil {
-		logs.Error("Error fetching new budget: %v", err)
-		return nil, err
+		logs.Error("Error checking category: %v", err)
+		return err
 	}
 	
-	return budget, nil
+	// 只能为支出分类设置预算
+	if categoryType != "expense" {
+		return ErrBudgetExpenseOnly
+	}
+	
+	return nil
 }
 
-// GetBudget 获取单个预算
-func GetBudget(id, userID uint) (*Budget, error) {
+// budgetColumns 预算的查询列，与scanBudget一一对应
+const budgetColumns = `b.id, b.user_id, b.category_id, b.name, b.scope_key, b.amount, DATE_FORMAT(b.month, '%Y-%m'), b.period,
+		       DATE_FORMAT(b.start_date, '%Y-%m-%d'), DATE_FORMAT(b.end_date, '%Y-%m-%d'),
+		       b.rollover_mode, b.rollover_cap, b.template_id, b.created_at, b.updated_at, c.name, c.icon`
+
+// scanBudget 读取一行预算数据并解析周期日期，同时返回组合预算的scope_key
+func scanBudget(row interface{ Scan(...interface{}) error }) (*Budget, string, error) {
 	budget := &Budget{}
-	var monthStr string
-	var categoryID sql.NullInt64
+	var monthStr, startStr, endStr, scopeKey string
+	var categoryID, templateID sql.NullInt64
 	var categoryName, categoryIcon sql.NullString
 	
-	// 查询预算基本信息
-	err := DB.QueryRow(`
-		SELECT b.id, b.user_id, b.category_id, b.amount, DATE_FORMAT(b.month, '%Y-%m'), 
-		       b.created_at, b.updated_at, c.name, c.icon
-		FROM budgets b
-		LEFT JOIN categories c ON b.category_id = c.id
-		WHERE b.id = ? AND b.user_id = ?
-	`, id, userID).Scan(
+	err := row.Scan(
 		&budget.ID,
 		&budget.UserID,
 		&categoryID,
+		&budget.Name,
+		&scopeKey,
 		&budget.Amount,
 		&monthStr,
+		&budget.Period,
+		&startStr,
+		&endStr,
+		&budget.RolloverMode,
+		&budget.RolloverCap,
+		&templateID,
 		&budget.CreatedAt,
 		&budget.UpdatedAt,
 		&categoryName,
 		&categoryIcon,
 	)
-	
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("预算不存在")
+		if err != sql.ErrNoRows {
+			logs.Error("Error scanning budget row: %v", err)
 		}
-		logs.Error("Error querying budget: %v", err)
-		return nil, err
+		return nil, "", err
 	}
 	
 	// 处理可空字段
 	if categoryID.Valid {
 		budget.CategoryID = uint(categoryID.Int64)
 	}
+	budget.TemplateID = uint(templateID.Int64)
 	if categoryName.Valid {
 		budget.CategoryName = categoryName.String
 	}
@@ -196,72 +247,85 @@ func GetBudget(id, userID uint) (*Budget, error) {
 		budget.CategoryIcon = categoryIcon.String
 	}
 	
-	// 解析月份
-	budget.Month, err = time.Parse("2006-01", monthStr)
-	if err != nil {
-		logs.Error("Error parsing month from database: %v", err)
-		return nil, err
+	// 解析月份和周期起止日期
+	if err = parseBudgetDates(budget, monthStr, startStr, endStr); err != nil {
+		return nil, "", err
 	}
 	
-	// 计算已使用金额和百分比
-	startDate := budget.Month
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
-	
-	var query string
-	var args []interface{}
-	
-	if budget.CategoryID > 0 {
-		query = `
-			SELECT COALESCE(SUM(amount), 0)
-			FROM bills
-			WHERE user_id = ? AND category_id = ? AND type = 'expense' AND date BETWEEN ? AND ?
-		`
-		args = []interface{}{userID, budget.CategoryID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")}
-	} else {
-		query = `
-			SELECT COALESCE(SUM(amount), 0)
-			FROM bills
-			WHERE user_id = ? AND type = 'expense' AND date BETWEEN ? AND ?
-		`
-		args = []interface{}{userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")}
+	return budget, scopeKey, nil
+}
+
+// loadBudgetDetails 加载组合预算的分类和标签，并计算已使用金额、结转金额和百分比
+func loadBudgetDetails(ctx context.Context, budget *Budget, scopeKey string) error {
+	var err error
+	
+	// 加载组合预算的分类和标签
+	if scopeKey != "" {
+		if err = loadBudgetScope(ctx, budget); err != nil {
+			return err
+		}
 	}
 	
-	err = DB.QueryRow(query, args...).Scan(&budget.UsedAmount)
+	// 计算周期内已使用金额
+	budget.UsedAmount, err = budgetUsage(ctx, budget)
+	if err != nil {
+		return err
+	}
+
+	// 计算结转金额和百分比
+	return applyCarryOver(ctx, budget)
+}
+
+// GetBudget 获取单个预算
+func GetBudget(ctx context.Context, id, userID uint) (*Budget, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// 查询预算基本信息
+	budget, scopeKey, err := scanBudget(dbQueryRow(ctx, `
+		SELECT `+budgetColumns+`
+		FROM budgets b
+		LEFT JOIN categories c ON b.category_id = c.id
+		WHERE b.id = ? AND b.user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrBudgetNotFound
+	}
 	if err != nil {
-		logs.Error("Error calculating used amount: %v", err)
 		return nil, err
 	}
 	
-	// 计算百分比
-	if budget.Amount > 0 {
-		budget.Percentage = (budget.UsedAmount / budget.Amount) * 100
+	if err = loadBudgetDetails(ctx, budget, scopeKey); err != nil {
+		return nil, err
 	}
 	
 	return budget, nil
 }
 
 // GetBudgets 获取预算列表
-func GetBudgets(userID uint, month string) ([]*Budget, error) {
+func GetBudgets(ctx context.Context, userID uint, month string) ([]*Budget, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 验证月份格式
 	parsedMonth, err := time.Parse("2006-01", month)
 	if err != nil {
 		logs.Error("Error parsing month: %v", err)
-		return nil, errors.New("月份格式错误，正确格式为：YYYY-MM")
+		return nil, ErrInvalidMonth
 	}
 	
-	// 构建查询
+	// 周期与该月有重叠的预算，包括跨月的周预算和季度、年度预算
 	startDate := parsedMonth
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
-	
-	// 查询当月所有预算
-	rows, err := DB.Query(`
-		SELECT b.id, b.user_id, b.category_id, b.amount, DATE_FORMAT(b.month, '%Y-%m'), 
-		       b.created_at, b.updated_at, c.name, c.icon
+	endDate := startDate.AddDate(0, 1, -1)
+
+	// 查询与当月重叠的所有预算
+	rows, err := dbQuery(ctx, `
+		SELECT `+budgetColumns+`
 		FROM budgets b
 		LEFT JOIN categories c ON b.category_id = c.id
-		WHERE b.user_id = ? AND DATE_FORMAT(b.month, '%Y-%m') = ?
-		ORDER BY b.category_id IS NULL DESC, c.name
-	`, userID, month)
+		WHERE b.user_id = ? AND b.start_date <= ? AND b.end_date >= ?
+		ORDER BY b.category_id IS NULL AND b.scope_key = '' DESC, COALESCE(c.name, b.name), FIELD(b.period, 'weekly', 'monthly', 'quarterly', 'yearly', 'custom'), b.start_date
+	`, userID, endDate.Format("2006-01-02"), startDate.Format("2006-01-02"))
 	
 	if err != nil {
 		logs.Error("Error querying budgets: %v", err)
@@ -272,77 +336,15 @@ func GetBudgets(userID uint, month string) ([]*Budget, error) {
 	// 处理查询结果
 	budgets := make([]*Budget, 0)
 	for rows.Next() {
-		budget := &Budget{}
-		var monthStr string
-		var categoryID sql.NullInt64
-		var categoryName, categoryIcon sql.NullString
-		
-		err := rows.Scan(
-			&budget.ID,
-			&budget.UserID,
-			&categoryID,
-			&budget.Amount,
-			&monthStr,
-			&budget.CreatedAt,
-			&budget.UpdatedAt,
-			&categoryName,
-			&categoryIcon,
-		)
-		
-		if err != nil {
-			logs.Error("Error scanning budget row: %v", err)
-			return nil, err
-		}
-		
-		// 处理可空字段
-		if categoryID.Valid {
-			budget.CategoryID = uint(categoryID.Int64)
-		}
-		if categoryName.Valid {
-			budget.CategoryName = categoryName.String
-		}
-		if categoryIcon.Valid {
-			budget.CategoryIcon = categoryIcon.String
-		}
-		
-		// 解析月份
-		budget.Month, err = time.Parse("2006-01", monthStr)
+		budget, scopeKey, err := scanBudget(rows)
 		if err != nil {
-			logs.Error("Error parsing month from database: %v", err)
 			return nil, err
 		}
 		
-		// 查询已使用金额
-		var query string
-		var args []interface{}
-		
-		if budget.CategoryID > 0 {
-			query = `
-				SELECT COALESCE(SUM(amount), 0)
-				FROM bills
-				WHERE user_id = ? AND category_id = ? AND type = 'expense' AND date BETWEEN ? AND ?
-			`
-			args = []interface{}{userID, budget.CategoryID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")}
-		} else {
-			query = `
-				SELECT COALESCE(SUM(amount), 0)
-				FROM bills
-				WHERE user_id = ? AND type = 'expense' AND date BETWEEN ? AND ?
-			`
-			args = []interface{}{userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")}
-		}
-		
-		err = DB.QueryRow(query, args...).Scan(&budget.UsedAmount)
-		if err != nil {
-			logs.Error("Error calculating used amount: %v", err)
+		if err = loadBudgetDetails(ctx, budget, scopeKey); err != nil {
 			return nil, err
 		}
 		
-		// 计算百分比
-		if budget.Amount > 0 {
-			budget.Percentage = (budget.UsedAmount / budget.Amount) * 100
-		}
-		
 		budgets = append(budgets, budget)
 	}
 	
@@ -355,100 +357,81 @@ func GetBudgets(userID uint, month string) ([]*Budget, error) {
 }
 
 // UpdateBudget 更新预算
-func UpdateBudget(id, userID uint, req *BudgetRequest) (*Budget, error) {
+func UpdateBudget(ctx context.Context, id, userID uint, req *BudgetRequest) (*Budget, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查预算是否存在
-	budget, err := GetBudget(id, userID)
+	budget, err := GetBudget(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 计算周期起止日期
+	startDate, endDate, err := req.periodRange()
 	if err != nil {
 		return nil, err
 	}
+	month := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, time.UTC)
 	
-	// 如果要修改分类
+	// 如果要修改分类，检查新分类
+	req.normalizeScope()
 	if budget.CategoryID != req.CategoryID {
-		// 如果指定了新分类
-		if req.CategoryID > 0 {
-			// 检查分类是否存在且属于该用户
-			var exists bool
-			var categoryType string
-			err := DB.QueryRow(
-				"SELECT EXISTS(SELECT 1 FROM categories WHERE id = ? AND user_id = ?), type FROM categories WHERE id = ?",
-				req.CategoryID, userID, req.CategoryID,
-			).Scan(&exists, &categoryType)
-			
-			if err != nil {
-				logs.Error("Error checking category: %v", err)
-				return nil, err
-			}
-			
-			if !exists {
-				return nil, errors.New("分类不存在或不属于当前用户")
-			}
-			
-			// 只能为支出分类设置预算
-			if categoryType != "expense" {
-				return nil, errors.New("只能为支出分类设置预算")
-			}
-			
-			// 检查是否已有同月同分类的预算
-			var count int
-			err = DB.QueryRow(
-				"SELECT COUNT(*) FROM budgets WHERE user_id = ? AND category_id = ? AND DATE_FORMAT(month, '%Y-%m') = ? AND id != ?",
-				userID, req.CategoryID, req.Month, id,
-			).Scan(&count)
-			
-			if err != nil {
-				logs.Error("Error checking existing budget: %v", err)
-				return nil, err
-			}
-			
-			if count > 0 {
-				return nil, errors.New("该分类在当月已有预算设置")
-			}
-		} else {
-			// 检查是否已有同月的总预算
-			var count int
-			err = DB.QueryRow(
-				"SELECT COUNT(*) FROM budgets WHERE user_id = ? AND category_id IS NULL AND DATE_FORMAT(month, '%Y-%m') = ? AND id != ?",
-				userID, req.Month, id,
-			).Scan(&count)
-			
-			if err != nil {
-				logs.Error("Error checking existing total budget: %v", err)
-				return nil, err
-			}
-			
-			if count > 0 {
-				return nil, errors.New("当月已有总预算设置")
-			}
+		if err := checkBudgetCategory(ctx, userID, req.CategoryID); err != nil {
+			return nil, err
 		}
 	}
+	if err := checkBudgetScope(ctx, userID, req); err != nil {
+		return nil, err
+	}
+	
+	// 检查是否与其他同类预算冲突
+	if err := checkBudgetOverlaps(ctx, userID, id, req, startDate, endDate); err != nil {
+		return nil, err
+	}
 	
-	// 解析月份
-	month, err := time.Parse("2006-01", req.Month)
+	// 开始事务
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
-		logs.Error("Error parsing month: %v", err)
-		return nil, errors.New("月份格式错误，正确格式为：YYYY-MM")
+		logs.Error("Error starting transaction: %v", err)
+		return nil, err
 	}
 	
 	// 更新预算
+	var categoryID interface{}
 	if req.CategoryID > 0 {
-		_, err = DB.Exec(
-			"UPDATE budgets SET category_id = ?, amount = ?, month = ? WHERE id = ? AND user_id = ?",
-			req.CategoryID, req.Amount, month, id, userID,
-		)
-	} else {
-		_, err = DB.Exec(
-			"UPDATE budgets SET category_id = NULL, amount = ?, month = ? WHERE id = ? AND user_id = ?",
-			req.Amount, month, id, userID,
-		)
+		categoryID = req.CategoryID
 	}
+	_, err = txExec(ctx, tx, 
+		"UPDATE budgets SET category_id = ?, name = ?, scope_key = ?, amount = ?, month = ?, period = ?, start_date = ?, end_date = ?, rollover_mode = ?, rollover_cap = ? WHERE id = ? AND user_id = ?",
+		categoryID, req.Name, budgetScopeKey(req.CategoryIDs, req.Tags), req.Amount, month, req.period(),
+		startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), orNone(req.RolloverMode), req.RolloverCap, id, userID,
+	)
 	
+	if isDuplicateEntry(err) {
+		tx.Rollback()
+		return nil, ErrBudgetCategoryExists
+	}
 	if err != nil {
+		tx.Rollback()
 		logs.Error("Error updating budget: %v", err)
 		return nil, err
 	}
 	
+	// 替换组合预算的分类和标签
+	if err = saveBudgetScope(ctx, tx, id, req.CategoryIDs, req.Tags); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	
+	// 提交事务
+	if err = tx.Commit(); err != nil {
+		logs.Error("Error committing transaction: %v", err)
+		return nil, err
+	}
+	
 	// 获取更新后的预算
-	updatedBudget, err := GetBudget(id, userID)
+	updatedBudget, err := GetBudget(ctx, id, userID)
 	if err != nil {
 		logs.Error("Error fetching updated budget: %v", err)
 		return nil, err
@@ -458,22 +441,25 @@ func UpdateBudget(id, userID uint, req *BudgetRequest) (*Budget, error) {
 }
 
 // DeleteBudget 删除预算
-func DeleteBudget(id, userID uint) error {
+func DeleteBudget(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查预算是否存在
-	_, err := GetBudget(id, userID)
+	_, err := GetBudget(ctx, id, userID)
 	if err != nil {
 		return err
 	}
 	
 	// 开始事务
-	tx, err := DB.Begin()
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		logs.Error("Error starting transaction: %v", err)
 		return err
 	}
 	
 	// 删除关联的预算告警
-	_, err = tx.Exec("DELETE FROM budget_alerts WHERE budget_id = ?", id)
+	_, err = txExec(ctx, tx, "DELETE FROM budget_alerts WHERE budget_id = ?", id)
 	if err != nil {
 		tx.Rollback()
 		logs.Error("Error deleting budget alerts: %v", err)
@@ -481,7 +467,7 @@ func DeleteBudget(id, userID uint) error {
 	}
 	
 	// 删除预算
-	_, err = tx.Exec("DELETE FROM budgets WHERE id = ? AND user_id = ?", id, userID)
+	_, err = txExec(ctx, tx, "DELETE FROM budgets WHERE id = ? AND user_id = ?", id, userID)
 	if err != nil {
 		tx.Rollback()
 		logs.Error("Error deleting budget: %v", err)
@@ -498,21 +484,24 @@ func DeleteBudget(id, userID uint) error {
 }
 
 // CreateBudgetAlert 创建预算告警
-func CreateBudgetAlert(userID uint, req *BudgetAlertRequest) (*BudgetAlert, error) {
+func CreateBudgetAlert(ctx context.Context, userID uint, req *BudgetAlertRequest) (*BudgetAlert, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查预算是否存在且属于当前用户
-	_, err := GetBudget(req.BudgetID, userID)
+	_, err := GetBudget(ctx, req.BudgetID, userID)
 	if err != nil {
 		return nil, err
 	}
 	
 	// 检查阈值范围
 	if req.Threshold < 1 || req.Threshold > 100 {
-		return nil, errors.New("阈值必须在1-100之间")
+		return nil, ErrThresholdOutOfRange
 	}
 	
 	// 检查是否已存在告警
 	var count int
-	err = DB.QueryRow(
+	err = dbQueryRow(ctx, 
 		"SELECT COUNT(*) FROM budget_alerts WHERE budget_id = ? AND threshold = ?",
 		req.BudgetID, req.Threshold,
 	).Scan(&count)
@@ -523,11 +512,11 @@ func CreateBudgetAlert(userID uint, req *BudgetAlertRequest) (*BudgetAlert, erro
 	}
 	
 	if count > 0 {
-		return nil, fmt.Errorf("已存在相同阈值(%d%%)的告警", req.Threshold)
+		return nil, Conflict("alert_threshold_exists", req.Threshold)
 	}
 	
 	// 创建告警
-	result, err := DB.Exec(
+	result, err := dbExec(ctx, 
 		"INSERT INTO budget_alerts (user_id, budget_id, threshold, is_active) VALUES (?, ?, ?, ?)",
 		userID, req.BudgetID, req.Threshold, req.IsActive,
 	)
@@ -557,7 +546,10 @@ func CreateBudgetAlert(userID uint, req *BudgetAlertRequest) (*BudgetAlert, erro
 }
 
 // GetBudgetAlerts 获取预算告警列表
-func GetBudgetAlerts(userID uint, budgetID uint) ([]*BudgetAlert, error) {
+func GetBudgetAlerts(ctx context.Context, userID uint, budgetID uint) ([]*BudgetAlert, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 构建查询
 	query := `
 		SELECT id, user_id, budget_id, threshold, is_active, created_at, updated_at
@@ -574,7 +566,7 @@ func GetBudgetAlerts(userID uint, budgetID uint) ([]*BudgetAlert, error) {
 	query += " ORDER BY threshold"
 	
 	// 执行查询
-	rows, err := DB.Query(query, args...)
+	rows, err := dbQuery(ctx, query, args...)
 	if err != nil {
 		logs.Error("Error querying budget alerts: %v", err)
 		return nil, err
@@ -613,10 +605,13 @@ func GetBudgetAlerts(userID uint, budgetID uint) ([]*BudgetAlert, error) {
 }
 
 // UpdateBudgetAlert 更新预算告警
-func UpdateBudgetAlert(id, userID uint, req *BudgetAlertRequest) (*BudgetAlert, error) {
+func UpdateBudgetAlert(ctx context.Context, id, userID uint, req *BudgetAlertRequest) (*BudgetAlert, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查告警是否存在
 	var exists bool
-	err := DB.QueryRow(
+	err := dbQueryRow(ctx, 
 		"SELECT EXISTS(SELECT 1 FROM budget_alerts WHERE id = ? AND user_id = ?)",
 		id, userID,
 	).Scan(&exists)
@@ -627,23 +622,23 @@ func UpdateBudgetAlert(id, userID uint, req *BudgetAlertRequest) (*BudgetAlert,
 	}
 	
 	if !exists {
-		return nil, errors.New("预算告警不存在")
+		return nil, ErrAlertNotFound
 	}
 	
 	// 检查预算是否存在且属于当前用户
-	_, err = GetBudget(req.BudgetID, userID)
+	_, err = GetBudget(ctx, req.BudgetID, userID)
 	if err != nil {
 		return nil, err
 	}
 	
 	// 检查阈值范围
 	if req.Threshold < 1 || req.Threshold > 100 {
-		return nil, errors.New("阈值必须在1-100之间")
+		return nil, ErrThresholdOutOfRange
 	}
 	
 	// 检查是否与其他告警冲突
 	var count int
-	err = DB.QueryRow(
+	err = dbQueryRow(ctx, 
 		"SELECT COUNT(*) FROM budget_alerts WHERE budget_id = ? AND threshold = ? AND id != ?",
 		req.BudgetID, req.Threshold, id,
 	).Scan(&count)
@@ -654,11 +649,11 @@ func UpdateBudgetAlert(id, userID uint, req *BudgetAlertRequest) (*BudgetAlert,
 	}
 	
 	if count > 0 {
-		return nil, fmt.Errorf("已存在相同阈值(%d%%)的告警", req.Threshold)
+		return nil, Conflict("alert_threshold_exists", req.Threshold)
 	}
 	
 	// 更新告警
-	_, err = DB.Exec(
+	_, err = dbExec(ctx, 
 		"UPDATE budget_alerts SET budget_id = ?, threshold = ?, is_active = ? WHERE id = ? AND user_id = ?",
 		req.BudgetID, req.Threshold, req.IsActive, id, userID,
 	)
@@ -670,7 +665,7 @@ func UpdateBudgetAlert(id, userID uint, req *BudgetAlertRequest) (*BudgetAlert,
 	
 	// 获取更新后的告警信息
 	alert := &BudgetAlert{}
-	err = DB.QueryRow(
+	err = dbQueryRow(ctx, 
 		"SELECT id, user_id, budget_id, threshold, is_active, created_at, updated_at FROM budget_alerts WHERE id = ?",
 		id,
 	).Scan(
@@ -692,10 +687,13 @@ func UpdateBudgetAlert(id, userID uint, req *BudgetAlertRequest) (*BudgetAlert,
 }
 
 // DeleteBudgetAlert 删除预算告警
-func DeleteBudgetAlert(id, userID uint) error {
+func DeleteBudgetAlert(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查告警是否存在
 	var exists bool
-	err := DB.QueryRow(
+	err := dbQueryRow(ctx, 
 		"SELECT EXISTS(SELECT 1 FROM budget_alerts WHERE id = ? AND user_id = ?)",
 		id, userID,
 	).Scan(&exists)
@@ -706,11 +704,11 @@ func DeleteBudgetAlert(id, userID uint) error {
 	}
 	
 	if !exists {
-		return errors.New("预算告警不存在")
+		return ErrAlertNotFound
 	}
 	
 	// 删除告警
-	_, err = DB.Exec("DELETE FROM budget_alerts WHERE id = ? AND user_id = ?", id, userID)
+	_, err = dbExec(ctx, "DELETE FROM budget_alerts WHERE id = ? AND user_id = ?", id, userID)
 	if err != nil {
 		logs.Error("Error deleting budget alert: %v", err)
 		return err
@@ -719,27 +717,35 @@ func DeleteBudgetAlert(id, userID uint) error {
 	return nil
 }
 
-// CheckBudgetAlerts 检查超出预算告警
-func CheckBudgetAlerts(userID uint) ([]map[string]interface{}, error) {
-	// 获取当前月份
-	now := time.Now()
-	currentMonth := now.Format("2006-01")
+// CheckBudgetAlerts 检查当前周期内超出阈值的预算告警
+func CheckBudgetAlerts(ctx context.Context, userID uint) ([]map[string]interface{}, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// 获取用户时区下的当前月份
+	loc, err := UserLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	currentMonth := CurrentMonth(loc)
+	today := Today(loc).Format("2006-01-02")
 	
-	// 获取当月的所有预算及其使用情况
-	budgets, err := GetBudgets(userID, currentMonth)
+	// 获取与当月重叠的所有预算及其使用情况
+	budgets, err := GetBudgets(ctx, userID, currentMonth)
 	if err != nil {
 		logs.Error("Error getting budgets: %v", err)
 		return nil, err
 	}
 	
 	// 获取所有激活的预算告警
-	alerts, err := DB.Query(`
-		SELECT ba.id, ba.budget_id, ba.threshold, b.amount, b.category_id, c.name
+	alerts, err := dbQuery(ctx, `
+		SELECT ba.id, ba.budget_id, ba.threshold, b.amount, b.category_id, c.name, e.triggered_at
 		FROM budget_alerts ba
 		JOIN budgets b ON ba.budget_id = b.id
 		LEFT JOIN categories c ON b.category_id = c.id
-		WHERE ba.user_id = ? AND ba.is_active = 1 AND DATE_FORMAT(b.month, '%Y-%m') = ?
-	`, userID, currentMonth)
+		LEFT JOIN budget_alert_events e ON e.alert_id = ba.id AND e.threshold = ba.threshold AND e.month = b.month
+		WHERE ba.user_id = ? AND ba.is_active = 1 AND b.start_date <= ? AND b.end_date >= ?
+	`, userID, today, today)
 	
 	if err != nil {
 		logs.Error("Error querying active alerts: %v", err)
@@ -754,11 +760,12 @@ func CheckBudgetAlerts(userID uint) ([]map[string]interface{}, error) {
 	for alerts.Next() {
 		var alertID, budgetID uint
 		var threshold int
-		var budgetAmount float64
+		var budgetAmount Money
 		var categoryID sql.NullInt64
 		var categoryName sql.NullString
+		var triggeredAt sql.NullTime
 		
-		err := alerts.Scan(&alertID, &budgetID, &threshold, &budgetAmount, &categoryID, &categoryName)
+		err := alerts.Scan(&alertID, &budgetID, &threshold, &budgetAmount, &categoryID, &categoryName, &triggeredAt)
 		if err != nil {
 			logs.Error("Error scanning alert: %v", err)
 			return nil, err
@@ -781,7 +788,8 @@ func CheckBudgetAlerts(userID uint) ([]map[string]interface{}, error) {
 		usedPercentage := matchBudget.Percentage
 		
 		// 检查是否超过阈值
-		if usedPercentage >= float64(threshold) {
+		// 用整数比较，避免百分比舍入导致临界值误判
+		if matchBudget.UsedAmount*100 >= matchBudget.Available*Money(threshold) {
 			alertInfo := map[string]interface{}{
 				"alert_id":       alertID,
 				"budget_id":      budgetID,
@@ -789,6 +797,15 @@ func CheckBudgetAlerts(userID uint) ([]map[string]interface{}, error) {
 				"used_percent":   usedPercentage,
 				"used_amount":    matchBudget.UsedAmount,
 				"budget_amount":  budgetAmount,
+				"available":      matchBudget.Available,
+				"period":         matchBudget.Period,
+				"start_date":     matchBudget.StartDate.Format("2006-01-02"),
+				"end_date":       matchBudget.EndDate.Format("2006-01-02"),
+			}
+			
+			// 首次触发时间，告警在变更账单时检查，此前触发的告警可能还没有记录
+			if triggeredAt.Valid {
+				alertInfo["triggered_at"] = triggeredAt.Time
 			}
 			
 			if categoryID.Valid {
@@ -797,6 +814,11 @@ func CheckBudgetAlerts(userID uint) ([]map[string]interface{}, error) {
 					alertInfo["category_name"] = categoryName.String
 				}
 				alertInfo["budget_type"] = "category"
+			} else if matchBudget.IsGroup() {
+				alertInfo["name"] = matchBudget.Name
+				alertInfo["category_ids"] = matchBudget.CategoryIDs
+				alertInfo["tags"] = matchBudget.Tags
+				alertInfo["budget_type"] = "group"
 			} else {
 				alertInfo["budget_type"] = "total"
 			}
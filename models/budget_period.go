@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 预算周期类型，周、季度和年按自然周期对齐，周从周一开始
+const (
+	BudgetWeekly    = "weekly"
+	BudgetMonthly   = "monthly"
+	BudgetQuarterly = "quarterly"
+	BudgetYearly    = "yearly"
+	BudgetCustom    = "custom" // 自定义起止日期
+)
+
+// BudgetPeriodRange 包含day的自然周期的起止日期（含），自定义周期返回day本身
+func BudgetPeriodRange(period string, day time.Time) (time.Time, time.Time) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case BudgetWeekly:
+		start := weekStart(day)
+		return start, start.AddDate(0, 0, 6)
+	case BudgetQuarterly:
+		start := quarterStart(day)
+		return start, start.AddDate(0, 3, -1)
+	case BudgetYearly:
+		start := time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1)
+	case BudgetCustom:
+		return day, day
+	default:
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1)
+	}
+}
+
+// period 请求中的周期类型，默认为月度
+func (r *BudgetRequest) period() string {
+	if r.Period == "" {
+		return BudgetMonthly
+	}
+	return r.Period
+}
+
+// periodRange 计算请求对应的起止日期
+//
+// start_date优先，未指定时使用month的第一天；自然周期对齐到包含该日期的周期，自定义周期使用start_date到end_date。
+func (r *BudgetRequest) periodRange() (time.Time, time.Time, error) {
+	var anchor time.Time
+	var err error
+	if r.StartDate != "" {
+		if anchor, err = time.Parse("2006-01-02", r.StartDate); err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidDate
+		}
+	} else if anchor, err = time.Parse("2006-01", r.Month); err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidMonth
+	}
+
+	if r.period() != BudgetCustom {
+		start, end := BudgetPeriodRange(r.period(), anchor)
+		return start, end, nil
+	}
+
+	end, err := time.Parse("2006-01-02", r.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidDate
+	}
+	if end.Before(anchor) {
+		return time.Time{}, time.Time{}, ErrBudgetPeriodInvalid
+	}
+	return anchor, end, nil
+}
+
+// PeriodLabel 预算周期的显示名称，月度预算为YYYY-MM，其他为起止日期
+func (b *Budget) PeriodLabel() string {
+	return budgetPeriodLabel(b.Period, b.StartDate, b.EndDate)
+}
+
+// budgetPeriodLabel 按周期类型和起止日期生成显示名称
+func budgetPeriodLabel(period string, start, end time.Time) string {
+	if period == BudgetMonthly || period == "" {
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02") + " ~ " + end.Format("2006-01-02")
+}
+
+// parseBudgetDates 解析数据库中以字符串读取的月份和起止日期
+func parseBudgetDates(budget *Budget, month, start, end string) error {
+	var err error
+	if budget.Month, err = time.Parse("2006-01", month); err != nil {
+		logs.Error("Error parsing month from database: %v", err)
+		return err
+	}
+	if budget.StartDate, err = time.Parse("2006-01-02", start); err != nil {
+		logs.Error("Error parsing start date from database: %v", err)
+		return err
+	}
+	if budget.EndDate, err = time.Parse("2006-01-02", end); err != nil {
+		logs.Error("Error parsing end date from database: %v", err)
+		return err
+	}
+	return nil
+}
+
+// IsBudgetPeriod 是否为支持的预算周期类型
+func IsBudgetPeriod(period string) bool {
+	switch period {
+	case BudgetWeekly, BudgetMonthly, BudgetQuarterly, BudgetYearly, BudgetCustom:
+		return true
+	}
+	return false
+}
+
+// FilterBudgets 只保留指定周期类型的预算
+func FilterBudgets(budgets []*Budget, period string) []*Budget {
+	filtered := make([]*Budget, 0, len(budgets))
+	for _, budget := range budgets {
+		if budget.Period == period {
+			filtered = append(filtered, budget)
+		}
+	}
+	return filtered
+}
+
+// checkBudgetOverlap 检查同一分类（或总预算）是否已有时间重叠的同类周期预算，excludeID为修改中的预算，不用于组合预算
+//
+// 不同周期类型的预算可以同时存在，如每周零花钱预算和月度总预算。
+func checkBudgetOverlap(ctx context.Context, userID, excludeID, categoryID uint, period string, start, end time.Time) error {
+	query := `
+		SELECT COUNT(*) FROM budgets
+		WHERE user_id = ? AND id != ? AND period = ? AND start_date <= ? AND end_date >= ? AND `
+	args := []interface{}{userID, excludeID, period, end.Format("2006-01-02"), start.Format("2006-01-02")}
+	if categoryID > 0 {
+		query += "category_id = ?"
+		args = append(args, categoryID)
+	} else {
+		query += "category_id IS NULL AND scope_key = ''"
+	}
+
+	var count int
+	if err := dbQueryRow(ctx, query, args...).Scan(&count); err != nil {
+		logs.Error("Error checking overlapping budgets: %v", err)
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	if categoryID > 0 {
+		return ErrBudgetCategoryExists
+	}
+	return ErrBudgetTotalExists
+}
+
+// budgetUsage 计算预算周期内的支出
+func budgetUsage(ctx context.Context, budget *Budget) (Money, error) {
+	query := `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM bills
+		WHERE user_id = ? AND type = 'expense' AND date BETWEEN ? AND ?`
+	args := []interface{}{budget.UserID, budget.StartDate.Format("2006-01-02"), budget.EndDate.Format("2006-01-02")}
+	filter, filterArgs := budgetScopeFilter(budget)
+	query += filter
+	args = append(args, filterArgs...)
+
+	var used Money
+	if err := dbQueryRow(ctx, query, args...).Scan(&used); err != nil {
+		logs.Error("Error calculating used amount: %v", err)
+		return 0, err
+	}
+	return used, nil
+}
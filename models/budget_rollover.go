@@ -0,0 +1,226 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 预算结转方式，决定上一周期预算的结余或超支如何计入本周期
+const (
+	RolloverNone    = "none"    // 不结转
+	RolloverSurplus = "surplus" // 只结转结余
+	RolloverDeficit = "deficit" // 只结转超支，从本周期扣除
+	RolloverBoth    = "both"    // 结余和超支都结转
+)
+
+// RolloverAmount 按结转方式计算上一周期剩余金额中结转到本周期的部分
+//
+// leftover为上一周期可用金额减去已使用金额，负数表示超支；capAmount大于0时限制结转金额的绝对值。
+func RolloverAmount(mode string, capAmount, leftover Money) Money {
+	switch mode {
+	case RolloverSurplus:
+		if leftover < 0 {
+			return 0
+		}
+	case RolloverDeficit:
+		if leftover > 0 {
+			return 0
+		}
+	case RolloverBoth:
+	default:
+		return 0
+	}
+
+	if capAmount > 0 {
+		if leftover > capAmount {
+			return capAmount
+		}
+		if leftover < -capAmount {
+			return -capAmount
+		}
+	}
+	return leftover
+}
+
+// BudgetPercentage 已使用金额占可用金额的百分比，可用金额被上一周期超支抵消为0或负数时视为已用完
+func BudgetPercentage(used, available Money) float64 {
+	if available <= 0 {
+		return 100
+	}
+	return used.Percent(available)
+}
+
+// rolloverPeriod 结转链上的一个周期
+type rolloverPeriod struct {
+	start     time.Time
+	end       time.Time
+	amount    Money
+	mode      string
+	capAmount Money
+}
+
+// applyCarryOver 计算预算的结转金额、可用金额和使用百分比，UsedAmount需已计算
+//
+// 同一分类（或总预算、相同组合）相同周期类型的预算首尾相接时组成结转链，中间有空档时链条中断。
+// 每个周期的结转金额由该周期预算的结转方式和上限决定，上一周期的可用金额本身也包含更早的结转。
+func applyCarryOver(ctx context.Context, budget *Budget) error {
+	carry, err := carryOver(ctx, budget)
+	if err != nil {
+		return err
+	}
+	budget.CarryOver = carry
+	budget.Available = budget.Amount + carry
+	budget.Percentage = BudgetPercentage(budget.UsedAmount, budget.Available)
+	return nil
+}
+
+// carryOver 计算从上一周期结转到budget的金额
+func carryOver(ctx context.Context, budget *Budget) (Money, error) {
+	if budget.RolloverMode == "" || budget.RolloverMode == RolloverNone {
+		return 0, nil
+	}
+
+	chain, err := rolloverChain(ctx, budget)
+	if err != nil || len(chain) == 0 {
+		return 0, err
+	}
+
+	used, err := periodUsage(ctx, budget, chain)
+	if err != nil {
+		return 0, err
+	}
+
+	// 从链条最早的周期开始逐个计算，最早的周期没有结转
+	var carry Money
+	for i, p := range chain {
+		leftover := p.amount + carry - used[i]
+		next := budget.RolloverMode
+		nextCap := budget.RolloverCap
+		if i+1 < len(chain) {
+			next = chain[i+1].mode
+			nextCap = chain[i+1].capAmount
+		}
+		carry = RolloverAmount(next, nextCap, leftover)
+	}
+	return carry, nil
+}
+
+// rolloverChain 获取budget之前首尾相接的同类预算，按开始日期升序；遇到不结转的预算时，更早的周期不再影响结果
+func rolloverChain(ctx context.Context, budget *Budget) ([]*rolloverPeriod, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT DATE_FORMAT(start_date, '%Y-%m-%d'), DATE_FORMAT(end_date, '%Y-%m-%d'), amount, rollover_mode, rollover_cap
+		FROM budgets
+		WHERE user_id = ? AND period = ? AND start_date < ? AND `
+	args := []interface{}{budget.UserID, budget.Period, budget.StartDate.Format("2006-01-02")}
+	if budget.CategoryID > 0 {
+		query += "category_id = ?"
+		args = append(args, budget.CategoryID)
+	} else {
+		query += "category_id IS NULL AND scope_key = ?"
+		args = append(args, budgetScopeKey(budget.CategoryIDs, budget.Tags))
+	}
+	query += " ORDER BY start_date DESC"
+
+	rows, err := dbQuery(ctx, query, args...)
+	if err != nil {
+		logs.Error("Error querying rollover budgets: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	chain := make([]*rolloverPeriod, 0)
+	expected := budget.StartDate.AddDate(0, 0, -1)
+	for rows.Next() {
+		p := &rolloverPeriod{}
+		var startStr, endStr string
+		if err := rows.Scan(&startStr, &endStr, &p.amount, &p.mode, &p.capAmount); err != nil {
+			logs.Error("Error scanning rollover budget row: %v", err)
+			return nil, err
+		}
+		if p.start, err = time.Parse("2006-01-02", startStr); err != nil {
+			logs.Error("Error parsing start date from database: %v", err)
+			return nil, err
+		}
+		if p.end, err = time.Parse("2006-01-02", endStr); err != nil {
+			logs.Error("Error parsing end date from database: %v", err)
+			return nil, err
+		}
+		if !p.end.Equal(expected) {
+			break
+		}
+		chain = append(chain, p)
+		if p.mode == RolloverNone {
+			break
+		}
+		expected = p.start.AddDate(0, 0, -1)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating rollover budget rows: %v", err)
+		return nil, err
+	}
+
+	// 转为升序
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// periodUsage 按天汇总结转链覆盖范围内属于budget统计范围的支出，再归入各个周期，返回值与chain一一对应
+func periodUsage(ctx context.Context, budget *Budget, chain []*rolloverPeriod) ([]Money, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT DATE_FORMAT(date, '%Y-%m-%d'), COALESCE(SUM(amount), 0)
+		FROM bills
+		WHERE user_id = ? AND type = 'expense' AND date BETWEEN ? AND ?`
+	args := []interface{}{budget.UserID, chain[0].start.Format("2006-01-02"), chain[len(chain)-1].end.Format("2006-01-02")}
+	filter, filterArgs := budgetScopeFilter(budget)
+	query += filter
+	args = append(args, filterArgs...)
+	query += " GROUP BY date ORDER BY date"
+
+	rows, err := dbQuery(ctx, query, args...)
+	if err != nil {
+		logs.Error("Error querying rollover usage: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	used := make([]Money, len(chain))
+	i := 0
+	for rows.Next() {
+		var dayStr string
+		var amount Money
+		if err := rows.Scan(&dayStr, &amount); err != nil {
+			logs.Error("Error scanning rollover usage: %v", err)
+			return nil, err
+		}
+		day, err := time.Parse("2006-01-02", dayStr)
+		if err != nil {
+			logs.Error("Error parsing date from database: %v", err)
+			return nil, err
+		}
+		// 周期首尾相接且按日期升序，依次向后查找所在周期
+		for i < len(chain) && day.After(chain[i].end) {
+			i++
+		}
+		if i < len(chain) {
+			used[i] += amount
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating rollover usage: %v", err)
+		return nil, err
+	}
+
+	return used, nil
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// maxBudgetCategories 组合预算最多包含的分类数
+const maxBudgetCategories = 20
+
+// isGroup 请求是否为组合预算，即按多个分类或标签统计的预算
+func (r *BudgetRequest) isGroup() bool {
+	return len(r.CategoryIDs) > 0 || len(r.Tags) > 0
+}
+
+// normalizeScope 去除重复的分类和标签，分类按ID排序
+func (r *BudgetRequest) normalizeScope() {
+	seen := make(map[uint]bool, len(r.CategoryIDs))
+	ids := make([]uint, 0, len(r.CategoryIDs))
+	for _, id := range r.CategoryIDs {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	r.CategoryIDs = ids
+	r.Tags = normalizeTags(r.Tags)
+	r.Name = strings.TrimSpace(r.Name)
+}
+
+// IsGroup 是否为组合预算
+func (b *Budget) IsGroup() bool {
+	return len(b.CategoryIDs) > 0 || len(b.Tags) > 0
+}
+
+// DisplayName 预算的显示名称：组合预算为预算名称，分类预算为分类名称，总预算为空
+func (b *Budget) DisplayName() string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return b.CategoryName
+}
+
+// budgetScopeKey 组合预算范围的标识，分类和标签都相同的组合预算标识相同，非组合预算为空
+//
+// 用于在结转链中找到同一组合的预算，以及区分组合预算和同样没有category_id的总预算。
+func budgetScopeKey(categoryIDs []uint, tags []string) string {
+	if len(categoryIDs) == 0 && len(tags) == 0 {
+		return ""
+	}
+
+	ids := make([]string, 0, len(categoryIDs))
+	for _, id := range categoryIDs {
+		ids = append(ids, strconv.FormatUint(uint64(id), 10))
+	}
+	lower := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		lower = append(lower, strings.ToLower(tag))
+	}
+	sort.Strings(lower)
+
+	sum := sha256.Sum256([]byte("c:" + strings.Join(ids, ",") + ";t:" + strings.Join(lower, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// placeholders 生成n个以逗号分隔的占位符
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// budgetScopeFilter 预算统计范围对应的账单查询条件，以AND开头，账单表需使用bills作为表名
+func budgetScopeFilter(b *Budget) (string, []interface{}) {
+	if b.CategoryID > 0 {
+		return " AND category_id = ?", []interface{}{b.CategoryID}
+	}
+	if !b.IsGroup() {
+		return "", nil
+	}
+
+	// 同时属于多个分类或带有多个标签的账单只统计一次
+	conditions := make([]string, 0, 2)
+	args := make([]interface{}, 0, len(b.CategoryIDs)+len(b.Tags))
+	if len(b.CategoryIDs) > 0 {
+		conditions = append(conditions, "category_id IN ("+placeholders(len(b.CategoryIDs))+")")
+		for _, id := range b.CategoryIDs {
+			args = append(args, id)
+		}
+	}
+	if len(b.Tags) > 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM bill_tags bt WHERE bt.bill_id = bills.id AND bt.tag IN ("+placeholders(len(b.Tags))+"))")
+		for _, tag := range b.Tags {
+			args = append(args, tag)
+		}
+	}
+	return " AND (" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// loadBudgetScope 加载组合预算的分类和标签
+func loadBudgetScope(ctx context.Context, b *Budget) error {
+	rows, err := dbQuery(ctx, "SELECT category_id FROM budget_categories WHERE budget_id = ? ORDER BY category_id", b.ID)
+	if err != nil {
+		logs.Error("Error querying budget categories: %v", err)
+		return err
+	}
+	b.CategoryIDs = make([]uint, 0)
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			logs.Error("Error scanning budget category: %v", err)
+			return err
+		}
+		b.CategoryIDs = append(b.CategoryIDs, id)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		logs.Error("Error iterating budget categories: %v", err)
+		return err
+	}
+
+	rows, err = dbQuery(ctx, "SELECT tag FROM budget_tags WHERE budget_id = ? ORDER BY id", b.ID)
+	if err != nil {
+		logs.Error("Error querying budget tags: %v", err)
+		return err
+	}
+	defer rows.Close()
+	b.Tags = make([]string, 0)
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			logs.Error("Error scanning budget tag: %v", err)
+			return err
+		}
+		b.Tags = append(b.Tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating budget tags: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// saveBudgetScope 替换组合预算的全部分类和标签
+func saveBudgetScope(ctx context.Context, tx *sql.Tx, budgetID uint, categoryIDs []uint, tags []string) error {
+	if _, err := txExec(ctx, tx, "DELETE FROM budget_categories WHERE budget_id = ?", budgetID); err != nil {
+		logs.Error("Error deleting budget categories: %v", err)
+		return err
+	}
+	if _, err := txExec(ctx, tx, "DELETE FROM budget_tags WHERE budget_id = ?", budgetID); err != nil {
+		logs.Error("Error deleting budget tags: %v", err)
+		return err
+	}
+
+	for _, id := range categoryIDs {
+		if _, err := txExec(ctx, tx, "INSERT INTO budget_categories (budget_id, category_id) VALUES (?, ?)", budgetID, id); err != nil {
+			logs.Error("Error inserting budget category: %v", err)
+			return err
+		}
+	}
+	for _, tag := range tags {
+		if _, err := txExec(ctx, tx, "INSERT INTO budget_tags (budget_id, tag) VALUES (?, ?)", budgetID, tag); err != nil {
+			logs.Error("Error inserting budget tag: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkBudgetScope 检查组合预算的分类都属于用户且为支出分类
+func checkBudgetScope(ctx context.Context, userID uint, req *BudgetRequest) error {
+	for _, id := range req.CategoryIDs {
+		if err := checkBudgetCategory(ctx, userID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBudgetScopeOverlap 检查分类或标签是否已被另一个时间重叠的同类周期预算统计，excludeID为修改中的预算
+//
+// 同一笔支出不应同时计入两个同类预算：分类预算和包含该分类的组合预算、两个包含相同分类或标签的组合预算都视为重叠。
+// 总预算统计所有支出，不参与检查。
+func checkBudgetScopeOverlap(ctx context.Context, userID, excludeID uint, req *BudgetRequest, period string, start, end time.Time) error {
+	categoryIDs := req.CategoryIDs
+	if req.CategoryID > 0 {
+		categoryIDs = []uint{req.CategoryID}
+	}
+	if len(categoryIDs) == 0 && len(req.Tags) == 0 {
+		return nil
+	}
+
+	conditions := make([]string, 0, 3)
+	args := []interface{}{userID, excludeID, period, end.Format("2006-01-02"), start.Format("2006-01-02")}
+	if len(categoryIDs) > 0 {
+		in := placeholders(len(categoryIDs))
+		conditions = append(conditions,
+			"category_id IN ("+in+")",
+			"id IN (SELECT budget_id FROM budget_categories WHERE category_id IN ("+in+"))",
+		)
+		for i := 0; i < 2; i++ {
+			for _, id := range categoryIDs {
+				args = append(args, id)
+			}
+		}
+	}
+	if len(req.Tags) > 0 {
+		conditions = append(conditions, "id IN (SELECT budget_id FROM budget_tags WHERE tag IN ("+placeholders(len(req.Tags))+"))")
+		for _, tag := range req.Tags {
+			args = append(args, tag)
+		}
+	}
+
+	var count int
+	err := dbQueryRow(ctx, `
+		SELECT COUNT(*) FROM budgets
+		WHERE user_id = ? AND id != ? AND period = ? AND start_date <= ? AND end_date >= ? AND (`+strings.Join(conditions, " OR ")+`)
+	`, args...).Scan(&count)
+	if err != nil {
+		logs.Error("Error checking overlapping budget scopes: %v", err)
+		return err
+	}
+	if count > 0 {
+		return ErrBudgetScopeOverlap
+	}
+	return nil
+}
@@ -0,0 +1,567 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/core/validation"
+)
+
+// 复制预算时允许的调整百分比范围
+const (
+	minAdjustPercent = -99.99
+	maxAdjustPercent = 1000
+)
+
+// BudgetTemplate 预算模板，在start_month到end_month之间的每个月自动生成预算
+type BudgetTemplate struct {
+	ID                 uint      `json:"id"`
+	UserID             uint      `json:"user_id"`
+	CategoryID         uint      `json:"category_id,omitempty"`
+	CategoryName       string    `json:"category_name,omitempty"`
+	Amount             Money     `json:"amount"`
+	StartMonth         string    `json:"start_month"`
+	EndMonth           string    `json:"end_month,omitempty"` // 为空表示直到取消
+	RolloverMode       string    `json:"rollover_mode"`
+	RolloverCap        Money     `json:"rollover_cap,omitempty"`
+	LastGeneratedMonth string    `json:"last_generated_month,omitempty"` // 最近一次生成预算的月份
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// BudgetTemplateRequest 预算模板请求参数
+type BudgetTemplateRequest struct {
+	CategoryID   uint   `json:"category_id"` // 为0时为总预算
+	Amount       Money  `json:"amount" valid:"Required;Money"`
+	StartMonth   string `json:"start_month" valid:"Required;Month"`
+	EndMonth     string `json:"end_month" valid:"Month"`
+	RolloverMode string `json:"rollover_mode" valid:"Match(/^(none|surplus|deficit|both)?$/)"`
+	RolloverCap  Money  `json:"rollover_cap"`
+}
+
+// Valid 结束月份不能早于开始月份，结转上限不能为负数
+func (r *BudgetTemplateRequest) Valid(v *validation.Validation) {
+	if r.EndMonth != "" && r.EndMonth < r.StartMonth {
+		v.AddError("EndMonth.Month.", "budget_template_end_before_start")
+	}
+	if r.RolloverCap < 0 || r.RolloverCap > MaxAmount {
+		v.AddError("RolloverCap.Money.", "money_out_of_range")
+	}
+}
+
+// BudgetAdjustment 复制预算时单个预算的调整百分比
+type BudgetAdjustment struct {
+	BudgetID uint    `json:"budget_id"` // 源月份中的预算ID
+	Percent  float64 `json:"percent"`
+}
+
+// BudgetCopyRequest 复制预算请求参数
+type BudgetCopyRequest struct {
+	FromMonth   string             `json:"from_month" valid:"Month"` // 默认为to_month的上一个月
+	ToMonth     string             `json:"to_month" valid:"Required;Month"`
+	Percent     float64            `json:"percent"`     // 所有预算的调整百分比，10表示增加10%
+	Adjustments []BudgetAdjustment `json:"adjustments"` // 按源预算的调整百分比，优先于percent
+}
+
+// Valid 源月份和目标月份不能相同，调整百分比必须大于-100%，即调整后的金额必须为正数
+func (r *BudgetCopyRequest) Valid(v *validation.Validation) {
+	if r.FromMonth != "" && r.FromMonth == r.ToMonth {
+		v.AddError("FromMonth.Month.", "budget_copy_same_month")
+	}
+	percents := []float64{r.Percent}
+	for _, a := range r.Adjustments {
+		if a.BudgetID == 0 {
+			v.AddError("Adjustments.Required.", "adjust_budget_required")
+			return
+		}
+		percents = append(percents, a.Percent)
+	}
+	for _, p := range percents {
+		if p < minAdjustPercent || p > maxAdjustPercent {
+			v.AddError("Percent.Range.", "adjust_percent_out_of_range")
+			return
+		}
+	}
+}
+
+// percentFor 源预算使用的调整百分比
+func (r *BudgetCopyRequest) percentFor(budgetID uint) float64 {
+	for _, a := range r.Adjustments {
+		if a.BudgetID == budgetID {
+			return a.Percent
+		}
+	}
+	return r.Percent
+}
+
+// BudgetCopyResult 复制预算的结果
+type BudgetCopyResult struct {
+	FromMonth string    `json:"from_month"`
+	ToMonth   string    `json:"to_month"`
+	Created   []*Budget `json:"created"`
+	Skipped   int       `json:"skipped"`  // 目标月份已有相同预算而跳过的数量
+	Rejected  []uint    `json:"rejected"` // 调整后金额为0或超出上限而未复制的源预算ID
+}
+
+// orNone 未指定结转方式时不结转
+func orNone(mode string) string {
+	if mode == "" {
+		return RolloverNone
+	}
+	return mode
+}
+
+// budgetTemplateColumns 模板的查询列，与scanBudgetTemplate对应
+const budgetTemplateColumns = `t.id, t.user_id, t.category_id, c.name, t.amount,
+	DATE_FORMAT(t.start_month, '%Y-%m'), DATE_FORMAT(t.end_month, '%Y-%m'), t.rollover_mode, t.rollover_cap,
+	DATE_FORMAT(t.last_generated_month, '%Y-%m'), t.created_at, t.updated_at`
+
+// scanBudgetTemplate 读取一行模板数据，之后可以跟随额外的列
+func scanBudgetTemplate(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*BudgetTemplate, error) {
+	t := &BudgetTemplate{}
+	var categoryID sql.NullInt64
+	var categoryName, endMonth, lastGenerated sql.NullString
+	dest := append([]interface{}{
+		&t.ID, &t.UserID, &categoryID, &categoryName, &t.Amount,
+		&t.StartMonth, &endMonth, &t.RolloverMode, &t.RolloverCap,
+		&lastGenerated, &t.CreatedAt, &t.UpdatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if err != sql.ErrNoRows {
+			logs.Error("Error scanning budget template row: %v", err)
+		}
+		return nil, err
+	}
+	t.CategoryID = uint(categoryID.Int64)
+	t.CategoryName = categoryName.String
+	t.EndMonth = endMonth.String
+	t.LastGeneratedMonth = lastGenerated.String
+	return t, nil
+}
+
+// covers 月份是否在模板的有效范围内
+func (t *BudgetTemplate) covers(month string) bool {
+	return t.StartMonth <= month && (t.EndMonth == "" || month <= t.EndMonth)
+}
+
+// GetBudgetTemplates 获取用户的预算模板
+func GetBudgetTemplates(ctx context.Context, userID uint) ([]*BudgetTemplate, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT `+budgetTemplateColumns+`
+		FROM budget_templates t
+		LEFT JOIN categories c ON t.category_id = c.id
+		WHERE t.user_id = ?
+		ORDER BY t.category_id IS NULL DESC, c.name, t.start_month
+	`, userID)
+	if err != nil {
+		logs.Error("Error querying budget templates: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]*BudgetTemplate, 0)
+	for rows.Next() {
+		t, err := scanBudgetTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating budget template rows: %v", err)
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// GetBudgetTemplate 获取单个预算模板
+func GetBudgetTemplate(ctx context.Context, id, userID uint) (*BudgetTemplate, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	t, err := scanBudgetTemplate(dbQueryRow(ctx, `
+		SELECT `+budgetTemplateColumns+`
+		FROM budget_templates t
+		LEFT JOIN categories c ON t.category_id = c.id
+		WHERE t.id = ? AND t.user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrBudgetTemplateNotFound
+	}
+	return t, err
+}
+
+// checkBudgetTemplate 检查分类归属以及同一分类的模板在时间上是否重叠，excludeID为修改中的模板
+func checkBudgetTemplate(ctx context.Context, userID, excludeID uint, req *BudgetTemplateRequest) error {
+	if req.CategoryID > 0 {
+		var categoryType string
+		err := dbQueryRow(ctx,
+			"SELECT type FROM categories WHERE id = ? AND user_id = ?",
+			req.CategoryID, userID,
+		).Scan(&categoryType)
+		if err == sql.ErrNoRows {
+			return ErrCategoryNotOwned
+		}
+		if err != nil {
+			logs.Error("Error checking category: %v", err)
+			return err
+		}
+		if categoryType != "expense" {
+			return ErrBudgetExpenseOnly
+		}
+	}
+
+	// 两个范围[a1, a2]和[b1, b2]重叠当且仅当a1 <= b2且b1 <= a2，结束月份为空表示无限
+	query := `
+		SELECT COUNT(*) FROM budget_templates
+		WHERE user_id = ? AND id != ? AND (end_month IS NULL OR end_month >= ?)`
+	args := []interface{}{userID, excludeID, req.StartMonth + "-01"}
+	if req.EndMonth != "" {
+		query += " AND start_month <= ?"
+		args = append(args, req.EndMonth+"-01")
+	}
+	if req.CategoryID > 0 {
+		query += " AND category_id = ?"
+		args = append(args, req.CategoryID)
+	} else {
+		query += " AND category_id IS NULL"
+	}
+
+	var count int
+	if err := dbQueryRow(ctx, query, args...).Scan(&count); err != nil {
+		logs.Error("Error checking overlapping budget templates: %v", err)
+		return err
+	}
+	if count > 0 {
+		return ErrBudgetTemplateExists
+	}
+	return nil
+}
+
+// nullableMonth 月份写入为当月1日，空字符串写入为NULL
+func nullableMonth(month string) interface{} {
+	if month == "" {
+		return nil
+	}
+	return month + "-01"
+}
+
+// nullableCategory 分类ID为0时写入为NULL
+func nullableCategory(categoryID uint) interface{} {
+	if categoryID == 0 {
+		return nil
+	}
+	return categoryID
+}
+
+// CreateBudgetTemplate 创建预算模板，同一分类的模板时间范围不能重叠
+func CreateBudgetTemplate(ctx context.Context, userID uint, req *BudgetTemplateRequest) (*BudgetTemplate, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if err := checkBudgetTemplate(ctx, userID, 0, req); err != nil {
+		return nil, err
+	}
+
+	result, err := dbExec(ctx, `
+		INSERT INTO budget_templates (user_id, category_id, amount, start_month, end_month, rollover_mode, rollover_cap)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, userID, nullableCategory(req.CategoryID), req.Amount, req.StartMonth+"-01", nullableMonth(req.EndMonth),
+		orNone(req.RolloverMode), req.RolloverCap)
+	if err != nil {
+		logs.Error("Error creating budget template: %v", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logs.Error("Error getting budget template ID: %v", err)
+		return nil, err
+	}
+
+	return GetBudgetTemplate(ctx, uint(id), userID)
+}
+
+// UpdateBudgetTemplate 修改预算模板，只影响之后生成的预算
+func UpdateBudgetTemplate(ctx context.Context, id, userID uint, req *BudgetTemplateRequest) (*BudgetTemplate, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := GetBudgetTemplate(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	if err := checkBudgetTemplate(ctx, userID, id, req); err != nil {
+		return nil, err
+	}
+
+	_, err := dbExec(ctx, `
+		UPDATE budget_templates
+		SET category_id = ?, amount = ?, start_month = ?, end_month = ?, rollover_mode = ?, rollover_cap = ?
+		WHERE id = ? AND user_id = ?
+	`, nullableCategory(req.CategoryID), req.Amount, req.StartMonth+"-01", nullableMonth(req.EndMonth),
+		orNone(req.RolloverMode), req.RolloverCap, id, userID)
+	if err != nil {
+		logs.Error("Error updating budget template: %v", err)
+		return nil, err
+	}
+
+	return GetBudgetTemplate(ctx, id, userID)
+}
+
+// DeleteBudgetTemplate 删除预算模板，已生成的预算保留
+func DeleteBudgetTemplate(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Error("Error starting transaction: %v", err)
+		return err
+	}
+
+	result, err := txExec(ctx, tx, "DELETE FROM budget_templates WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		tx.Rollback()
+		logs.Error("Error deleting budget template: %v", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		logs.Error("Error getting affected rows: %v", err)
+		return err
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return ErrBudgetTemplateNotFound
+	}
+
+	_, err = txExec(ctx, tx, "UPDATE budgets SET template_id = NULL WHERE template_id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		tx.Rollback()
+		logs.Error("Error detaching budgets from template: %v", err)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logs.Error("Error committing transaction: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// GenerateTemplateBudget 为模板生成用户时区下当月的预算，模板不覆盖当月、当月已生成过或已有同类预算时返回nil
+func GenerateTemplateBudget(ctx context.Context, t *BudgetTemplate) (*Budget, error) {
+	loc, err := UserLocation(ctx, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return generateTemplateBudget(ctx, t, CurrentMonth(loc))
+}
+
+// generateTemplateBudget 为模板生成指定月份的预算
+//
+// 先占用月份再创建预算：多个实例同时运行时只有一个能占用；已生成的预算被用户删除后也不会再次生成。
+func generateTemplateBudget(ctx context.Context, t *BudgetTemplate, month string) (*Budget, error) {
+	if !t.covers(month) || t.LastGeneratedMonth >= month {
+		return nil, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx, `
+		UPDATE budget_templates SET last_generated_month = ?
+		WHERE id = ? AND (last_generated_month IS NULL OR last_generated_month < ?)
+	`, month+"-01", t.ID, month+"-01")
+	if err != nil {
+		logs.Error("Error claiming budget template: %v", err)
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, nil
+	}
+	previous := t.LastGeneratedMonth
+	t.LastGeneratedMonth = month
+
+	budget, err := CreateBudget(ctx, t.UserID, &BudgetRequest{
+		CategoryID:   t.CategoryID,
+		Amount:       t.Amount,
+		Month:        month,
+		RolloverMode: t.RolloverMode,
+		RolloverCap:  t.RolloverCap,
+	})
+	if isBudgetConflict(err) {
+		return nil, nil
+	}
+	if err != nil {
+		// 创建失败时释放月份，下次检查时重试
+		_, releaseErr := dbExec(context.Background(),
+			"UPDATE budget_templates SET last_generated_month = ? WHERE id = ? AND last_generated_month = ?",
+			nullableMonth(previous), t.ID, month+"-01",
+		)
+		if releaseErr != nil {
+			logs.Error("Error releasing budget template: %v", releaseErr)
+		}
+		t.LastGeneratedMonth = previous
+		return nil, err
+	}
+
+	if _, err = dbExec(ctx, "UPDATE budgets SET template_id = ? WHERE id = ?", t.ID, budget.ID); err != nil {
+		logs.Error("Error linking budget to template: %v", err)
+		return nil, err
+	}
+	budget.TemplateID = t.ID
+
+	return budget, nil
+}
+
+// GenerateDueBudgets 为所有用户的模板生成各自时区下当月的预算，返回新生成的预算；单个模板失败不影响其他模板
+func GenerateDueBudgets(ctx context.Context) ([]*Budget, error) {
+	queryCtx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// 结束月份早于上个月的模板在任何时区都已过期
+	rows, err := dbQuery(queryCtx, `
+		SELECT `+budgetTemplateColumns+`, u.timezone
+		FROM budget_templates t
+		JOIN users u ON t.user_id = u.id
+		LEFT JOIN categories c ON t.category_id = c.id
+		WHERE t.end_month IS NULL OR t.end_month >= DATE_FORMAT(CURDATE() - INTERVAL 1 MONTH, '%Y-%m-01')
+		ORDER BY t.id
+	`)
+	if err != nil {
+		logs.Error("Error querying due budget templates: %v", err)
+		return nil, err
+	}
+
+	type dueTemplate struct {
+		template *BudgetTemplate
+		month    string
+	}
+	due := make([]dueTemplate, 0)
+	for rows.Next() {
+		var timezone string
+		t, err := scanBudgetTemplate(rows, &timezone)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		month := CurrentMonth(LoadLocation(timezone))
+		if t.covers(month) && t.LastGeneratedMonth < month {
+			due = append(due, dueTemplate{template: t, month: month})
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		logs.Error("Error iterating budget template rows: %v", err)
+		return nil, err
+	}
+
+	budgets := make([]*Budget, 0)
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return budgets, ctx.Err()
+		}
+		budget, err := generateTemplateBudget(ctx, d.template, d.month)
+		if err != nil {
+			logs.Error("Error generating budget from template %d: %v", d.template.ID, err)
+			continue
+		}
+		if budget != nil {
+			budgets = append(budgets, budget)
+		}
+	}
+	return budgets, nil
+}
+
+// isBudgetConflict 是否因已有同类预算或统计范围重叠而无法创建预算
+func isBudgetConflict(err error) bool {
+	return errors.Is(err, ErrBudgetCategoryExists) || errors.Is(err, ErrBudgetTotalExists) || errors.Is(err, ErrBudgetScopeOverlap)
+}
+
+// CopyBudgets 把源月份的月度预算按调整百分比复制到目标月份，目标月份已有的同类预算跳过
+//
+// 所有预算在同一个事务中创建，任一预算创建失败时整个复制不生效。
+func CopyBudgets(ctx context.Context, userID uint, req *BudgetCopyRequest) (*BudgetCopyResult, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	toMonth, err := time.Parse("2006-01", req.ToMonth)
+	if err != nil {
+		return nil, ErrInvalidMonth
+	}
+	fromMonth := req.FromMonth
+	if fromMonth == "" {
+		fromMonth = toMonth.AddDate(0, -1, 0).Format("2006-01")
+	}
+
+	source, err := GetBudgets(ctx, userID, fromMonth)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		logs.Error("Error starting transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &BudgetCopyResult{FromMonth: fromMonth, ToMonth: req.ToMonth, Created: make([]*Budget, 0), Rejected: make([]uint, 0)}
+	ids := make([]uint, 0)
+	for _, b := range FilterBudgets(source, BudgetMonthly) {
+		amount := b.Amount.Adjust(req.percentFor(b.ID))
+		if amount <= 0 || amount > MaxAmount {
+			result.Rejected = append(result.Rejected, b.ID)
+			continue
+		}
+		id, err := createBudget(ctx, tx, userID, &BudgetRequest{
+			CategoryID:   b.CategoryID,
+			Name:         b.Name,
+			CategoryIDs:  b.CategoryIDs,
+			Tags:         b.Tags,
+			Amount:       amount,
+			Month:        req.ToMonth,
+			RolloverMode: b.RolloverMode,
+			RolloverCap:  b.RolloverCap,
+		})
+		if isBudgetConflict(err) {
+			result.Skipped++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logs.Error("Error committing budget copy: %v", err)
+		return nil, err
+	}
+
+	for _, id := range ids {
+		budget, err := GetBudget(ctx, id, userID)
+		if err != nil {
+			return nil, err
+		}
+		result.Created = append(result.Created, budget)
+	}
+	return result, nil
+}
@@ -1,8 +1,8 @@
 package models
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/beego/beego/v2/core/logs"
@@ -22,22 +22,25 @@ type Category struct {
 // CategoryRequest 分类请求参数
 type CategoryRequest struct {
 	Name string `json:"name" valid:"Required;MinSize(1);MaxSize(50)"`
-	Type string `json:"type" valid:"Required;Match(income|expense)"`
-	Icon string `json:"icon,omitempty"`
+	Type string `json:"type" valid:"Required;Match(/^(income|expense)$/)"`
+	Icon string `json:"icon,omitempty" valid:"MaxSize(50)"`
 }
 
 // GetCategories 获取用户的所有分类
-func GetCategories(userID uint, categoryType string) ([]*Category, error) {
+func GetCategories(ctx context.Context, userID uint, categoryType string) ([]*Category, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var rows *sql.Rows
 	var err error
 	
 	if categoryType != "" {
-		rows, err = DB.Query(
+		rows, err = dbQuery(ctx, 
 			"SELECT id, user_id, name, type, icon, created_at, updated_at FROM categories WHERE user_id = ? AND type = ? ORDER BY name",
 			userID, categoryType,
 		)
 	} else {
-		rows, err = DB.Query(
+		rows, err = dbQuery(ctx, 
 			"SELECT id, user_id, name, type, icon, created_at, updated_at FROM categories WHERE user_id = ? ORDER BY type, name",
 			userID,
 		)
@@ -77,9 +80,12 @@ func GetCategories(userID uint, categoryType string) ([]*Category, error) {
 }
 
 // GetCategory 获取单个分类
-func GetCategory(id, userID uint) (*Category, error) {
+func GetCategory(ctx context.Context, id, userID uint) (*Category, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	category := &Category{}
-	err := DB.QueryRow(
+	err := dbQueryRow(ctx, 
 		"SELECT id, user_id, name, type, icon, created_at, updated_at FROM categories WHERE id = ? AND user_id = ?",
 		id, userID,
 	).Scan(
@@ -94,7 +100,7 @@ func GetCategory(id, userID uint) (*Category, error) {
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("分类不存在")
+			return nil, ErrCategoryNotFound
 		}
 		logs.Error("Error querying category: %v", err)
 		return nil, err
@@ -104,10 +110,13 @@ func GetCategory(id, userID uint) (*Category, error) {
 }
 
 // CreateCategory 创建新分类
-func CreateCategory(userID uint, req *CategoryRequest) (*Category, error) {
+func CreateCategory(ctx context.Context, userID uint, req *CategoryRequest) (*Category, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查分类名是否已存在
 	var exists bool
-	err := DB.QueryRow(
+	err := dbQueryRow(ctx, 
 		"SELECT EXISTS(SELECT 1 FROM categories WHERE user_id = ? AND name = ? AND type = ?)",
 		userID, req.Name, req.Type,
 	).Scan(&exists)
@@ -118,15 +127,18 @@ func CreateCategory(userID uint, req *CategoryRequest) (*Category, error) {
 	}
 	
 	if exists {
-		return nil, errors.New("分类名已存在")
+		return nil, ErrCategoryExists
 	}
 	
 	// 创建分类
-	result, err := DB.Exec(
+	result, err := dbExec(ctx, 
 		"INSERT INTO categories (user_id, name, type, icon) VALUES (?, ?, ?, ?)",
 		userID, req.Name, req.Type, req.Icon,
 	)
 	
+	if isDuplicateEntry(err) {
+		return nil, ErrCategoryExists
+	}
 	if err != nil {
 		logs.Error("Error creating category: %v", err)
 		return nil, err
@@ -140,7 +152,7 @@ func CreateCategory(userID uint, req *CategoryRequest) (*Category, error) {
 	}
 	
 	// 查询完整的分类信息
-	category, err := GetCategory(uint(categoryID), userID)
+	category, err := GetCategory(ctx, uint(categoryID), userID)
 	if err != nil {
 		logs.Error("Error fetching new category: %v", err)
 		return nil, err
@@ -150,16 +162,19 @@ func CreateCategory(userID uint, req *CategoryRequest) (*Category, error) {
 }
 
 // UpdateCategory 更新分类
-func UpdateCategory(id, userID uint, req *CategoryRequest) (*Category, error) {
+func UpdateCategory(ctx context.Context, id, userID uint, req *CategoryRequest) (*Category, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查分类是否存在
-	_, err := GetCategory(id, userID)
+	_, err := GetCategory(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
 	
 	// 检查修改后的名称是否与其他分类冲突
 	var exists bool
-	err = DB.QueryRow(
+	err = dbQueryRow(ctx, 
 		"SELECT EXISTS(SELECT 1 FROM categories WHERE user_id = ? AND name = ? AND type = ? AND id != ?)",
 		userID, req.Name, req.Type, id,
 	).Scan(&exists)
@@ -170,22 +185,25 @@ func UpdateCategory(id, userID uint, req *CategoryRequest) (*Category, error) {
 	}
 	
 	if exists {
-		return nil, errors.New("已存在同名同类型的分类")
+		return nil, ErrCategoryExists
 	}
 	
 	// 更新分类
-	_, err = DB.Exec(
+	_, err = dbExec(ctx, 
 		"UPDATE categories SET name = ?, type = ?, icon = ? WHERE id = ? AND user_id = ?",
 		req.Name, req.Type, req.Icon, id, userID,
 	)
 	
+	if isDuplicateEntry(err) {
+		return nil, ErrCategoryExists
+	}
 	if err != nil {
 		logs.Error("Error updating category: %v", err)
 		return nil, err
 	}
 	
 	// 返回更新后的分类
-	category, err := GetCategory(id, userID)
+	category, err := GetCategory(ctx, id, userID)
 	if err != nil {
 		logs.Error("Error fetching updated category: %v", err)
 		return nil, err
@@ -195,39 +213,45 @@ func UpdateCategory(id, userID uint, req *CategoryRequest) (*Category, error) {
 }
 
 // DeleteCategory 删除分类
-func DeleteCategory(id, userID uint) error {
+func DeleteCategory(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查分类是否存在
-	_, err := GetCategory(id, userID)
+	_, err := GetCategory(ctx, id, userID)
 	if err != nil {
 		return err
 	}
 	
 	// 检查分类是否被账单使用
 	var billsCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM bills WHERE category_id = ?", id).Scan(&billsCount)
+	err = dbQueryRow(ctx, "SELECT COUNT(*) FROM bills WHERE category_id = ?", id).Scan(&billsCount)
 	if err != nil {
 		logs.Error("Error checking if category is used in bills: %v", err)
 		return err
 	}
 	
 	if billsCount > 0 {
-		return errors.New("该分类已被使用，无法删除")
+		return ErrCategoryInUse
 	}
 	
 	// 检查分类是否被预算使用
 	var budgetsCount int
-	err = DB.QueryRow("SELECT COUNT(*) FROM budgets WHERE category_id = ?", id).Scan(&budgetsCount)
+	err = dbQueryRow(ctx,
+		"SELECT (SELECT COUNT(*) FROM budgets WHERE category_id = ?) + (SELECT COUNT(*) FROM budget_categories WHERE category_id = ?)",
+		id, id,
+	).Scan(&budgetsCount)
 	if err != nil {
 		logs.Error("Error checking if category is used in budgets: %v", err)
 		return err
 	}
 	
 	if budgetsCount > 0 {
-		return errors.New("该分类已设置预算，无法删除")
+		return ErrCategoryHasBudget
 	}
 	
 	// 删除分类
-	_, err = DB.Exec("DELETE FROM categories WHERE id = ? AND user_id = ?", id, userID)
+	_, err = dbExec(ctx, "DELETE FROM categories WHERE id = ? AND user_id = ?", id, userID)
 	if err != nil {
 		logs.Error("Error deleting category: %v", err)
 		return err
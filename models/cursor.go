@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Cursor 键集分页的游标，记录上一页最后一条记录的排序键和ID
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// ErrInvalidCursor 游标无法解析或与当前排序方式不一致
+var ErrInvalidCursor = Invalid("invalid_cursor")
+
+// Encode 编码为不透明的URL安全字符串
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析游标，sort为当前请求的排序方式，必须与生成游标时一致
+func DecodeCursor(s, sort string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.ID == 0 || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+)
+
+// 相对日期范围
+const (
+	RangeToday       = "today"
+	RangeYesterday   = "yesterday"
+	RangeLast7Days   = "last_7_days"
+	RangeLast30Days  = "last_30_days"
+	RangeLast90Days  = "last_90_days"
+	RangeThisWeek    = "this_week"
+	RangeLastWeek    = "last_week"
+	RangeThisMonth   = "this_month"
+	RangeLastMonth   = "last_month"
+	RangeThisQuarter = "this_quarter"
+	RangeLastQuarter = "last_quarter"
+	RangeThisYear    = "this_year"
+	RangeLastYear    = "last_year"
+)
+
+// relativeRanges 相对日期范围的计算方式，参数为用户时区下的当天日期
+var relativeRanges = map[string]func(today time.Time) (time.Time, time.Time){
+	RangeToday: func(today time.Time) (time.Time, time.Time) {
+		return today, today
+	},
+	RangeYesterday: func(today time.Time) (time.Time, time.Time) {
+		day := today.AddDate(0, 0, -1)
+		return day, day
+	},
+	RangeLast7Days: func(today time.Time) (time.Time, time.Time) {
+		return today.AddDate(0, 0, -6), today
+	},
+	RangeLast30Days: func(today time.Time) (time.Time, time.Time) {
+		return today.AddDate(0, 0, -29), today
+	},
+	RangeLast90Days: func(today time.Time) (time.Time, time.Time) {
+		return today.AddDate(0, 0, -89), today
+	},
+	RangeThisWeek: func(today time.Time) (time.Time, time.Time) {
+		start := weekStart(today)
+		return start, start.AddDate(0, 0, 6)
+	},
+	RangeLastWeek: func(today time.Time) (time.Time, time.Time) {
+		start := weekStart(today).AddDate(0, 0, -7)
+		return start, start.AddDate(0, 0, 6)
+	},
+	RangeThisMonth: func(today time.Time) (time.Time, time.Time) {
+		start := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1)
+	},
+	RangeLastMonth: func(today time.Time) (time.Time, time.Time) {
+		start := time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1)
+	},
+	RangeThisQuarter: func(today time.Time) (time.Time, time.Time) {
+		start := quarterStart(today)
+		return start, start.AddDate(0, 3, -1)
+	},
+	RangeLastQuarter: func(today time.Time) (time.Time, time.Time) {
+		start := quarterStart(today).AddDate(0, -3, 0)
+		return start, start.AddDate(0, 3, -1)
+	},
+	RangeThisYear: func(today time.Time) (time.Time, time.Time) {
+		start := time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1)
+	},
+	RangeLastYear: func(today time.Time) (time.Time, time.Time) {
+		start := time.Date(today.Year()-1, 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1)
+	},
+}
+
+// weekStart 日期所在周的周一
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// quarterStart 日期所在季度的第一天
+func quarterStart(day time.Time) time.Time {
+	month := time.Month((int(day.Month())-1)/3*3 + 1)
+	return time.Date(day.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Today 用户时区下的当天日期，以UTC零点表示，与账单日期的表示方式一致
+func Today(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// IsRelativeRange 是否为支持的相对日期范围
+func IsRelativeRange(name string) bool {
+	_, ok := relativeRanges[name]
+	return ok
+}
+
+// ResolveDateRange 计算相对日期范围的起止日期（含），格式为YYYY-MM-DD
+func ResolveDateRange(name string, today time.Time) (string, string, bool) {
+	resolve, ok := relativeRanges[name]
+	if !ok {
+		return "", "", false
+	}
+	start, end := resolve(today)
+	return start.Format("2006-01-02"), end.Format("2006-01-02"), true
+}
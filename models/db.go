@@ -1,17 +1,26 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
 	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var DB *sql.DB
 
+// QueryTimeout 单次模型调用中数据库操作的超时时间，为0时不限制
+var QueryTimeout = 5 * time.Second
+
 // InitDB 初始化数据库连接
 func InitDB() {
 	var err error
@@ -44,6 +53,16 @@ func InitDB() {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", 
 		dbUser, dbPassword, dbHost, dbPort, dbName)
 	
+	// 查询超时配置
+	if timeout, err := web.AppConfig.String("dbquerytimeout"); err == nil && timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			logs.Error("Invalid dbquerytimeout %q: %v", timeout, err)
+			panic(err)
+		}
+		QueryTimeout = d
+	}
+	
 	// 连接数据库
 	DB, err = sql.Open("mysql", dsn)
 	if err != nil {
@@ -65,10 +84,90 @@ func InitDB() {
 	
 	logs.Info("Database connected successfully")
 	
+	// 金额JSON编码方式
+	MoneyJSONString, _ = web.AppConfig.Bool("moneyjsonstring")
+	
+	// 默认时区
+	loadDefaultLocation()
+	
+	// 登录保护策略
+	loadLoginPolicy()
+	
+	// 账单搜索后端
+	loadSearchBackend()
+	
 	// 初始化表结构
 	initTables()
 }
 
+// withQueryTimeout 在调用方上下文的基础上附加查询超时
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if QueryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, QueryTimeout)
+}
+
+// startSQLSpan 为一条SQL语句创建追踪span
+func startSQLSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	statement := strings.Join(strings.Fields(query), " ")
+	operation := statement
+	if i := strings.IndexByte(statement, ' '); i > 0 {
+		operation = statement[:i]
+	}
+	operation = strings.ToUpper(operation)
+
+	return otel.Tracer("finwise").Start(ctx, "SQL "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBStatementKey.String(statement),
+		),
+	)
+}
+
+// endSQLSpan 记录SQL执行结果并结束span
+func endSQLSpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// dbQuery 执行带追踪的查询
+func dbQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	rows, err := DB.QueryContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return rows, err
+}
+
+// dbQueryRow 执行带追踪的单行查询
+func dbQueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSQLSpan(ctx, query)
+	row := DB.QueryRowContext(ctx, query, args...)
+	endSQLSpan(span, row.Err())
+	return row
+}
+
+// dbExec 执行带追踪的写操作
+func dbExec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	result, err := DB.ExecContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return result, err
+}
+
+// txExec 在事务中执行带追踪的写操作
+func txExec(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	result, err := tx.ExecContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return result, err
+}
+
 // 创建必要的表结构
 func initTables() {
 	// 用户表
@@ -80,6 +179,8 @@ func initTables() {
 			password VARCHAR(100) NOT NULL,
 			phone VARCHAR(20),
 			avatar VARCHAR(255),
+			timezone VARCHAR(64) NOT NULL DEFAULT '',
+			language VARCHAR(10) NOT NULL DEFAULT '',
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			INDEX idx_email (email),
@@ -116,15 +217,18 @@ func initTables() {
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id INT NOT NULL,
 			category_id INT NOT NULL,
-			amount DECIMAL(10,2) NOT NULL,
+			amount DECIMAL(19,2) NOT NULL,
 			type ENUM('income', 'expense') NOT NULL,
 			date DATE NOT NULL,
 			description TEXT,
+			merchant VARCHAR(100) NOT NULL DEFAULT '',
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
 			INDEX idx_user_date (user_id, date),
+			INDEX idx_user_amount (user_id, amount),
+			INDEX idx_user_created (user_id, created_at),
 			INDEX idx_category (category_id)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 	`)
@@ -133,19 +237,44 @@ func initTables() {
 		panic(err)
 	}
 	
+	// 账单标签表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS bill_tags (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			bill_id INT NOT NULL,
+			tag VARCHAR(32) NOT NULL,
+			FOREIGN KEY (bill_id) REFERENCES bills(id) ON DELETE CASCADE,
+			UNIQUE KEY uk_bill_tag (bill_id, tag),
+			INDEX idx_tag (tag)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create bill_tags table: %v", err)
+		panic(err)
+	}
+	
 	// 预算表
 	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS budgets (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			user_id INT NOT NULL,
 			category_id INT,
-			amount DECIMAL(10,2) NOT NULL,
+			name VARCHAR(50) NOT NULL DEFAULT '',
+			scope_key CHAR(64) NOT NULL DEFAULT '',
+			amount DECIMAL(19,2) NOT NULL,
 			month DATE NOT NULL,
+			period VARCHAR(10) NOT NULL DEFAULT 'monthly',
+			start_date DATE NOT NULL,
+			end_date DATE NOT NULL,
+			rollover_mode VARCHAR(10) NOT NULL DEFAULT 'none',
+			rollover_cap DECIMAL(19,2) NOT NULL DEFAULT 0,
+			template_id INT,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL,
-			UNIQUE KEY unique_budget (user_id, category_id, month)
+			UNIQUE KEY unique_budget_period (user_id, category_id, period, start_date),
+			INDEX idx_user_dates (user_id, start_date, end_date)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 	`)
 	if err != nil {
@@ -153,6 +282,39 @@ func initTables() {
 		panic(err)
 	}
 	
+	// 组合预算统计的分类
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS budget_categories (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			budget_id INT NOT NULL,
+			category_id INT NOT NULL,
+			FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE,
+			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
+			UNIQUE KEY uk_budget_category (budget_id, category_id),
+			INDEX idx_category (category_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create budget_categories table: %v", err)
+		panic(err)
+	}
+	
+	// 组合预算统计的标签
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS budget_tags (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			budget_id INT NOT NULL,
+			tag VARCHAR(32) NOT NULL,
+			FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE,
+			UNIQUE KEY uk_budget_tag (budget_id, tag),
+			INDEX idx_tag (tag)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create budget_tags table: %v", err)
+		panic(err)
+	}
+	
 	// 预算告警表
 	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS budget_alerts (
@@ -172,5 +334,428 @@ func initTables() {
 		panic(err)
 	}
 	
+	// 登录尝试记录表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS login_attempts (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT,
+			username VARCHAR(100) NOT NULL,
+			ip VARCHAR(45) NOT NULL,
+			user_agent VARCHAR(255),
+			success BOOLEAN NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_user_created (user_id, created_at),
+			INDEX idx_ip_created (ip, created_at),
+			INDEX idx_username_created (username, created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create login_attempts table: %v", err)
+		panic(err)
+	}
+	
+	// 账户锁定表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS account_lockouts (
+			user_id INT PRIMARY KEY,
+			failed_count INT NOT NULL DEFAULT 0,
+			last_failed_at DATETIME,
+			locked_until DATETIME,
+			unlock_token VARCHAR(64),
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_unlock_token (unlock_token)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create account_lockouts table: %v", err)
+		panic(err)
+	}
+	
+	// 保存的账单视图表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS saved_views (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			name VARCHAR(50) NOT NULL,
+			filter TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_view_name (user_id, name)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create saved_views table: %v", err)
+		panic(err)
+	}
+	
+	// 报告邮件订阅表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS digest_subscriptions (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			frequency VARCHAR(10) NOT NULL,
+			last_period_start DATE,
+			unsubscribe_token VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_digest_frequency (user_id, frequency),
+			UNIQUE KEY unique_unsubscribe_token (unsubscribe_token)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create digest_subscriptions table: %v", err)
+		panic(err)
+	}
+	
+	// 报告邮件发送记录表，退订后保留记录
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS digest_deliveries (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			subscription_id INT,
+			frequency VARCHAR(10) NOT NULL,
+			period_start DATE NOT NULL,
+			period_end DATE NOT NULL,
+			recipient VARCHAR(100) NOT NULL,
+			status VARCHAR(10) NOT NULL,
+			error VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (subscription_id) REFERENCES digest_subscriptions(id) ON DELETE SET NULL,
+			INDEX idx_user_created (user_id, created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create digest_deliveries table: %v", err)
+		panic(err)
+	}
+	
+	// 预算告警触发记录表，每个告警的每个阈值每月只记录一次
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS budget_alert_events (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			alert_id INT NOT NULL,
+			budget_id INT NOT NULL,
+			threshold INT NOT NULL,
+			month DATE NOT NULL,
+			used_amount DECIMAL(19,2) NOT NULL,
+			budget_amount DECIMAL(19,2) NOT NULL,
+			triggered_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (alert_id) REFERENCES budget_alerts(id) ON DELETE CASCADE,
+			FOREIGN KEY (budget_id) REFERENCES budgets(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_alert_threshold_month (alert_id, threshold, month),
+			INDEX idx_user_triggered (user_id, triggered_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create budget_alert_events table: %v", err)
+		panic(err)
+	}
+	
+	// 预算告警通知投递表，与触发记录在同一事务中写入，每个渠道一条，失败后由notify包重试
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS budget_alert_deliveries (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			event_id INT NOT NULL,
+			channel VARCHAR(20) NOT NULL,
+			status VARCHAR(10) NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			error VARCHAR(255) NOT NULL DEFAULT '',
+			next_attempt_at DATETIME,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			FOREIGN KEY (event_id) REFERENCES budget_alert_events(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_event_channel (event_id, channel),
+			INDEX idx_status_next (status, next_attempt_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create budget_alert_deliveries table: %v", err)
+		panic(err)
+	}
+	
+	// 站内通知表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS notifications (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			type VARCHAR(50) NOT NULL,
+			title VARCHAR(255) NOT NULL,
+			body TEXT NOT NULL,
+			payload JSON,
+			read_at DATETIME,
+			created_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_user_read (user_id, read_at),
+			INDEX idx_created (created_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create notifications table: %v", err)
+		panic(err)
+	}
+	
+	// Webhook表，events为逗号分隔的事件类型
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			url VARCHAR(500) NOT NULL,
+			events VARCHAR(500) NOT NULL,
+			secret VARCHAR(64) NOT NULL,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create webhooks table: %v", err)
+		panic(err)
+	}
+	
+	// Webhook投递记录表，payload按签名时的原文保存
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			webhook_id INT NOT NULL,
+			user_id INT NOT NULL,
+			event VARCHAR(50) NOT NULL,
+			payload MEDIUMTEXT NOT NULL,
+			status VARCHAR(10) NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			response_code INT NOT NULL DEFAULT 0,
+			response_body VARCHAR(1024) NOT NULL DEFAULT '',
+			error VARCHAR(255) NOT NULL DEFAULT '',
+			next_attempt_at DATETIME,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+			INDEX idx_status_next (status, next_attempt_at),
+			INDEX idx_webhook (webhook_id, id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create webhook_deliveries table: %v", err)
+		panic(err)
+	}
+	
+	// 预算模板表，在有效范围内的每个月自动生成预算
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS budget_templates (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			category_id INT,
+			amount DECIMAL(19,2) NOT NULL,
+			start_month DATE NOT NULL,
+			end_month DATE,
+			rollover_mode VARCHAR(10) NOT NULL DEFAULT 'none',
+			rollover_cap DECIMAL(19,2) NOT NULL DEFAULT 0,
+			last_generated_month DATE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
+			INDEX idx_user (user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create budget_templates table: %v", err)
+		panic(err)
+	}
+	
+	// 实时事件流的一次性票据表，EventSource无法设置请求头时代替JWT使用
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS stream_tickets (
+			ticket VARCHAR(64) PRIMARY KEY,
+			user_id INT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_expires (expires_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		logs.Error("Failed to create stream_tickets table: %v", err)
+		panic(err)
+	}
+	
 	logs.Info("Database tables created successfully")
+	
+	migrateTables()
+}
+
+// migrateTables 升级已有表结构，每条语句都必须可重复执行
+func migrateTables() {
+	migrations := []string{
+		// 金额精度由DECIMAL(10,2)扩大，避免年度汇总溢出
+		"ALTER TABLE bills MODIFY amount DECIMAL(19,2) NOT NULL",
+		"ALTER TABLE budgets MODIFY amount DECIMAL(19,2) NOT NULL",
+	}
+	
+	for _, migration := range migrations {
+		if _, err := DB.Exec(migration); err != nil {
+			logs.Error("Failed to migrate tables: %s: %v", migration, err)
+			panic(err)
+		}
+	}
+	
+	// 新增字段
+	columns := []struct {
+		Table, Column, Definition string
+	}{
+		{"users", "timezone", "VARCHAR(64) NOT NULL DEFAULT '' AFTER avatar"},
+		{"users", "language", "VARCHAR(10) NOT NULL DEFAULT '' AFTER timezone"},
+		{"bills", "merchant", "VARCHAR(100) NOT NULL DEFAULT '' AFTER description"},
+		{"budgets", "rollover_mode", "VARCHAR(10) NOT NULL DEFAULT 'none' AFTER month"},
+		{"budgets", "rollover_cap", "DECIMAL(19,2) NOT NULL DEFAULT 0 AFTER rollover_mode"},
+		{"budgets", "template_id", "INT AFTER rollover_cap"},
+		{"budgets", "period", "VARCHAR(10) NOT NULL DEFAULT 'monthly' AFTER month"},
+		{"budgets", "start_date", "DATE AFTER period"},
+		{"budgets", "end_date", "DATE AFTER start_date"},
+		{"budgets", "name", "VARCHAR(50) NOT NULL DEFAULT '' AFTER category_id"},
+		{"budgets", "scope_key", "CHAR(64) NOT NULL DEFAULT '' AFTER name"},
+	}
+	
+	for _, c := range columns {
+		if err := addColumnIfMissing(c.Table, c.Column, c.Definition); err != nil {
+			logs.Error("Failed to add column %s.%s: %v", c.Table, c.Column, err)
+			panic(err)
+		}
+	}
+	
+	// 已有的预算都是月度预算，补全周期起止日期
+	if _, err := DB.Exec("UPDATE budgets SET start_date = month, end_date = LAST_DAY(month) WHERE start_date IS NULL"); err != nil {
+		logs.Error("Failed to backfill budget periods: %v", err)
+		panic(err)
+	}
+	
+	// 新增索引
+	indexes := []struct {
+		Table, Name, Columns string
+	}{
+		// 账单按金额、创建时间排序的游标分页
+		{"bills", "idx_user_amount", "user_id, amount"},
+		{"bills", "idx_user_created", "user_id, created_at"},
+		// 按日期范围查询预算
+		{"budgets", "idx_user_dates", "user_id, start_date, end_date"},
+	}
+	
+	for _, i := range indexes {
+		if err := addIndexIfMissing(i.Table, i.Name, i.Columns); err != nil {
+			logs.Error("Failed to add index %s.%s: %v", i.Table, i.Name, err)
+			panic(err)
+		}
+	}
+	
+	// 预算唯一键由月份改为周期，同一月内可以有多个周预算；先添加新键，user_id外键始终有可用的索引
+	if err := addUniqueIndexIfMissing("budgets", "unique_budget_period", "user_id, category_id, period, start_date"); err != nil {
+		logs.Error("Failed to add unique index budgets.unique_budget_period: %v", err)
+		panic(err)
+	}
+	if err := dropIndexIfExists("budgets", "unique_budget"); err != nil {
+		logs.Error("Failed to drop index budgets.unique_budget: %v", err)
+		panic(err)
+	}
+	
+	// 账单搜索使用的全文索引，数据库不支持时回退为LIKE搜索
+	if SearchBackend == SearchFulltext {
+		fulltextIndexes := []struct {
+			Table, Name, Columns string
+		}{
+			{"bills", "ft_bills_text", "description, merchant"},
+			{"categories", "ft_categories_name", "name"},
+			{"bill_tags", "ft_bill_tags_tag", "tag"},
+		}
+		
+		for _, i := range fulltextIndexes {
+			if err := addFulltextIndexIfMissing(i.Table, i.Name, i.Columns); err != nil {
+				logs.Warn("Failed to add fulltext index %s.%s, falling back to LIKE search: %v", i.Table, i.Name, err)
+				SearchBackend = SearchLike
+				break
+			}
+		}
+	}
+	if SearchBackend == SearchFulltext {
+		loadNgramTokenSize()
+	}
+}
+
+// addColumnIfMissing 字段不存在时添加，MySQL不支持ADD COLUMN IF NOT EXISTS
+func addColumnIfMissing(table, column, definition string) error {
+	var exists bool
+	err := DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?)",
+		table, column,
+	).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// addIndexIfMissing 索引不存在时添加
+func addIndexIfMissing(table, name, columns string) error {
+	var exists bool
+	err := DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?)",
+		table, name,
+	).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD INDEX %s (%s)", table, name, columns))
+	return err
+}
+
+// addUniqueIndexIfMissing 唯一索引不存在时添加
+func addUniqueIndexIfMissing(table, name, columns string) error {
+	var exists bool
+	err := DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?)",
+		table, name,
+	).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD UNIQUE INDEX %s (%s)", table, name, columns))
+	return err
+}
+
+// dropIndexIfExists 索引存在时删除
+func dropIndexIfExists(table, name string) error {
+	var exists bool
+	err := DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?)",
+		table, name,
+	).Scan(&exists)
+	if err != nil || !exists {
+		return err
+	}
+	
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s DROP INDEX %s", table, name))
+	return err
+}
+
+// addFulltextIndexIfMissing 全文索引不存在时添加，使用ngram分词以支持中文
+func addFulltextIndexIfMissing(table, name, columns string) error {
+	var exists bool
+	err := DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?)",
+		table, name,
+	).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT INDEX %s (%s) WITH PARSER ngram", table, name, columns))
+	return err
 } 
\ No newline at end of file
@@ -0,0 +1,408 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 报告邮件的发送频率
+const (
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+)
+
+// 报告邮件的发送状态
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// digestTopCategories 报告邮件中列出的支出分类数
+const digestTopCategories = 5
+
+// DigestSubscription 定期报告邮件订阅
+type DigestSubscription struct {
+	ID               uint      `json:"id"`
+	UserID           uint      `json:"user_id"`
+	Frequency        string    `json:"frequency"`
+	LastPeriodStart  string    `json:"last_period_start,omitempty"` // 最近一次已发送报告的周期开始日期
+	UnsubscribeToken string    `json:"-"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// DigestRequest 订阅请求参数
+type DigestRequest struct {
+	Frequency string `json:"frequency" valid:"Required;Match(/^(weekly|monthly)$/)"`
+}
+
+// DigestDelivery 报告邮件发送记录
+type DigestDelivery struct {
+	ID          uint      `json:"id"`
+	Frequency   string    `json:"frequency"`
+	PeriodStart string    `json:"period_start"`
+	PeriodEnd   string    `json:"period_end"`
+	Recipient   string    `json:"recipient"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DueDigest 待发送的报告邮件，周期为用户时区下最近一个完整的周或月
+type DueDigest struct {
+	Subscription *DigestSubscription
+	User         *User
+	StartDate    string
+	EndDate      string
+}
+
+// Digest 报告邮件内容
+type Digest struct {
+	User       *User
+	Frequency  string
+	Report     *Report
+	Categories []*ReportCategory // 支出最多的分类
+	Budgets    []*Budget         // 周期结束所在月份的预算
+}
+
+// DigestPeriod 以today为当天时最近一个已结束的完整周期，周从周一开始
+func DigestPeriod(frequency string, today time.Time) (string, string) {
+	name := RangeLastMonth
+	if frequency == DigestWeekly {
+		name = RangeLastWeek
+	}
+	start, end, _ := ResolveDateRange(name, today)
+	return start, end
+}
+
+// GetDigestSubscriptions 获取用户的报告邮件订阅
+func GetDigestSubscriptions(ctx context.Context, userID uint) ([]*DigestSubscription, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT id, user_id, frequency, DATE_FORMAT(last_period_start, '%Y-%m-%d'), unsubscribe_token, created_at
+		FROM digest_subscriptions
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		logs.Error("Error querying digest subscriptions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*DigestSubscription, 0)
+	for rows.Next() {
+		subscription, err := scanDigestSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating digest subscription rows: %v", err)
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// scanDigestSubscription 读取一行订阅数据，之后可以跟随额外的列
+func scanDigestSubscription(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*DigestSubscription, error) {
+	subscription := &DigestSubscription{}
+	var lastPeriodStart sql.NullString
+	dest := append([]interface{}{
+		&subscription.ID, &subscription.UserID, &subscription.Frequency,
+		&lastPeriodStart, &subscription.UnsubscribeToken, &subscription.CreatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if err != sql.ErrNoRows {
+			logs.Error("Error scanning digest subscription row: %v", err)
+		}
+		return nil, err
+	}
+	subscription.LastPeriodStart = lastPeriodStart.String
+	return subscription, nil
+}
+
+// CreateDigestSubscription 订阅报告邮件，每种频率只能订阅一次
+func CreateDigestSubscription(ctx context.Context, userID uint, req *DigestRequest) (*DigestSubscription, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dbExec(ctx,
+		"INSERT INTO digest_subscriptions (user_id, frequency, unsubscribe_token) VALUES (?, ?, ?)",
+		userID, req.Frequency, token,
+	)
+	if isDuplicateEntry(err) {
+		return nil, ErrDigestExists
+	}
+	if err != nil {
+		logs.Error("Error creating digest subscription: %v", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logs.Error("Error getting digest subscription ID: %v", err)
+		return nil, err
+	}
+
+	subscription, err := scanDigestSubscription(dbQueryRow(ctx, `
+		SELECT id, user_id, frequency, DATE_FORMAT(last_period_start, '%Y-%m-%d'), unsubscribe_token, created_at
+		FROM digest_subscriptions
+		WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDigestNotFound
+	}
+	return subscription, err
+}
+
+// DeleteDigestSubscription 取消订阅
+func DeleteDigestSubscription(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx, "DELETE FROM digest_subscriptions WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		logs.Error("Error deleting digest subscription: %v", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrDigestNotFound
+	}
+
+	return nil
+}
+
+// UnsubscribeDigest 通过邮件中的退订令牌取消订阅，无需登录
+func UnsubscribeDigest(ctx context.Context, token string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if token == "" {
+		return ErrUnsubscribeTokenInvalid
+	}
+
+	result, err := dbExec(ctx, "DELETE FROM digest_subscriptions WHERE unsubscribe_token = ?", token)
+	if err != nil {
+		logs.Error("Error unsubscribing digest: %v", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrUnsubscribeTokenInvalid
+	}
+
+	return nil
+}
+
+// GetDueDigests 获取所有待发送的报告邮件
+func GetDueDigests(ctx context.Context) ([]*DueDigest, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT s.id, s.user_id, s.frequency, DATE_FORMAT(s.last_period_start, '%Y-%m-%d'), s.unsubscribe_token, s.created_at,
+		       u.username, u.email, u.timezone, u.language
+		FROM digest_subscriptions s
+		JOIN users u ON s.user_id = u.id
+		ORDER BY s.id
+	`)
+	if err != nil {
+		logs.Error("Error querying due digests: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := make([]*DueDigest, 0)
+	for rows.Next() {
+		user := &User{}
+		subscription, err := scanDigestSubscription(rows, &user.Username, &user.Email, &user.Timezone, &user.Language)
+		if err != nil {
+			return nil, err
+		}
+		user.ID = subscription.UserID
+
+		start, end := DigestPeriod(subscription.Frequency, Today(user.Location()))
+		if subscription.LastPeriodStart >= start {
+			continue
+		}
+		due = append(due, &DueDigest{
+			Subscription: subscription,
+			User:         user,
+			StartDate:    start,
+			EndDate:      end,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating due digest rows: %v", err)
+		return nil, err
+	}
+
+	return due, nil
+}
+
+// ClaimDigest 将周期标记为已发送，返回false表示已被其他实例发送
+//
+// 发送前先占用周期，多个实例同时运行时每个周期只会发送一次；发送失败后调用ReleaseDigest以便重试。
+func ClaimDigest(ctx context.Context, d *DueDigest) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx, `
+		UPDATE digest_subscriptions SET last_period_start = ?
+		WHERE id = ? AND (last_period_start IS NULL OR last_period_start < ?)
+	`, d.StartDate, d.Subscription.ID, d.StartDate)
+	if err != nil {
+		logs.Error("Error claiming digest: %v", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// ReleaseDigest 发送失败时恢复订阅的上次发送周期
+func ReleaseDigest(ctx context.Context, d *DueDigest) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var previous interface{}
+	if d.Subscription.LastPeriodStart != "" {
+		previous = d.Subscription.LastPeriodStart
+	}
+	_, err := dbExec(ctx,
+		"UPDATE digest_subscriptions SET last_period_start = ? WHERE id = ? AND last_period_start = ?",
+		previous, d.Subscription.ID, d.StartDate,
+	)
+	if err != nil {
+		logs.Error("Error releasing digest: %v", err)
+	}
+	return err
+}
+
+// BuildDigest 汇总报告邮件内容：周期收支及对比、支出最多的分类和预算执行情况
+func BuildDigest(ctx context.Context, d *DueDigest) (*Digest, error) {
+	period := PeriodMonth
+	if d.Subscription.Frequency == DigestWeekly {
+		period = PeriodWeek
+	}
+	report, err := GetReport(ctx, d.User.ID, &ReportParams{
+		Period: period,
+		Date:   d.StartDate,
+		Bucket: BucketDay,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]*ReportCategory, 0, digestTopCategories)
+	for _, category := range report.Categories {
+		if category.Type == "expense" && len(categories) < digestTopCategories {
+			categories = append(categories, category)
+		}
+	}
+
+	budgets, err := GetBudgets(ctx, d.User.ID, d.EndDate[:7])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Digest{
+		User:       d.User,
+		Frequency:  d.Subscription.Frequency,
+		Report:     report,
+		Categories: categories,
+		Budgets:    FilterBudgets(budgets, BudgetMonthly),
+	}, nil
+}
+
+// RecordDigestDelivery 记录报告邮件的发送结果，sendErr为nil表示发送成功
+func RecordDigestDelivery(ctx context.Context, d *DueDigest, sendErr error) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	status, message := DeliverySent, ""
+	if sendErr != nil {
+		status, message = DeliveryFailed, sendErr.Error()
+		if len(message) > 255 {
+			message = message[:255]
+		}
+	}
+
+	_, err := dbExec(ctx, `
+		INSERT INTO digest_deliveries (user_id, subscription_id, frequency, period_start, period_end, recipient, status, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, d.User.ID, d.Subscription.ID, d.Subscription.Frequency, d.StartDate, d.EndDate, d.User.Email, status, message)
+	if err != nil {
+		logs.Error("Error recording digest delivery: %v", err)
+	}
+	return err
+}
+
+// GetDigestDeliveries 获取用户最近的报告邮件发送记录
+func GetDigestDeliveries(ctx context.Context, userID uint, limit int) ([]*DigestDelivery, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT id, frequency, DATE_FORMAT(period_start, '%Y-%m-%d'), DATE_FORMAT(period_end, '%Y-%m-%d'),
+		       recipient, status, error, created_at
+		FROM digest_deliveries
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		logs.Error("Error querying digest deliveries: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*DigestDelivery, 0)
+	for rows.Next() {
+		delivery := &DigestDelivery{}
+		err := rows.Scan(&delivery.ID, &delivery.Frequency, &delivery.PeriodStart, &delivery.PeriodEnd,
+			&delivery.Recipient, &delivery.Status, &delivery.Error, &delivery.CreatedAt)
+		if err != nil {
+			logs.Error("Error scanning digest delivery row: %v", err)
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating digest delivery rows: %v", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+
+	"blog/i18n"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Kind 业务错误的类别，由控制器统一映射为HTTP状态码
+type Kind int
+
+const (
+	KindInternal     Kind = iota // 内部错误，消息不对外暴露
+	KindNotFound                 // 资源不存在
+	KindConflict                 // 与已有数据冲突
+	KindForbidden                // 无权访问
+	KindValidation               // 请求内容不符合业务规则
+	KindUnauthorized             // 身份认证失败
+	KindRateLimited              // 请求过于频繁
+)
+
+// LocalizedError 带稳定错误码、可按语言渲染消息的错误
+type LocalizedError interface {
+	error
+	ErrorKind() Kind
+	ErrorCode() string
+	Localize(lang string) string
+}
+
+// Error 业务错误，Code为稳定的机器可读错误码
+type Error struct {
+	Kind Kind
+	Code string
+	Args []interface{}
+}
+
+// NewError 创建业务错误
+func NewError(kind Kind, code string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Code: code, Args: args}
+}
+
+// NotFound 资源不存在
+func NotFound(code string, args ...interface{}) *Error {
+	return NewError(KindNotFound, code, args...)
+}
+
+// Conflict 与已有数据冲突
+func Conflict(code string, args ...interface{}) *Error {
+	return NewError(KindConflict, code, args...)
+}
+
+// Forbidden 无权访问
+func Forbidden(code string, args ...interface{}) *Error {
+	return NewError(KindForbidden, code, args...)
+}
+
+// Invalid 请求内容不符合业务规则
+func Invalid(code string, args ...interface{}) *Error {
+	return NewError(KindValidation, code, args...)
+}
+
+// Error 使用默认语言渲染消息
+func (e *Error) Error() string {
+	return e.Localize(i18n.Default)
+}
+
+// ErrorKind 错误类别
+func (e *Error) ErrorKind() Kind {
+	return e.Kind
+}
+
+// ErrorCode 错误码
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+// Localize 按指定语言渲染消息
+func (e *Error) Localize(lang string) string {
+	return i18n.T(lang, e.Code, e.Args...)
+}
+
+// Is 错误码相同即视为同一错误，便于errors.Is比较带参数的错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// isDuplicateEntry 是否为唯一键冲突，并发创建时检查语句可能漏掉重复数据
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// 业务错误
+var (
+	ErrUserNotFound        = NotFound("user_not_found")
+	ErrUsernameTaken       = Conflict("username_taken")
+	ErrEmailTaken          = Conflict("email_taken")
+	ErrWrongPassword       = Invalid("wrong_password")
+	ErrEmailNotFound       = NotFound("email_not_found")
+	ErrUnlockTokenInvalid  = Invalid("unlock_token_invalid")
+	ErrStreamTicketInvalid = NewError(KindUnauthorized, "invalid_stream_ticket")
+
+	ErrCategoryNotFound  = NotFound("category_not_found")
+	ErrCategoryNotOwned  = Invalid("category_not_owned")
+	ErrCategoryExists    = Conflict("category_exists")
+	ErrCategoryInUse     = Conflict("category_in_use")
+	ErrCategoryHasBudget = Conflict("category_has_budget")
+
+	ErrBillNotFound     = NotFound("bill_not_found")
+	ErrBillTypeMismatch = Invalid("bill_type_mismatch")
+	ErrInvalidDate      = Invalid("invalid_date")
+	ErrInvalidMonth     = Invalid("invalid_month")
+
+	ErrBudgetNotFound       = NotFound("budget_not_found")
+	ErrBudgetExpenseOnly    = Invalid("budget_expense_only")
+	ErrBudgetCategoryExists = Conflict("budget_category_exists")
+	ErrBudgetTotalExists    = Conflict("budget_total_exists")
+	ErrBudgetPeriodInvalid  = Invalid("budget_period_invalid")
+	ErrBudgetScopeOverlap   = Conflict("budget_scope_overlap")
+	ErrThresholdOutOfRange  = Invalid("threshold_out_of_range")
+	ErrAlertNotFound        = NotFound("alert_not_found")
+	ErrAlertEventNotFound   = NotFound("alert_event_not_found")
+
+	ErrBudgetTemplateNotFound = NotFound("budget_template_not_found")
+	ErrBudgetTemplateExists   = Conflict("budget_template_exists")
+
+	ErrViewNotFound = NotFound("view_not_found")
+	ErrViewExists   = Conflict("view_exists")
+
+	ErrDigestNotFound          = NotFound("digest_not_found")
+	ErrDigestExists            = Conflict("digest_exists")
+	ErrUnsubscribeTokenInvalid = Invalid("unsubscribe_token_invalid")
+
+	ErrNotificationNotFound = NotFound("notification_not_found")
+
+	ErrWebhookNotFound         = NotFound("webhook_not_found")
+	ErrWebhookURLInvalid       = Invalid("webhook_url_invalid")
+	ErrWebhookDeliveryNotFound = NotFound("webhook_delivery_not_found")
+)
@@ -0,0 +1,402 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"math"
+	"time"
+
+	"blog/i18n"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials 登录失败的统一错误，不区分用户是否存在
+var ErrInvalidCredentials = NewError(KindUnauthorized, "invalid_credentials")
+
+// LoginThrottledError 登录尝试过于频繁或账户被锁定
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return e.Localize(i18n.Default)
+}
+
+// ErrorKind 错误类别
+func (e *LoginThrottledError) ErrorKind() Kind {
+	return KindRateLimited
+}
+
+// ErrorCode 错误码
+func (e *LoginThrottledError) ErrorCode() string {
+	return "login_throttled"
+}
+
+// Localize 按指定语言渲染消息
+func (e *LoginThrottledError) Localize(lang string) string {
+	return i18n.T(lang, e.ErrorCode(), int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// AccountLockedError 本次失败触发了账户锁定，需要向用户发送解锁邮件
+type AccountLockedError struct {
+	LoginThrottledError
+	User        *User
+	UnlockToken string
+}
+
+// LoginAttempt 登录尝试记录
+type LoginAttempt struct {
+	ID        uint      `json:"id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginMeta 登录请求的来源信息
+type LoginMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// 登录保护策略，可通过配置覆盖
+var (
+	LoginFreeAttempts   = 3                // 不触发退避的连续失败次数
+	LoginMaxFailures    = 10               // 触发账户锁定的连续失败次数
+	LoginLockout        = 30 * time.Minute // 账户锁定时长
+	LoginMaxBackoff     = 5 * time.Minute  // 单次退避的最长等待
+	LoginIPWindow       = 15 * time.Minute // 统计IP失败次数的时间窗口
+	LoginIPFreeAttempts = 20               // 窗口内IP不触发退避的失败次数
+)
+
+// dummyPasswordHash 用户不存在时也执行一次bcrypt比较，避免通过响应时间判断账户是否存在
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("finwise-dummy-password"), bcrypt.DefaultCost)
+
+// loadLoginPolicy 从配置读取登录保护策略
+func loadLoginPolicy() {
+	if v, err := web.AppConfig.Int("loginmaxfailures"); err == nil && v > 0 {
+		LoginMaxFailures = v
+	}
+	if v, err := web.AppConfig.Int("loginipfreeattempts"); err == nil && v > 0 {
+		LoginIPFreeAttempts = v
+	}
+	if v, _ := web.AppConfig.String("loginlockout"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			LoginLockout = d
+		} else {
+			logs.Error("Invalid loginlockout %q: %v", v, err)
+		}
+	}
+}
+
+// LoginBackoff 连续失败failures次后的指数退避时长，前free次失败不退避
+func LoginBackoff(failures, free int) time.Duration {
+	if failures < free {
+		return 0
+	}
+	exp := failures - free
+	if exp > 16 {
+		exp = 16
+	}
+	d := time.Second << uint(exp)
+	if d > LoginMaxBackoff {
+		d = LoginMaxBackoff
+	}
+	return d
+}
+
+// checkIPThrottle 检查来源IP在时间窗口内的失败次数
+func checkIPThrottle(ctx context.Context, ip string, now time.Time) (time.Duration, error) {
+	var failures int
+	var lastFailure sql.NullTime
+	err := dbQueryRow(ctx,
+		"SELECT COUNT(*), MAX(created_at) FROM login_attempts WHERE ip = ? AND success = 0 AND created_at > ?",
+		ip, now.Add(-LoginIPWindow),
+	).Scan(&failures, &lastFailure)
+	if err != nil {
+		logs.Error("Error counting ip login failures: %v", err)
+		return 0, err
+	}
+
+	if !lastFailure.Valid {
+		return 0, nil
+	}
+	return lastFailure.Time.Add(LoginBackoff(failures, LoginIPFreeAttempts)).Sub(now), nil
+}
+
+// checkAccountThrottle 检查账户的锁定与退避状态，账户不存在时按登录名统计，保证响应一致
+func checkAccountThrottle(ctx context.Context, userID uint, identifier string, now time.Time) (int, time.Duration, error) {
+	var failures int
+	var lastFailure, lockedUntil sql.NullTime
+	var err error
+
+	if userID > 0 {
+		err = dbQueryRow(ctx,
+			"SELECT failed_count, last_failed_at, locked_until FROM account_lockouts WHERE user_id = ?",
+			userID,
+		).Scan(&failures, &lastFailure, &lockedUntil)
+		if err == sql.ErrNoRows {
+			return 0, 0, nil
+		}
+	} else {
+		err = dbQueryRow(ctx,
+			"SELECT COUNT(*), MAX(created_at) FROM login_attempts WHERE username = ? AND success = 0 AND created_at > ?",
+			identifier, now.Add(-LoginLockout),
+		).Scan(&failures, &lastFailure)
+	}
+	if err != nil {
+		logs.Error("Error checking account lockout: %v", err)
+		return 0, 0, err
+	}
+
+	var last, locked *time.Time
+	if lastFailure.Valid {
+		last = &lastFailure.Time
+	}
+	if lockedUntil.Valid {
+		locked = &lockedUntil.Time
+	}
+	failures, wait := AccountThrottle(userID > 0, failures, last, locked, now)
+	return failures, wait, nil
+}
+
+// AccountThrottle 根据失败记录计算当前有效的连续失败次数和需要等待的时长
+//
+// known为true时记录来自account_lockouts：锁定未到期时等待到解锁，锁定到期或最近一次失败已超出统计窗口时重新计数，
+// 与不存在的账户按时间窗口统计保持一致。known为false时记录来自窗口内的login_attempts，达到上限后与锁定等待相同的时长。
+func AccountThrottle(known bool, failures int, lastFailure, lockedUntil *time.Time, now time.Time) (int, time.Duration) {
+	if lockedUntil != nil && lockedUntil.After(now) {
+		return failures, lockedUntil.Sub(now)
+	}
+	if known && (lockedUntil != nil || lastFailure == nil || !lastFailure.After(now.Add(-LoginLockout))) {
+		return 0, 0
+	}
+	if lastFailure == nil {
+		return failures, 0
+	}
+	if !known && failures >= LoginMaxFailures {
+		return failures, lastFailure.Add(LoginLockout).Sub(now)
+	}
+	return failures, lastFailure.Add(LoginBackoff(failures, LoginFreeAttempts)).Sub(now)
+}
+
+// recordLoginAttempt 记录一次登录尝试
+func recordLoginAttempt(ctx context.Context, userID uint, identifier string, meta *LoginMeta, success bool) {
+	var uid interface{}
+	if userID > 0 {
+		uid = userID
+	}
+	userAgent := meta.UserAgent
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	_, err := dbExec(ctx,
+		"INSERT INTO login_attempts (user_id, username, ip, user_agent, success) VALUES (?, ?, ?, ?, ?)",
+		uid, identifier, meta.IP, userAgent, success,
+	)
+	if err != nil {
+		logs.Error("Error recording login attempt: %v", err)
+	}
+}
+
+// AuthenticateUser 验证用户凭据，带有按账户与按IP的失败退避和账户锁定
+func AuthenticateUser(ctx context.Context, login *LoginRequest, meta *LoginMeta) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+
+	// 检查IP退避
+	wait, err := checkIPThrottle(ctx, meta.IP, now)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	user := &User{}
+	var hashedPassword string
+
+	// 支持用户名或邮箱登录
+	err = dbQueryRow(ctx,
+		"SELECT id, username, email, password, phone, avatar, timezone, language, created_at, updated_at FROM users WHERE username = ? OR email = ?",
+		login.Username, login.Username,
+	).Scan(&user.ID, &user.Username, &user.Email, &hashedPassword, &user.Phone, &user.Avatar, &user.Timezone, &user.Language, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil && err != sql.ErrNoRows {
+		logs.Error("Error querying user for authentication: %v", err)
+		return nil, err
+	}
+	if err == sql.ErrNoRows {
+		user = nil
+		hashedPassword = string(dummyPasswordHash)
+	}
+
+	var userID uint
+	if user != nil {
+		userID = user.ID
+	}
+
+	// 检查账户锁定与退避
+	failures, wait, err := checkAccountThrottle(ctx, userID, login.Username, now)
+	if err != nil {
+		return nil, err
+	}
+	if wait > 0 {
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	// 验证密码
+	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(login.Password))
+	if err == nil && user != nil {
+		recordLoginAttempt(ctx, userID, login.Username, meta, true)
+		_, err = dbExec(ctx, "DELETE FROM account_lockouts WHERE user_id = ?", userID)
+		if err != nil {
+			logs.Error("Error resetting account lockout: %v", err)
+		}
+		return user, nil
+	}
+
+	recordLoginAttempt(ctx, userID, login.Username, meta, false)
+	if user == nil {
+		if failures+1 >= LoginMaxFailures {
+			return nil, &LoginThrottledError{RetryAfter: LoginLockout}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// 在数据库中原子累计失败次数，并发的失败请求不会互相覆盖。
+	// 与AccountThrottle的规则一致：锁定到期或上次失败超出统计窗口时从1重新计数；
+	// ON DUPLICATE KEY UPDATE按顺序赋值，前面的表达式读取的是更新前的locked_until和last_failed_at
+	_, err = dbExec(ctx, `
+		INSERT INTO account_lockouts (user_id, failed_count, last_failed_at) VALUES (?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			failed_count = IF((locked_until IS NOT NULL AND locked_until <= ?) OR last_failed_at IS NULL OR last_failed_at <= ?, 1, failed_count + 1),
+			unlock_token = IF(locked_until IS NOT NULL AND locked_until <= ?, NULL, unlock_token),
+			locked_until = IF(locked_until IS NOT NULL AND locked_until <= ?, NULL, locked_until),
+			last_failed_at = VALUES(last_failed_at)
+	`, userID, now, now, now.Add(-LoginLockout), now, now)
+	if err != nil {
+		logs.Error("Error updating account failures: %v", err)
+		return nil, err
+	}
+
+	// 是否锁定以数据库中累计后的次数为准
+	err = dbQueryRow(ctx, "SELECT failed_count FROM account_lockouts WHERE user_id = ?", userID).Scan(&failures)
+	if err != nil {
+		logs.Error("Error reading account failures: %v", err)
+		return nil, err
+	}
+	if failures < LoginMaxFailures {
+		return nil, ErrInvalidCredentials
+	}
+
+	// 达到上限时锁定并生成解锁令牌，并发请求中只有一个会设置锁定并发送解锁邮件
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	lockedUntil := now.Add(LoginLockout)
+	result, err := dbExec(ctx, `
+		UPDATE account_lockouts SET locked_until = ?, unlock_token = ?
+		WHERE user_id = ? AND failed_count >= ? AND (locked_until IS NULL OR locked_until <= ?)
+	`, lockedUntil, token, userID, LoginMaxFailures, now)
+	if err != nil {
+		logs.Error("Error locking account: %v", err)
+		return nil, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting locked rows: %v", err)
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, &LoginThrottledError{RetryAfter: LoginLockout}
+	}
+
+	return nil, &AccountLockedError{
+		LoginThrottledError: LoginThrottledError{RetryAfter: LoginLockout},
+		User:                user,
+		UnlockToken:         token,
+	}
+}
+
+// UnlockAccount 通过邮件中的解锁令牌解除账户锁定
+func UnlockAccount(ctx context.Context, token string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if token == "" {
+		return ErrUnlockTokenInvalid
+	}
+
+	result, err := dbExec(ctx, "DELETE FROM account_lockouts WHERE unlock_token = ?", token)
+	if err != nil {
+		logs.Error("Error unlocking account: %v", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting unlocked rows: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrUnlockTokenInvalid
+	}
+
+	return nil
+}
+
+// GetLoginHistory 获取用户最近的登录记录
+func GetLoginHistory(ctx context.Context, userID uint, limit int) ([]*LoginAttempt, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx,
+		"SELECT id, ip, user_agent, success, created_at FROM login_attempts WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT ?",
+		userID, limit,
+	)
+	if err != nil {
+		logs.Error("Error querying login history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := make([]*LoginAttempt, 0)
+	for rows.Next() {
+		attempt := &LoginAttempt{}
+		var userAgent sql.NullString
+		err := rows.Scan(&attempt.ID, &attempt.IP, &userAgent, &attempt.Success, &attempt.CreatedAt)
+		if err != nil {
+			logs.Error("Error scanning login attempt row: %v", err)
+			return nil, err
+		}
+		attempt.UserAgent = userAgent.String
+		attempts = append(attempts, attempt)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating login attempt rows: %v", err)
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// generateToken 生成随机令牌
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		logs.Error("Error generating token: %v", err)
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，以分为单位的整数保存，避免浮点运算误差
+//
+// 舍入规则：超出两位小数的部分按四舍五入（远离零方向）处理；
+// 请求中的金额不做舍入，超过两位小数直接视为格式错误。
+type Money int64
+
+// MoneyScale 金额的小数位数
+const MoneyScale = 2
+
+// moneyUnit 一元对应的最小单位数
+const moneyUnit = 100
+
+// MaxAmount 单笔金额上限（一万亿元）
+const MaxAmount Money = 1000000000000 * moneyUnit
+
+// MoneyJSONString 为true时金额在JSON中输出为字符串，避免前端按双精度解析丢失精度
+var MoneyJSONString = false
+
+// ErrMoneyPrecision 金额超出两位小数
+var ErrMoneyPrecision = Invalid("money_invalid")
+
+// ParseMoney 解析十进制金额字符串，超出两位小数时四舍五入
+func ParseMoney(s string) (Money, error) {
+	return parseMoney(s, false)
+}
+
+// parseMoney 精确解析十进制字符串，strict为true时拒绝超出两位的小数
+func parseMoney(s string, strict bool) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.Contains(s, "/") {
+		return 0, fmt.Errorf("invalid money %q", s)
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid money %q", s)
+	}
+	r.Mul(r, big.NewRat(moneyUnit, 1))
+
+	if !r.IsInt() && strict {
+		return 0, ErrMoneyPrecision
+	}
+
+	minor := roundRat(r)
+	if !minor.IsInt64() {
+		return 0, fmt.Errorf("money %q out of range", s)
+	}
+	return Money(minor.Int64()), nil
+}
+
+// roundRat 四舍五入到整数，0.5远离零
+func roundRat(r *big.Rat) *big.Int {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// |rem| * 2 >= den 时向远离零的方向进位
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo
+}
+
+// MoneyFromFloat 由浮点数转换为金额，四舍五入到分
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * moneyUnit))
+}
+
+// String 以两位小数的十进制字符串表示
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/moneyUnit, v%moneyUnit)
+}
+
+// Float64 转换为浮点数，仅用于展示或比例计算
+func (m Money) Float64() float64 {
+	return float64(m) / moneyUnit
+}
+
+// Percent 计算m占total的百分比，保留两位小数
+func (m Money) Percent(total Money) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(m)/float64(total)*100*100) / 100
+}
+
+// Adjust 按百分比调整金额，percent为10时增加10%，为-10时减少10%，结果四舍五入到分
+func (m Money) Adjust(percent float64) Money {
+	return Money(math.Round(float64(m) * (100 + percent) / 100))
+}
+
+// MarshalJSON 输出为两位小数的数字，开启MoneyJSONString时输出为字符串
+func (m Money) MarshalJSON() ([]byte, error) {
+	if MoneyJSONString {
+		return []byte(strconv.Quote(m.String())), nil
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 同时接受数字和字符串形式的金额，直接解析原始文本，不经过浮点数
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	v, err := parseMoney(s, true)
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: "money " + string(data), Type: reflect.TypeOf(Money(0))}
+	}
+	*m = v
+	return nil
+}
+
+// Scan 从DECIMAL列读取金额，MySQL驱动返回的是十进制文本
+func (m *Money) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		*m, err = ParseMoney(string(v))
+	case string:
+		*m, err = ParseMoney(v)
+	case int64:
+		*m = Money(v * moneyUnit)
+	case float64:
+		*m = MoneyFromFloat(v)
+	default:
+		err = fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return err
+}
+
+// Value 以十进制文本写入数据库，由MySQL精确转换为DECIMAL
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
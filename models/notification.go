@@ -0,0 +1,284 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// notificationCursorSort 站内通知游标的排序标识，通知固定按ID倒序
+const notificationCursorSort = "-id"
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint            `json:"id"`
+	UserID    uint            `json:"user_id"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Read      bool            `json:"read"`
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NotificationQuery 站内通知查询参数
+type NotificationQuery struct {
+	Unread   bool
+	Type     string
+	Cursor   string
+	PageSize int
+}
+
+// NotificationPage 一页站内通知及未读数
+type NotificationPage struct {
+	Notifications []*Notification
+	HasMore       bool
+	NextCursor    string
+	UnreadCount   int
+}
+
+// CreateNotification 给用户发送一条站内通知，payload为附带的结构化数据，可以为nil
+func CreateNotification(ctx context.Context, userID uint, notificationType, title, body string, payload interface{}) (*Notification, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			logs.Error("Error encoding notification payload: %v", err)
+			return nil, err
+		}
+	}
+
+	notification := &Notification{
+		UserID:    userID,
+		Type:      notificationType,
+		Title:     title,
+		Body:      body,
+		Payload:   data,
+		CreatedAt: time.Now(),
+	}
+	result, err := dbExec(ctx,
+		"INSERT INTO notifications (user_id, type, title, body, payload, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		userID, notificationType, title, body, nullableString(string(data)), notification.CreatedAt,
+	)
+	if err != nil {
+		logs.Error("Error creating notification: %v", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logs.Error("Error getting notification ID: %v", err)
+		return nil, err
+	}
+	notification.ID = uint(id)
+
+	return notification, nil
+}
+
+// nullableString 空字符串写入为NULL
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetNotifications 按时间倒序获取站内通知，使用游标分页
+func GetNotifications(ctx context.Context, userID uint, q *NotificationQuery) (*NotificationPage, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	where := []string{"user_id = ?"}
+	args := []interface{}{userID}
+	if q.Unread {
+		where = append(where, "read_at IS NULL")
+	}
+	if q.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, q.Type)
+	}
+	if q.Cursor != "" {
+		cursor, err := DecodeCursor(q.Cursor, notificationCursorSort)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "id < ?")
+		args = append(args, cursor.ID)
+	}
+	args = append(args, q.PageSize+1)
+
+	rows, err := dbQuery(ctx, `
+		SELECT id, user_id, type, title, body, payload, read_at, created_at
+		FROM notifications
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		logs.Error("Error querying notifications: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &NotificationPage{Notifications: make([]*Notification, 0)}
+	for rows.Next() {
+		notification := &Notification{}
+		var payload sql.NullString
+		var readAt sql.NullTime
+		err := rows.Scan(&notification.ID, &notification.UserID, &notification.Type, &notification.Title,
+			&notification.Body, &payload, &readAt, &notification.CreatedAt)
+		if err != nil {
+			logs.Error("Error scanning notification row: %v", err)
+			return nil, err
+		}
+		if payload.Valid {
+			notification.Payload = json.RawMessage(payload.String)
+		}
+		if readAt.Valid {
+			notification.Read = true
+			notification.ReadAt = &readAt.Time
+		}
+		page.Notifications = append(page.Notifications, notification)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating notification rows: %v", err)
+		return nil, err
+	}
+
+	if len(page.Notifications) > q.PageSize {
+		page.Notifications = page.Notifications[:q.PageSize]
+		last := page.Notifications[q.PageSize-1]
+		page.HasMore = true
+		page.NextCursor = (&Cursor{Sort: notificationCursorSort, ID: last.ID}).Encode()
+	}
+
+	page.UnreadCount, err = CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// CountUnreadNotifications 未读通知数
+func CountUnreadNotifications(ctx context.Context, userID uint) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := dbQueryRow(ctx,
+		"SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL",
+		userID,
+	).Scan(&count)
+	if err != nil {
+		logs.Error("Error counting unread notifications: %v", err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkNotificationRead 标记通知为已读，已读的通知保持原已读时间
+func MarkNotificationRead(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := dbQueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND user_id = ?)",
+		id, userID,
+	).Scan(&exists)
+	if err != nil {
+		logs.Error("Error checking notification existence: %v", err)
+		return err
+	}
+	if !exists {
+		return ErrNotificationNotFound
+	}
+
+	_, err = dbExec(ctx,
+		"UPDATE notifications SET read_at = ? WHERE id = ? AND user_id = ? AND read_at IS NULL",
+		time.Now(), id, userID,
+	)
+	if err != nil {
+		logs.Error("Error marking notification read: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead 标记所有未读通知为已读，返回标记的数量
+func MarkAllNotificationsRead(ctx context.Context, userID uint) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx,
+		"UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL",
+		time.Now(), userID,
+	)
+	if err != nil {
+		logs.Error("Error marking all notifications read: %v", err)
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return 0, err
+	}
+
+	return affected, nil
+}
+
+// DeleteNotification 删除通知
+func DeleteNotification(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx, "DELETE FROM notifications WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		logs.Error("Error deleting notification: %v", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// PurgeNotifications 删除before之前创建的通知，返回删除的数量
+func PurgeNotifications(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx, "DELETE FROM notifications WHERE created_at < ?", before)
+	if err != nil {
+		logs.Error("Error purging notifications: %v", err)
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return 0, err
+	}
+
+	return affected, nil
+}
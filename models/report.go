@@ -0,0 +1,446 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/core/validation"
+)
+
+// 报表周期
+const (
+	PeriodWeek    = "week"
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+	PeriodYear    = "year"
+)
+
+// 时间序列的分桶粒度
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// 报表范围限制
+const (
+	maxReportDays    = 3660 // 自定义范围最长约十年
+	maxReportBuckets = 400  // 单个报表的时间序列最多分桶数
+)
+
+// ReportParams 报表查询参数
+//
+// 指定Period时报表覆盖Date所在的整个周期（周从周一开始），否则使用StartDate和EndDate的自定义范围；
+// 两者都未指定时默认为用户时区下的本月。
+type ReportParams struct {
+	Period    string `json:"period" valid:"Match(/^(week|month|quarter|year)?$/)"`
+	Date      string `json:"date" valid:"Date"` // 周期内的任意一天，默认为今天
+	StartDate string `json:"start_date" valid:"Date"`
+	EndDate   string `json:"end_date" valid:"Date"`
+	Bucket    string `json:"bucket" valid:"Match(/^(day|week|month)?$/)"` // 默认按范围长度选择
+}
+
+// ReportSummary 收支合计
+type ReportSummary struct {
+	Income  Money `json:"income"`
+	Expense Money `json:"expense"`
+	Balance Money `json:"balance"`
+	Count   int   `json:"count"`
+}
+
+// ReportCategory 分类汇总，Percent为占同类型（收入或支出）合计的百分比
+type ReportCategory struct {
+	CategoryID uint    `json:"category_id"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Icon       string  `json:"icon,omitempty"`
+	Total      Money   `json:"total"`
+	Count      int     `json:"count"`
+	Percent    float64 `json:"percent"`
+}
+
+// ReportBucket 时间序列中的一个分桶，首尾分桶按报表范围截断
+type ReportBucket struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Income    Money  `json:"income"`
+	Expense   Money  `json:"expense"`
+	Balance   Money  `json:"balance"`
+}
+
+// ReportComparison 与另一时期的对比，变化百分比在对比期为0时为null
+type ReportComparison struct {
+	StartDate     string        `json:"start_date"`
+	EndDate       string        `json:"end_date"`
+	Summary       ReportSummary `json:"summary"`
+	IncomeChange  *float64      `json:"income_change"`
+	ExpenseChange *float64      `json:"expense_change"`
+	BalanceChange Money         `json:"balance_change"`
+}
+
+// Report 报表
+type Report struct {
+	Period     string            `json:"period,omitempty"`
+	StartDate  string            `json:"start_date"`
+	EndDate    string            `json:"end_date"`
+	Bucket     string            `json:"bucket"`
+	Summary    ReportSummary     `json:"summary"`
+	Categories []*ReportCategory `json:"categories"`
+	Series     []*ReportBucket   `json:"series"`
+	Previous   *ReportComparison `json:"previous"`  // 与上一周期对比，自定义范围时为紧邻的等长区间
+	LastYear   *ReportComparison `json:"last_year"` // 与去年同期对比
+}
+
+// Valid 校验自定义范围完整且不与周期同时使用
+func (p *ReportParams) Valid(v *validation.Validation) {
+	custom := p.StartDate != "" || p.EndDate != ""
+	if custom && p.Period != "" {
+		v.AddError("Period.ReportRange.", "report_range_conflict")
+		return
+	}
+	if custom && (p.StartDate == "" || p.EndDate == "") {
+		v.AddError("EndDate.ReportRange.", "report_range_incomplete")
+		return
+	}
+	if !custom {
+		return
+	}
+
+	start, _ := time.Parse("2006-01-02", p.StartDate)
+	end, _ := time.Parse("2006-01-02", p.EndDate)
+	if start.After(end) {
+		v.AddError("EndDate.DateRange.", "date_range_invalid")
+		return
+	}
+	if daysBetween(start, end) > maxReportDays {
+		v.AddError("EndDate.ReportRange.", "report_range_too_long")
+		return
+	}
+	bucket := p.Bucket
+	if bucket == "" {
+		bucket = defaultBucket(start, end)
+	}
+	if len(ReportBuckets(start, end, bucket)) > maxReportBuckets {
+		v.AddError("Bucket.ReportBuckets.", "report_too_many_buckets")
+	}
+}
+
+// daysBetween 两个日期间隔的天数（含首尾）
+func daysBetween(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// defaultBucket 按范围长度选择分桶粒度
+func defaultBucket(start, end time.Time) string {
+	switch days := daysBetween(start, end); {
+	case days <= 31:
+		return BucketDay
+	case days <= 92:
+		return BucketWeek
+	default:
+		return BucketMonth
+	}
+}
+
+// periodRange 日期所在周期的起止日期
+func periodRange(period string, day time.Time) (time.Time, time.Time) {
+	switch period {
+	case PeriodWeek:
+		return relativeRanges[RangeThisWeek](day)
+	case PeriodQuarter:
+		return relativeRanges[RangeThisQuarter](day)
+	case PeriodYear:
+		return relativeRanges[RangeThisYear](day)
+	default:
+		return relativeRanges[RangeThisMonth](day)
+	}
+}
+
+// addYears 按年平移日期，2月29日平移到非闰年时取2月28日
+func addYears(day time.Time, years int) time.Time {
+	shifted := time.Date(day.Year()+years, day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := shifted.AddDate(0, 1, -1).Day()
+	if day.Day() < lastDay {
+		lastDay = day.Day()
+	}
+	return time.Date(shifted.Year(), shifted.Month(), lastDay, 0, 0, 0, 0, time.UTC)
+}
+
+// DateSpan 日期区间（含首尾）
+type DateSpan struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Ranges 计算报表的周期、范围以及上一周期和去年同期的范围，today为用户时区下的当天
+func (p *ReportParams) Ranges(today time.Time) (period string, current, previous, lastYear DateSpan) {
+	if p.StartDate != "" && p.EndDate != "" {
+		start, _ := time.Parse("2006-01-02", p.StartDate)
+		end, _ := time.Parse("2006-01-02", p.EndDate)
+		days := daysBetween(start, end)
+		current = DateSpan{start, end}
+		previous = DateSpan{start.AddDate(0, 0, -days), start.AddDate(0, 0, -1)}
+		lastYear = DateSpan{addYears(start, -1), addYears(end, -1)}
+		return "", current, previous, lastYear
+	}
+
+	period = p.Period
+	if period == "" {
+		period = PeriodMonth
+	}
+	anchor := today
+	if p.Date != "" {
+		anchor, _ = time.Parse("2006-01-02", p.Date)
+	}
+	current = periodSpan(period, anchor)
+	previous = periodSpan(period, current.Start.AddDate(0, 0, -1))
+	if period == PeriodWeek {
+		// 去年同期取去年同一天所在的周
+		lastYear = periodSpan(period, addYears(anchor, -1))
+	} else {
+		lastYear = periodSpan(period, addYears(current.Start, -1))
+	}
+	return period, current, previous, lastYear
+}
+
+// periodSpan 日期所在周期的区间
+func periodSpan(period string, day time.Time) DateSpan {
+	start, end := periodRange(period, day)
+	return DateSpan{start, end}
+}
+
+// bucketStart 日期所在分桶的第一天
+func bucketStart(day time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return weekStart(day)
+	case BucketMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextBucket 下一个分桶的第一天
+func nextBucket(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case BucketMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// ReportBuckets 生成范围内的所有分桶，首尾分桶按范围截断
+func ReportBuckets(start, end time.Time, bucket string) []*ReportBucket {
+	buckets := make([]*ReportBucket, 0)
+	for b := bucketStart(start, bucket); !b.After(end); b = nextBucket(b, bucket) {
+		from, to := b, nextBucket(b, bucket).AddDate(0, 0, -1)
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		buckets = append(buckets, &ReportBucket{
+			StartDate: from.Format("2006-01-02"),
+			EndDate:   to.Format("2006-01-02"),
+		})
+	}
+	return buckets
+}
+
+// bucketKeyExpr 分桶键的SQL表达式，结果为分桶第一天的YYYY-MM-DD
+var bucketKeyExpr = map[string]string{
+	BucketDay:   "DATE_FORMAT(b.date, '%Y-%m-%d')",
+	BucketWeek:  "DATE_FORMAT(DATE_SUB(b.date, INTERVAL WEEKDAY(b.date) DAY), '%Y-%m-%d')",
+	BucketMonth: "DATE_FORMAT(b.date, '%Y-%m-01')",
+}
+
+// GetReport 生成指定范围的报表，包括合计、分类汇总、时间序列和同比环比
+func GetReport(ctx context.Context, userID uint, params *ReportParams) (*Report, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	loc, err := UserLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	period, current, previous, lastYear := params.Ranges(Today(loc))
+	start, end := current.Start, current.End
+	bucket := params.Bucket
+	if bucket == "" {
+		bucket = defaultBucket(start, end)
+	}
+
+	report := &Report{
+		Period:    period,
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Bucket:    bucket,
+	}
+
+	summary, err := reportSummary(ctx, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	report.Summary = *summary
+
+	if report.Categories, err = reportCategories(ctx, userID, start, end, summary); err != nil {
+		return nil, err
+	}
+
+	if report.Series, err = reportSeries(ctx, userID, start, end, bucket); err != nil {
+		return nil, err
+	}
+
+	if report.Previous, err = reportComparison(ctx, userID, previous, summary); err != nil {
+		return nil, err
+	}
+
+	if report.LastYear, err = reportComparison(ctx, userID, lastYear, summary); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// reportSummary 统计范围内的收支合计
+func reportSummary(ctx context.Context, userID uint, start, end time.Time) (*ReportSummary, error) {
+	summary := &ReportSummary{}
+	err := dbQueryRow(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0),
+		       COUNT(*)
+		FROM bills
+		WHERE user_id = ? AND date BETWEEN ? AND ?
+	`, userID, start.Format("2006-01-02"), end.Format("2006-01-02")).Scan(&summary.Income, &summary.Expense, &summary.Count)
+	if err != nil {
+		logs.Error("Error calculating report summary: %v", err)
+		return nil, err
+	}
+	summary.Balance = summary.Income - summary.Expense
+	return summary, nil
+}
+
+// reportCategories 统计范围内各分类的合计
+func reportCategories(ctx context.Context, userID uint, start, end time.Time, summary *ReportSummary) ([]*ReportCategory, error) {
+	rows, err := dbQuery(ctx, `
+		SELECT c.id, c.name, c.type, c.icon, SUM(b.amount) AS total, COUNT(*)
+		FROM bills b
+		JOIN categories c ON b.category_id = c.id
+		WHERE b.user_id = ? AND b.date BETWEEN ? AND ?
+		GROUP BY b.category_id
+		ORDER BY total DESC
+	`, userID, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		logs.Error("Error querying report categories: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]*ReportCategory, 0)
+	for rows.Next() {
+		category := &ReportCategory{}
+		err := rows.Scan(&category.CategoryID, &category.Name, &category.Type, &category.Icon, &category.Total, &category.Count)
+		if err != nil {
+			logs.Error("Error scanning report category row: %v", err)
+			return nil, err
+		}
+		if category.Type == "income" {
+			category.Percent = category.Total.Percent(summary.Income)
+		} else {
+			category.Percent = category.Total.Percent(summary.Expense)
+		}
+		categories = append(categories, category)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating report category rows: %v", err)
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// reportSeries 按分桶统计范围内的收支，没有账单的分桶补零
+func reportSeries(ctx context.Context, userID uint, start, end time.Time, bucket string) ([]*ReportBucket, error) {
+	keyExpr := bucketKeyExpr[bucket]
+	rows, err := dbQuery(ctx, `
+		SELECT `+keyExpr+` AS bucket,
+		       SUM(CASE WHEN b.type = 'income' THEN b.amount ELSE 0 END),
+		       SUM(CASE WHEN b.type = 'expense' THEN b.amount ELSE 0 END)
+		FROM bills b
+		WHERE b.user_id = ? AND b.date BETWEEN ? AND ?
+		GROUP BY bucket
+	`, userID, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		logs.Error("Error querying report series: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := ReportBuckets(start, end, bucket)
+	byKey := make(map[string]*ReportBucket, len(series))
+	for _, b := range series {
+		day, _ := time.Parse("2006-01-02", b.StartDate)
+		byKey[bucketStart(day, bucket).Format("2006-01-02")] = b
+	}
+
+	for rows.Next() {
+		var key string
+		var income, expense Money
+		if err := rows.Scan(&key, &income, &expense); err != nil {
+			logs.Error("Error scanning report series row: %v", err)
+			return nil, err
+		}
+		if b, ok := byKey[key]; ok {
+			b.Income, b.Expense, b.Balance = income, expense, income-expense
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating report series rows: %v", err)
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// reportComparison 统计对比期的合计并计算变化
+func reportComparison(ctx context.Context, userID uint, span DateSpan, current *ReportSummary) (*ReportComparison, error) {
+	summary, err := reportSummary(ctx, userID, span.Start, span.End)
+	if err != nil {
+		return nil, err
+	}
+	return &ReportComparison{
+		StartDate:     span.Start.Format("2006-01-02"),
+		EndDate:       span.End.Format("2006-01-02"),
+		Summary:       *summary,
+		IncomeChange:  changePercent(current.Income, summary.Income),
+		ExpenseChange: changePercent(current.Expense, summary.Expense),
+		BalanceChange: current.Balance - summary.Balance,
+	}, nil
+}
+
+// changePercent 相对对比值的变化百分比，对比值为0时无意义返回nil
+func changePercent(current, base Money) *float64 {
+	if base == 0 {
+		return nil
+	}
+	change := (current - base).Percent(base)
+	return &change
+}
+
+// FormatChange 格式化changePercent计算的变化百分比，无法比较时显示为—，用于对账单和报告邮件
+func FormatChange(change *float64) string {
+	if change == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%+.2f%%", *change)
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 账单搜索后端
+const (
+	SearchFulltext = "fulltext" // MySQL FULLTEXT索引，使用ngram分词以支持中文
+	SearchLike     = "like"     // LIKE模糊匹配，用于不支持FULLTEXT的数据库
+)
+
+// SearchBackend 当前使用的搜索后端，可通过 searchbackend 配置，FULLTEXT索引创建失败时自动回退为LIKE
+var SearchBackend = SearchFulltext
+
+// maxSearchTerms 单次搜索最多使用的关键词数
+const maxSearchTerms = 5
+
+// ngramTokenSize ngram分词的长度，短于该长度的关键词无法命中FULLTEXT索引
+var ngramTokenSize = 2
+
+// snippetRadius 高亮片段在首个命中位置前后保留的字符数
+const snippetRadius = 40
+
+// loadSearchBackend 从配置读取搜索后端
+func loadSearchBackend() {
+	backend, _ := web.AppConfig.String("searchbackend")
+	switch backend {
+	case "":
+	case SearchFulltext, SearchLike:
+		SearchBackend = backend
+	default:
+		logs.Error("Invalid searchbackend %q, using %s", backend, SearchBackend)
+	}
+}
+
+// loadNgramTokenSize 读取数据库的ngram分词长度
+func loadNgramTokenSize() {
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+
+	var size int
+	if err := dbQueryRow(ctx, "SELECT @@ngram_token_size").Scan(&size); err != nil {
+		logs.Warn("Failed to read ngram_token_size, assuming %d: %v", ngramTokenSize, err)
+		return
+	}
+	ngramTokenSize = size
+}
+
+// SplitSearchTerms 将搜索词按空白拆分为关键词，去除重复和FULLTEXT布尔运算符中的引号
+func SplitSearchTerms(q string) []string {
+	terms := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, term := range strings.Fields(strings.Replace(q, `"`, " ", -1)) {
+		key := strings.ToLower(term)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms = append(terms, term)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// searchCondition 生成搜索条件，每个关键词需命中描述、商户、分类名称或标签之一
+func searchCondition(terms []string) (string, []interface{}) {
+	var where string
+	args := make([]interface{}, 0)
+	for _, term := range terms {
+		if SearchBackend == SearchFulltext && utf8.RuneCountInString(term) >= ngramTokenSize {
+			phrase := `"` + term + `"`
+			where += ` AND (MATCH(b.description, b.merchant) AGAINST (? IN BOOLEAN MODE)
+				OR MATCH(c.name) AGAINST (? IN BOOLEAN MODE)
+				OR EXISTS(SELECT 1 FROM bill_tags t WHERE t.bill_id = b.id AND MATCH(t.tag) AGAINST (? IN BOOLEAN MODE)))`
+			args = append(args, phrase, phrase, phrase)
+			continue
+		}
+
+		// 不使用FULLTEXT或关键词短于分词长度时退化为LIKE
+		pattern := "%" + escapeLike(term) + "%"
+		where += ` AND (b.description LIKE ? OR b.merchant LIKE ? OR c.name LIKE ?
+			OR EXISTS(SELECT 1 FROM bill_tags t WHERE t.bill_id = b.id AND t.tag LIKE ?))`
+		args = append(args, pattern, pattern, pattern, pattern)
+	}
+	return where, args
+}
+
+// escapeLike 转义LIKE中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Highlight 对命中的关键词加上<em>标签，其余内容做HTML转义
+//
+// 文本较长时只保留首个命中位置附近的片段，没有命中时返回false。
+func Highlight(text string, terms []string) (string, bool) {
+	spans := matchSpans(text, terms)
+	if len(spans) == 0 {
+		return "", false
+	}
+
+	// 截取首个命中位置附近的片段
+	start, end := 0, len(text)
+	prefix, suffix := "", ""
+	if utf8.RuneCountInString(text) > snippetRadius*3 {
+		start = moveRunes(text, spans[0][0], -snippetRadius)
+		end = moveRunes(text, spans[0][1], snippetRadius)
+		if start > 0 {
+			prefix = "…"
+		}
+		if end < len(text) {
+			suffix = "…"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	pos := start
+	for _, span := range spans {
+		if span[0] < pos || span[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:span[0]]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[span[0]:span[1]]))
+		b.WriteString("</em>")
+		pos = span[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	b.WriteString(suffix)
+	return b.String(), true
+}
+
+// matchSpans 查找所有关键词的命中区间（字节偏移），按位置排序并合并重叠部分
+func matchSpans(text string, terms []string) [][2]int {
+	// 大小写转换可能改变字节长度，此时退化为区分大小写的匹配
+	haystack := strings.ToLower(text)
+	foldCase := len(haystack) == len(text)
+	if !foldCase {
+		haystack = text
+	}
+
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		needle := term
+		if foldCase {
+			needle = strings.ToLower(term)
+		}
+		if needle == "" {
+			continue
+		}
+		for offset := 0; ; {
+			i := strings.Index(haystack[offset:], needle)
+			if i < 0 {
+				break
+			}
+			for j := offset + i; j < offset+i+len(needle); j++ {
+				marked[j] = true
+			}
+			offset += i + len(needle)
+		}
+	}
+
+	spans := make([][2]int, 0)
+	for i := 0; i < len(marked); i++ {
+		if !marked[i] {
+			continue
+		}
+		j := i
+		for j < len(marked) && marked[j] {
+			j++
+		}
+		spans = append(spans, [2]int{i, j})
+		i = j
+	}
+	return spans
+}
+
+// moveRunes 从字节偏移pos起向前或向后移动n个字符，返回新的字节偏移
+func moveRunes(s string, pos, n int) int {
+	for ; n < 0 && pos > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(s[:pos])
+		pos -= size
+	}
+	for ; n > 0 && pos < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		pos += size
+	}
+	return pos
+}
+
+// highlight 为搜索结果生成各字段的高亮片段
+func (b *Bill) highlight(terms []string) {
+	highlights := make(map[string]string)
+	if s, ok := Highlight(b.Description, terms); ok {
+		highlights["description"] = s
+	}
+	if s, ok := Highlight(b.Merchant, terms); ok {
+		highlights["merchant"] = s
+	}
+	if s, ok := Highlight(b.CategoryName, terms); ok {
+		highlights["category_name"] = s
+	}
+	tags := make([]string, 0)
+	for _, tag := range b.Tags {
+		if s, ok := Highlight(tag, terms); ok {
+			tags = append(tags, s)
+		}
+	}
+	if len(tags) > 0 {
+		highlights["tags"] = strings.Join(tags, ", ")
+	}
+	if len(highlights) > 0 {
+		b.Highlights = highlights
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// statementTopBills 月度对账单中列出的最大支出笔数
+const statementTopBills = 10
+
+// Statement 月度对账单
+type Statement struct {
+	User        *User     `json:"user"`
+	Month       string    `json:"month"`
+	Report      *Report   `json:"report"`
+	Budgets     []*Budget `json:"budgets"`
+	TopBills    []*Bill   `json:"top_bills"` // 当月金额最大的支出
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// GetStatement 汇总月度对账单所需的数据，month格式为YYYY-MM
+func GetStatement(ctx context.Context, userID uint, month string) (*Statement, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, ErrInvalidMonth
+	}
+
+	user, err := GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := GetReport(ctx, userID, &ReportParams{
+		Period: PeriodMonth,
+		Date:   start.Format("2006-01-02"),
+		Bucket: BucketDay,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	budgets, err := GetBudgets(ctx, userID, month)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := GetBills(ctx, userID, &BillQueryParams{
+		StartDate: report.StartDate,
+		EndDate:   report.EndDate,
+		Type:      "expense",
+		Sort:      "-amount",
+		PageSize:  statementTopBills,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Statement{
+		User:        user,
+		Month:       month,
+		Report:      report,
+		Budgets:     FilterBudgets(budgets, BudgetMonthly),
+		TopBills:    page.Bills,
+		GeneratedAt: time.Now().In(user.Location()),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// StreamTicketTTL 事件流票据的有效期，票据只用于立即发起的连接
+const StreamTicketTTL = 30 * time.Second
+
+// StreamTicket 建立实时事件流使用的一次性票据
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateStreamTicket 为用户生成事件流票据，同时清理该用户已过期的票据
+//
+// 浏览器的EventSource无法设置请求头，票据通过查询参数传递，可能出现在代理和访问日志中，
+// 因此只短时有效且只能使用一次，避免把长期有效的JWT放在URL里。
+func CreateStreamTicket(ctx context.Context, userID uint) (*StreamTicket, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	if _, err := dbExec(ctx, "DELETE FROM stream_tickets WHERE user_id = ? AND expires_at <= ?", userID, now); err != nil {
+		logs.Error("Error deleting expired stream tickets: %v", err)
+		return nil, err
+	}
+
+	ticket, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(StreamTicketTTL)
+	if _, err := dbExec(ctx, "INSERT INTO stream_tickets (ticket, user_id, expires_at) VALUES (?, ?, ?)", ticket, userID, expiresAt); err != nil {
+		logs.Error("Error creating stream ticket: %v", err)
+		return nil, err
+	}
+
+	return &StreamTicket{Ticket: ticket, ExpiresAt: expiresAt}, nil
+}
+
+// ConsumeStreamTicket 使用票据并返回所属用户，票据无效、过期或已被使用时返回ErrStreamTicketInvalid
+func ConsumeStreamTicket(ctx context.Context, ticket string) (uint, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if ticket == "" {
+		return 0, ErrStreamTicketInvalid
+	}
+
+	var userID uint
+	var expiresAt time.Time
+	err := dbQueryRow(ctx, "SELECT user_id, expires_at FROM stream_tickets WHERE ticket = ?", ticket).Scan(&userID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrStreamTicketInvalid
+	}
+	if err != nil {
+		logs.Error("Error querying stream ticket: %v", err)
+		return 0, err
+	}
+
+	// 并发使用同一票据时只有删除成功的一方有效
+	result, err := dbExec(ctx, "DELETE FROM stream_tickets WHERE ticket = ?", ticket)
+	if err != nil {
+		logs.Error("Error consuming stream ticket: %v", err)
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting consumed stream tickets: %v", err)
+		return 0, err
+	}
+	if affected == 0 || !expiresAt.After(time.Now()) {
+		return 0, ErrStreamTicketInvalid
+	}
+
+	return userID, nil
+}
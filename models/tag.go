@@ -0,0 +1,94 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 标签限制
+const (
+	MaxBillTags  = 10 // 单个账单最多标签数
+	MaxTagLength = 32 // 单个标签最大字符数
+)
+
+// normalizeTags 去除标签首尾空白、空标签和重复标签，保持原有顺序
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// saveBillTags 替换账单的全部标签
+func saveBillTags(ctx context.Context, tx *sql.Tx, billID uint, tags []string) error {
+	if _, err := txExec(ctx, tx, "DELETE FROM bill_tags WHERE bill_id = ?", billID); err != nil {
+		logs.Error("Error deleting bill tags: %v", err)
+		return err
+	}
+
+	for _, tag := range normalizeTags(tags) {
+		if _, err := txExec(ctx, tx, "INSERT INTO bill_tags (bill_id, tag) VALUES (?, ?)", billID, tag); err != nil {
+			logs.Error("Error inserting bill tag: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadBillTags 批量加载账单的标签
+func loadBillTags(ctx context.Context, bills []*Bill) error {
+	if len(bills) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint]*Bill, len(bills))
+	placeholders := make([]string, 0, len(bills))
+	args := make([]interface{}, 0, len(bills))
+	for _, bill := range bills {
+		bill.Tags = make([]string, 0)
+		byID[bill.ID] = bill
+		placeholders = append(placeholders, "?")
+		args = append(args, bill.ID)
+	}
+
+	rows, err := dbQuery(ctx,
+		"SELECT bill_id, tag FROM bill_tags WHERE bill_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		logs.Error("Error querying bill tags: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var billID uint
+		var tag string
+		if err := rows.Scan(&billID, &tag); err != nil {
+			logs.Error("Error scanning bill tag: %v", err)
+			return err
+		}
+		if bill, ok := byID[billID]; ok {
+			bill.Tags = append(bill.Tags, tag)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		logs.Error("Error iterating bill tags: %v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// DefaultLocation 用户未设置时区时使用的时区，可通过 defaulttimezone 配置
+var DefaultLocation = time.Local
+
+// loadDefaultLocation 从配置读取默认时区
+func loadDefaultLocation() {
+	name, _ := web.AppConfig.String("defaulttimezone")
+	if name == "" {
+		return
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		logs.Error("Invalid defaulttimezone %q: %v", name, err)
+		return
+	}
+	DefaultLocation = loc
+}
+
+// LoadLocation 按IANA名称加载时区，空值或无效时返回默认时区
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return DefaultLocation
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		logs.Warn("Unknown timezone %q, falling back to default: %v", name, err)
+		return DefaultLocation
+	}
+	return loc
+}
+
+// Location 用户所在时区
+func (u *User) Location() *time.Location {
+	return LoadLocation(u.Timezone)
+}
+
+// UserLocation 查询用户所在时区
+func UserLocation(ctx context.Context, userID uint) (*time.Location, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var timezone string
+	err := dbQueryRow(ctx, "SELECT timezone FROM users WHERE id = ?", userID).Scan(&timezone)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		logs.Error("Error querying user timezone: %v", err)
+		return nil, err
+	}
+
+	return LoadLocation(timezone), nil
+}
+
+// CurrentMonth 用户时区下的当前月份，格式为YYYY-MM
+func CurrentMonth(loc *time.Location) string {
+	return time.Now().In(loc).Format("2006-01")
+}
+
+// monthRange 月份的起止日期（含），账单日期为不带时区的日历日期
+func monthRange(year int, month time.Month) (string, string) {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	return start.Format("2006-01-02"), end.Format("2006-01-02")
+}
+
+// ParseBillDate 解析账单日期，支持YYYY-MM-DD和RFC3339时间戳，时间戳按用户时区换算为日期
+func ParseBillDate(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,307 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 趋势与占比查询的默认值
+const (
+	DefaultTrendMonths = 12
+	DefaultShareTop    = 5
+)
+
+// TrendParams 收支趋势查询参数
+type TrendParams struct {
+	Months   int    `json:"months" valid:"Range(1,60)"`
+	EndMonth string `json:"end_month" valid:"Month"`                   // 最后一个月，格式YYYY-MM，默认为用户时区下的本月
+	Type     string `json:"type" valid:"Match(/^(income|expense)?$/)"` // 分类序列的账单类型，默认expense
+}
+
+// TrendPoint 单月收支
+type TrendPoint struct {
+	Month   string `json:"month"`
+	Income  Money  `json:"income"`
+	Expense Money  `json:"expense"`
+	Balance Money  `json:"balance"`
+}
+
+// CategorySeries 分类的逐月合计，Values与Trend.Months一一对应
+type CategorySeries struct {
+	CategoryID uint    `json:"category_id"`
+	Name       string  `json:"name"`
+	Icon       string  `json:"icon,omitempty"`
+	Total      Money   `json:"total"`
+	Values     []Money `json:"values"`
+}
+
+// Trend 多月收支趋势
+type Trend struct {
+	Months     []string          `json:"months"`
+	Type       string            `json:"type"`
+	Series     []*TrendPoint     `json:"series"`
+	Categories []*CategorySeries `json:"categories"`
+}
+
+// ShareParams 分类占比查询参数，统计范围使用ReportParams
+type ShareParams struct {
+	Type string `json:"type" valid:"Match(/^(income|expense)?$/)"` // 默认expense
+	Top  int    `json:"top" valid:"Range(1,20)"`                   // 单独列出的分类数，其余归入"其他"
+}
+
+// ShareItem 分类占比，Other为true时表示其余分类的合计
+type ShareItem struct {
+	CategoryID uint    `json:"category_id,omitempty"`
+	Name       string  `json:"name"`
+	Icon       string  `json:"icon,omitempty"`
+	Total      Money   `json:"total"`
+	Count      int     `json:"count"`
+	Percent    float64 `json:"percent"`
+	Other      bool    `json:"other,omitempty"`
+}
+
+// CategoryShare 分类占比
+type CategoryShare struct {
+	StartDate string       `json:"start_date"`
+	EndDate   string       `json:"end_date"`
+	Type      string       `json:"type"`
+	Total     Money        `json:"total"`
+	Items     []*ShareItem `json:"items"`
+}
+
+// TrendMonths 以endMonth结尾的连续n个月，格式YYYY-MM
+func TrendMonths(endMonth time.Time, n int) []string {
+	months := make([]string, n)
+	first := time.Date(endMonth.Year(), endMonth.Month()-time.Month(n-1), 1, 0, 0, 0, 0, time.UTC)
+	for i := range months {
+		months[i] = first.AddDate(0, i, 0).Format("2006-01")
+	}
+	return months
+}
+
+// GetTrend 获取逐月收支和分类逐月合计，各使用一次分组查询
+func GetTrend(ctx context.Context, userID uint, params *TrendParams) (*Trend, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	n := params.Months
+	if n == 0 {
+		n = DefaultTrendMonths
+	}
+	billType := params.Type
+	if billType == "" {
+		billType = "expense"
+	}
+
+	endMonth := params.EndMonth
+	if endMonth == "" {
+		loc, err := UserLocation(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		endMonth = CurrentMonth(loc)
+	}
+	end, err := time.Parse("2006-01", endMonth)
+	if err != nil {
+		return nil, ErrInvalidMonth
+	}
+
+	months := TrendMonths(end, n)
+	startDate := months[0] + "-01"
+	_, endDate := monthRange(end.Year(), end.Month())
+	index := make(map[string]int, n)
+	for i, month := range months {
+		index[month] = i
+	}
+
+	trend := &Trend{
+		Months:     months,
+		Type:       billType,
+		Series:     make([]*TrendPoint, n),
+		Categories: make([]*CategorySeries, 0),
+	}
+	for i, month := range months {
+		trend.Series[i] = &TrendPoint{Month: month}
+	}
+
+	// 逐月收支
+	rows, err := dbQuery(ctx, `
+		SELECT DATE_FORMAT(date, '%Y-%m') AS month,
+		       SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END),
+		       SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END)
+		FROM bills
+		WHERE user_id = ? AND date BETWEEN ? AND ?
+		GROUP BY month
+	`, userID, startDate, endDate)
+	if err != nil {
+		logs.Error("Error querying monthly trend: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var month string
+		var income, expense Money
+		if err := rows.Scan(&month, &income, &expense); err != nil {
+			logs.Error("Error scanning monthly trend row: %v", err)
+			return nil, err
+		}
+		if i, ok := index[month]; ok {
+			trend.Series[i].Income = income
+			trend.Series[i].Expense = expense
+			trend.Series[i].Balance = income - expense
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating monthly trend rows: %v", err)
+		return nil, err
+	}
+
+	// 分类逐月合计，按区间总额降序
+	catRows, err := dbQuery(ctx, `
+		SELECT DATE_FORMAT(b.date, '%Y-%m') AS month, c.id, c.name, c.icon, SUM(b.amount)
+		FROM bills b
+		JOIN categories c ON b.category_id = c.id
+		WHERE b.user_id = ? AND b.type = ? AND b.date BETWEEN ? AND ?
+		GROUP BY month, b.category_id
+	`, userID, billType, startDate, endDate)
+	if err != nil {
+		logs.Error("Error querying category trend: %v", err)
+		return nil, err
+	}
+	defer catRows.Close()
+
+	byCategory := make(map[uint]*CategorySeries)
+	for catRows.Next() {
+		var month string
+		var total Money
+		series := &CategorySeries{}
+		if err := catRows.Scan(&month, &series.CategoryID, &series.Name, &series.Icon, &total); err != nil {
+			logs.Error("Error scanning category trend row: %v", err)
+			return nil, err
+		}
+		if existing, ok := byCategory[series.CategoryID]; ok {
+			series = existing
+		} else {
+			series.Values = make([]Money, n)
+			byCategory[series.CategoryID] = series
+			trend.Categories = append(trend.Categories, series)
+		}
+		if i, ok := index[month]; ok {
+			series.Values[i] = total
+			series.Total += total
+		}
+	}
+
+	if err = catRows.Err(); err != nil {
+		logs.Error("Error iterating category trend rows: %v", err)
+		return nil, err
+	}
+
+	sortCategorySeries(trend.Categories)
+	return trend, nil
+}
+
+// sortCategorySeries 按总额降序排列，总额相同时按分类ID排列
+func sortCategorySeries(series []*CategorySeries) {
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].Total != series[j].Total {
+			return series[i].Total > series[j].Total
+		}
+		return series[i].CategoryID < series[j].CategoryID
+	})
+}
+
+// GetCategoryShare 获取范围内各分类占比，前top个分类单独列出，其余归入"其他"
+func GetCategoryShare(ctx context.Context, userID uint, rangeParams *ReportParams, params *ShareParams) (*CategoryShare, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	loc, err := UserLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	_, current, _, _ := rangeParams.Ranges(Today(loc))
+
+	billType := params.Type
+	if billType == "" {
+		billType = "expense"
+	}
+	top := params.Top
+	if top == 0 {
+		top = DefaultShareTop
+	}
+
+	share := &CategoryShare{
+		StartDate: current.Start.Format("2006-01-02"),
+		EndDate:   current.End.Format("2006-01-02"),
+		Type:      billType,
+		Items:     make([]*ShareItem, 0),
+	}
+
+	rows, err := dbQuery(ctx, `
+		SELECT c.id, c.name, c.icon, SUM(b.amount) AS total, COUNT(*)
+		FROM bills b
+		JOIN categories c ON b.category_id = c.id
+		WHERE b.user_id = ? AND b.type = ? AND b.date BETWEEN ? AND ?
+		GROUP BY b.category_id
+		ORDER BY total DESC, c.id
+	`, userID, billType, share.StartDate, share.EndDate)
+	if err != nil {
+		logs.Error("Error querying category share: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &ShareItem{}
+		if err := rows.Scan(&item.CategoryID, &item.Name, &item.Icon, &item.Total, &item.Count); err != nil {
+			logs.Error("Error scanning category share row: %v", err)
+			return nil, err
+		}
+		share.Total += item.Total
+		share.Items = append(share.Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating category share rows: %v", err)
+		return nil, err
+	}
+
+	share.Items = BucketShares(share.Items, share.Total, top)
+	return share, nil
+}
+
+// BucketShares 计算各项百分比，超过top项时其余合并为"其他"
+//
+// "其他"的百分比取100减去前top项之和，保证各项合计恰好为100。
+func BucketShares(items []*ShareItem, total Money, top int) []*ShareItem {
+	if len(items) == 0 {
+		return items
+	}
+	if len(items) > top {
+		other := &ShareItem{Other: true}
+		for _, item := range items[top:] {
+			other.Total += item.Total
+			other.Count += item.Count
+		}
+		items = append(items[:top:top], other)
+	}
+
+	var sum float64
+	for _, item := range items {
+		if item.Other {
+			continue
+		}
+		item.Percent = item.Total.Percent(total)
+		sum += item.Percent
+	}
+	if last := items[len(items)-1]; last.Other && total > 0 {
+		last.Percent = float64(10000-int64(sum*100+0.5)) / 100
+	}
+	return items
+}
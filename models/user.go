@@ -1,10 +1,13 @@
 package models
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"strings"
 	"time"
 
+	"blog/i18n"
+
 	"github.com/beego/beego/v2/core/logs"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,6 +20,8 @@ type User struct {
 	Password  string    `json:"-"` // 不在JSON中显示密码
 	Phone     string    `json:"phone,omitempty"`
 	Avatar    string    `json:"avatar,omitempty"`
+	Timezone  string    `json:"timezone,omitempty"` // IANA时区名称，为空时使用默认时区
+	Language  string    `json:"language,omitempty"` // 界面语言，为空时按Accept-Language选择
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -26,46 +31,53 @@ type RegisterRequest struct {
 	Username string `json:"username" valid:"Required;MinSize(3);MaxSize(50)"`
 	Email    string `json:"email" valid:"Required;Email"`
 	Password string `json:"password" valid:"Required;MinSize(6)"`
-	Phone    string `json:"phone,omitempty"`
+	Phone    string `json:"phone,omitempty" valid:"MaxSize(20)"`
+	Timezone string `json:"timezone,omitempty" valid:"Timezone"`
+	Language string `json:"language,omitempty" valid:"Language"` // 同时决定默认分类名称的语言
 }
 
 // LoginRequest 用户登录请求
 type LoginRequest struct {
-	Username string `json:"username"` // 可以是用户名或邮箱
+	Username string `json:"username" valid:"Required"` // 可以是用户名或邮箱
 	Password string `json:"password" valid:"Required"`
 }
 
 // UserProfileResponse 用户资料响应
 type UserProfileResponse struct {
 	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Phone     string    `json:"phone,omitempty"`
-	Avatar    string    `json:"avatar,omitempty"`
+	Username  string    `json:"username" valid:"Required;MinSize(3);MaxSize(50)"`
+	Email     string    `json:"email" valid:"Required;Email"`
+	Phone     string    `json:"phone,omitempty" valid:"MaxSize(20)"`
+	Avatar    string    `json:"avatar,omitempty" valid:"MaxSize(255)"`
+	Timezone  string    `json:"timezone,omitempty" valid:"Timezone"`
+	Language  string    `json:"language,omitempty" valid:"Language"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 // CreateUser 创建新用户
-func CreateUser(req *RegisterRequest) (*User, error) {
+func CreateUser(ctx context.Context, req *RegisterRequest) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查用户名是否已存在
 	var exists bool
-	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", req.Username).Scan(&exists)
+	err := dbQueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", req.Username).Scan(&exists)
 	if err != nil {
 		logs.Error("Error checking username existence: %v", err)
 		return nil, err
 	}
 	if exists {
-		return nil, errors.New("用户名已存在")
+		return nil, ErrUsernameTaken
 	}
 
 	// 检查邮箱是否已存在
-	err = DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", req.Email).Scan(&exists)
+	err = dbQueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", req.Email).Scan(&exists)
 	if err != nil {
 		logs.Error("Error checking email existence: %v", err)
 		return nil, err
 	}
 	if exists {
-		return nil, errors.New("邮箱已被注册")
+		return nil, ErrEmailTaken
 	}
 
 	// 加密密码
@@ -76,17 +88,24 @@ func CreateUser(req *RegisterRequest) (*User, error) {
 	}
 
 	// 开始事务
-	tx, err := DB.Begin()
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
 		logs.Error("Error starting transaction: %v", err)
 		return nil, err
 	}
 
 	// 创建用户
-	result, err := tx.Exec(
-		"INSERT INTO users (username, email, password, phone) VALUES (?, ?, ?, ?)",
-		req.Username, req.Email, hashedPassword, req.Phone,
+	result, err := txExec(ctx, tx, 
+		"INSERT INTO users (username, email, password, phone, timezone, language) VALUES (?, ?, ?, ?, ?, ?)",
+		req.Username, req.Email, hashedPassword, req.Phone, req.Timezone, req.Language,
 	)
+	if isDuplicateEntry(err) {
+		tx.Rollback()
+		if strings.Contains(err.Error(), "email") {
+			return nil, ErrEmailTaken
+		}
+		return nil, ErrUsernameTaken
+	}
 	if err != nil {
 		tx.Rollback()
 		logs.Error("Error inserting user: %v", err)
@@ -100,25 +119,28 @@ func CreateUser(req *RegisterRequest) (*User, error) {
 		return nil, err
 	}
 
-	// 创建默认分类
+	// 创建默认分类，名称使用用户的语言
+	lang := req.Language
+	if lang == "" {
+		lang = i18n.Default
+	}
 	defaultCategories := []struct {
-		Name string
 		Type string
 		Icon string
 	}{
-		{"餐饮", "expense", "food"},
-		{"购物", "expense", "shopping"},
-		{"交通", "expense", "transport"},
-		{"住房", "expense", "home"},
-		{"工资", "income", "salary"},
-		{"奖金", "income", "bonus"},
-		{"投资", "income", "investment"},
+		{"expense", "food"},
+		{"expense", "shopping"},
+		{"expense", "transport"},
+		{"expense", "home"},
+		{"income", "salary"},
+		{"income", "bonus"},
+		{"income", "investment"},
 	}
 
 	for _, category := range defaultCategories {
-		_, err = tx.Exec(
+		_, err = txExec(ctx, tx, 
 			"INSERT INTO categories (user_id, name, type, icon) VALUES (?, ?, ?, ?)",
-			userID, category.Name, category.Type, category.Icon,
+			userID, i18n.T(lang, "category_"+category.Icon), category.Type, category.Icon,
 		)
 		if err != nil {
 			tx.Rollback()
@@ -139,22 +161,27 @@ func CreateUser(req *RegisterRequest) (*User, error) {
 		Username: req.Username,
 		Email:    req.Email,
 		Phone:    req.Phone,
+		Timezone: req.Timezone,
+		Language: req.Language,
 	}
 
 	return user, nil
 }
 
 // GetUserByID 通过ID获取用户
-func GetUserByID(id uint) (*User, error) {
+func GetUserByID(ctx context.Context, id uint) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	user := &User{}
-	err := DB.QueryRow(
-		"SELECT id, username, email, phone, avatar, created_at, updated_at FROM users WHERE id = ?",
+	err := dbQueryRow(ctx, 
+		"SELECT id, username, email, phone, avatar, timezone, language, created_at, updated_at FROM users WHERE id = ?",
 		id,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.Phone, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.Phone, &user.Avatar, &user.Timezone, &user.Language, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("用户不存在")
+			return nil, ErrUserNotFound
 		}
 		logs.Error("Error querying user by ID: %v", err)
 		return nil, err
@@ -163,39 +190,14 @@ func GetUserByID(id uint) (*User, error) {
 	return user, nil
 }
 
-// AuthenticateUser 验证用户凭据
-func AuthenticateUser(login *LoginRequest) (*User, error) {
-	user := &User{}
-	var hashedPassword string
-
-	// 支持用户名或邮箱登录
-	err := DB.QueryRow(
-		"SELECT id, username, email, password, phone, avatar, created_at, updated_at FROM users WHERE username = ? OR email = ?",
-		login.Username, login.Username,
-	).Scan(&user.ID, &user.Username, &user.Email, &hashedPassword, &user.Phone, &user.Avatar, &user.CreatedAt, &user.UpdatedAt)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("用户不存在")
-		}
-		logs.Error("Error querying user for authentication: %v", err)
-		return nil, err
-	}
-
-	// 验证密码
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(login.Password))
-	if err != nil {
-		return nil, errors.New("密码错误")
-	}
-
-	return user, nil
-}
-
 // UpdateUser 更新用户信息
-func UpdateUser(id uint, username, email, phone, avatar string) error {
-	_, err := DB.Exec(
-		"UPDATE users SET username = ?, email = ?, phone = ?, avatar = ? WHERE id = ?",
-		username, email, phone, avatar, id,
+func UpdateUser(ctx context.Context, id uint, profile *UserProfileResponse) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := dbExec(ctx, 
+		"UPDATE users SET username = ?, email = ?, phone = ?, avatar = ?, timezone = ?, language = ? WHERE id = ?",
+		profile.Username, profile.Email, profile.Phone, profile.Avatar, profile.Timezone, profile.Language, id,
 	)
 	
 	if err != nil {
@@ -207,11 +209,14 @@ func UpdateUser(id uint, username, email, phone, avatar string) error {
 }
 
 // UpdatePassword 更新用户密码
-func UpdatePassword(id uint, oldPassword, newPassword string) error {
+func UpdatePassword(ctx context.Context, id uint, oldPassword, newPassword string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var hashedPassword string
 	
 	// 获取当前密码
-	err := DB.QueryRow("SELECT password FROM users WHERE id = ?", id).Scan(&hashedPassword)
+	err := dbQueryRow(ctx, "SELECT password FROM users WHERE id = ?", id).Scan(&hashedPassword)
 	if err != nil {
 		logs.Error("Error getting current password: %v", err)
 		return err
@@ -220,7 +225,7 @@ func UpdatePassword(id uint, oldPassword, newPassword string) error {
 	// 验证旧密码
 	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(oldPassword))
 	if err != nil {
-		return errors.New("原密码错误")
+		return ErrWrongPassword
 	}
 	
 	// 加密新密码
@@ -231,7 +236,7 @@ func UpdatePassword(id uint, oldPassword, newPassword string) error {
 	}
 	
 	// 更新密码
-	_, err = DB.Exec("UPDATE users SET password = ? WHERE id = ?", newHashedPassword, id)
+	_, err = dbExec(ctx, "UPDATE users SET password = ? WHERE id = ?", newHashedPassword, id)
 	if err != nil {
 		logs.Error("Error updating password: %v", err)
 		return err
@@ -241,16 +246,19 @@ func UpdatePassword(id uint, oldPassword, newPassword string) error {
 }
 
 // ResetPassword 重置密码（忘记密码功能）
-func ResetPassword(email, newPassword string) error {
+func ResetPassword(ctx context.Context, email, newPassword string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// 检查邮箱是否存在
 	var exists bool
-	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", email).Scan(&exists)
+	err := dbQueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", email).Scan(&exists)
 	if err != nil {
 		logs.Error("Error checking email existence: %v", err)
 		return err
 	}
 	if !exists {
-		return errors.New("邮箱不存在")
+		return ErrEmailNotFound
 	}
 	
 	// 加密新密码
@@ -261,11 +269,29 @@ func ResetPassword(email, newPassword string) error {
 	}
 	
 	// 更新密码
-	_, err = DB.Exec("UPDATE users SET password = ? WHERE email = ?", hashedPassword, email)
+	_, err = dbExec(ctx, "UPDATE users SET password = ? WHERE email = ?", hashedPassword, email)
 	if err != nil {
 		logs.Error("Error resetting password: %v", err)
 		return err
 	}
 	
 	return nil
-} 
\ No newline at end of file
+}
+
+// UserLanguage 查询用户的语言偏好，未设置时返回空字符串
+func UserLanguage(ctx context.Context, id uint) (string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var language string
+	err := dbQueryRow(ctx, "SELECT language FROM users WHERE id = ?", id).Scan(&language)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrUserNotFound
+		}
+		logs.Error("Error querying user language: %v", err)
+		return "", err
+	}
+
+	return language, nil
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"blog/i18n"
+
+	"github.com/beego/beego/v2/core/validation"
+)
+
+// 允许的日期范围
+const (
+	minValidYear = 1900
+	maxValidYear = 2100
+)
+
+func init() {
+	// 自定义校验规则，可在valid标签中直接使用，错误信息为i18n消息码
+	validation.AddCustomFunc("Money", validateMoney)
+	validation.AddCustomFunc("Date", validateDate)
+	validation.AddCustomFunc("Month", validateMonth)
+	validation.AddCustomFunc("DateTime", validateDateTime)
+	validation.AddCustomFunc("Timezone", validateTimezone)
+	validation.AddCustomFunc("Language", validateLanguage)
+	validation.AddCustomFunc("DateRange", validateDateRange)
+}
+
+// validateMoney 金额必须为正数且不超过上限，小数位数在JSON解析时已经校验
+func validateMoney(v *validation.Validation, obj interface{}, key string) {
+	amount, ok := obj.(Money)
+	if !ok {
+		v.AddError(key, "money_invalid")
+		return
+	}
+	if amount <= 0 || amount > MaxAmount {
+		v.AddError(key, "money_out_of_range")
+	}
+}
+
+// validateDate 日期格式为YYYY-MM-DD且在合理范围内，空值交给Required处理
+func validateDate(v *validation.Validation, obj interface{}, key string) {
+	validateTimeString(v, obj, key, "2006-01-02", "date_invalid")
+}
+
+// validateMonth 月份格式为YYYY-MM且在合理范围内
+func validateMonth(v *validation.Validation, obj interface{}, key string) {
+	validateTimeString(v, obj, key, "2006-01", "month_invalid")
+}
+
+// validateDateTime 日期为YYYY-MM-DD或RFC3339时间戳
+func validateDateTime(v *validation.Validation, obj interface{}, key string) {
+	s, ok := obj.(string)
+	if ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			if t.Year() < minValidYear || t.Year() > maxValidYear {
+				v.AddError(key, "date_out_of_range")
+			}
+			return
+		}
+	}
+	validateTimeString(v, obj, key, "2006-01-02", "datetime_invalid")
+}
+
+// validateTimezone 时区必须是有效的IANA名称，如Asia/Shanghai
+func validateTimezone(v *validation.Validation, obj interface{}, key string) {
+	s, ok := obj.(string)
+	if !ok || s == "" {
+		return
+	}
+	if _, err := time.LoadLocation(s); err != nil || s == "Local" {
+		v.AddError(key, "timezone_invalid")
+	}
+}
+
+// validateLanguage 语言必须是支持的语言之一
+func validateLanguage(v *validation.Validation, obj interface{}, key string) {
+	s, ok := obj.(string)
+	if !ok || s == "" {
+		return
+	}
+	if i18n.Supported(s) != s {
+		v.AddError(key, "language_invalid")
+	}
+}
+
+// validateDateRange 相对日期范围必须是支持的名称之一，如last_30_days
+func validateDateRange(v *validation.Validation, obj interface{}, key string) {
+	s, ok := obj.(string)
+	if !ok || s == "" {
+		return
+	}
+	if !IsRelativeRange(s) {
+		v.AddError(key, "date_range_unknown")
+	}
+}
+
+func validateTimeString(v *validation.Validation, obj interface{}, key, layout, message string) {
+	s, ok := obj.(string)
+	if !ok {
+		v.AddError(key, message)
+		return
+	}
+	if s == "" {
+		return
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		v.AddError(key, message)
+		return
+	}
+	if t.Year() < minValidYear || t.Year() > maxValidYear {
+		v.AddError(key, "date_out_of_range")
+	}
+}
+
+// Valid 校验标签数量和长度
+func (r *BillRequest) Valid(v *validation.Validation) {
+	if len(r.Tags) > MaxBillTags {
+		v.AddError("Tags.Tags.", "tags_too_many")
+	}
+	for _, tag := range r.Tags {
+		if n := utf8.RuneCountInString(strings.TrimSpace(tag)); n > MaxTagLength {
+			v.AddError("Tags.Tags.", "tag_too_long")
+			break
+		}
+	}
+}
+
+// Valid 结转上限不能为负数，组合预算必须有名称且不能同时指定单个分类，周期必须指定开始，自定义周期还必须指定不早于开始的结束日期
+func (r *BudgetRequest) Valid(v *validation.Validation) {
+	if r.RolloverCap < 0 || r.RolloverCap > MaxAmount {
+		v.AddError("RolloverCap.Money.", "money_out_of_range")
+	}
+	if r.isGroup() {
+		if r.CategoryID > 0 {
+			v.AddError("CategoryID.Scope.", "budget_scope_conflict")
+		}
+		if strings.TrimSpace(r.Name) == "" {
+			v.AddError("Name.Required.", "budget_name_required")
+		}
+		if len(r.CategoryIDs) > maxBudgetCategories {
+			v.AddError("CategoryIDs.MaxSize.", "budget_categories_too_many")
+		}
+		if len(r.Tags) > MaxBillTags {
+			v.AddError("Tags.Tags.", "tags_too_many")
+		}
+		for _, tag := range r.Tags {
+			if n := utf8.RuneCountInString(strings.TrimSpace(tag)); n > MaxTagLength {
+				v.AddError("Tags.Tags.", "tag_too_long")
+				break
+			}
+		}
+	}
+	if r.Month == "" && r.StartDate == "" {
+		v.AddError("Month.Required.", "budget_start_required")
+		return
+	}
+	if r.Period != BudgetCustom {
+		return
+	}
+	start := r.StartDate
+	if start == "" {
+		start = r.Month + "-01"
+	}
+	if r.EndDate == "" {
+		v.AddError("EndDate.Required.", "budget_end_required")
+	} else if r.EndDate < start {
+		v.AddError("EndDate.DateRange.", "date_range_invalid")
+	}
+}
+
+// Valid 校验查询参数之间的范围关系
+func (p *BillQueryParams) Valid(v *validation.Validation) {
+	if p.StartDate != "" && p.EndDate != "" && p.StartDate > p.EndDate {
+		v.AddError("EndDate.DateRange.", "date_range_invalid")
+	}
+	if p.MinAmount < 0 {
+		v.AddError("MinAmount.Min.", "min_amount_negative")
+	}
+	if p.MaxAmount < 0 {
+		v.AddError("MaxAmount.Min.", "max_amount_negative")
+	}
+	if p.MinAmount > 0 && p.MaxAmount > 0 && p.MinAmount > p.MaxAmount {
+		v.AddError("MaxAmount.AmountRange.", "amount_range_invalid")
+	}
+}
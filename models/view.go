@@ -0,0 +1,258 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/core/validation"
+)
+
+// ViewFilter 保存的账单筛选条件
+//
+// DateRange为相对日期范围（如last_30_days、this_quarter），每次执行时按用户时区重新计算，
+// 设置后不能同时指定StartDate和EndDate。
+type ViewFilter struct {
+	DateRange  string `json:"date_range,omitempty" valid:"DateRange"`
+	StartDate  string `json:"start_date,omitempty" valid:"Date"`
+	EndDate    string `json:"end_date,omitempty" valid:"Date"`
+	Type       string `json:"type,omitempty" valid:"Match(/^(income|expense)?$/)"`
+	CategoryID uint   `json:"category_id,omitempty"`
+	MinAmount  Money  `json:"min_amount,omitempty"`
+	MaxAmount  Money  `json:"max_amount,omitempty"`
+	Q          string `json:"q,omitempty" valid:"MaxSize(100)"`
+	Sort       string `json:"sort,omitempty" valid:"Match(/^(-?(date|amount|created_at))?$/)"`
+}
+
+// SavedView 保存的账单视图
+type SavedView struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	Name      string     `json:"name"`
+	Filter    ViewFilter `json:"filter"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ViewRequest 视图请求参数
+type ViewRequest struct {
+	Name   string     `json:"name" valid:"Required;MaxSize(50)"`
+	Filter ViewFilter `json:"filter"`
+}
+
+// Valid 校验相对日期与固定日期不能同时使用，以及日期、金额范围
+func (f *ViewFilter) Valid(v *validation.Validation) {
+	if f.DateRange != "" && (f.StartDate != "" || f.EndDate != "") {
+		v.AddError("DateRange.DateRange.", "date_range_conflict")
+		return
+	}
+	params := f.Params(Today(DefaultLocation))
+	params.Valid(v)
+}
+
+// Params 转换为账单查询参数，相对日期范围基于用户时区下的当天日期计算
+func (f *ViewFilter) Params(today time.Time) *BillQueryParams {
+	params := &BillQueryParams{
+		StartDate:  f.StartDate,
+		EndDate:    f.EndDate,
+		Type:       f.Type,
+		CategoryID: f.CategoryID,
+		MinAmount:  f.MinAmount,
+		MaxAmount:  f.MaxAmount,
+		Q:          f.Q,
+		Sort:       f.Sort,
+	}
+	if start, end, ok := ResolveDateRange(f.DateRange, today); ok {
+		params.StartDate, params.EndDate = start, end
+	}
+	return params
+}
+
+// GetViews 获取用户的所有视图
+func GetViews(ctx context.Context, userID uint) ([]*SavedView, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx,
+		"SELECT id, user_id, name, filter, created_at, updated_at FROM saved_views WHERE user_id = ? ORDER BY name",
+		userID,
+	)
+	if err != nil {
+		logs.Error("Error querying views: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]*SavedView, 0)
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating view rows: %v", err)
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// GetView 获取单个视图
+func GetView(ctx context.Context, id, userID uint) (*SavedView, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	view, err := scanView(dbQueryRow(ctx,
+		"SELECT id, user_id, name, filter, created_at, updated_at FROM saved_views WHERE id = ? AND user_id = ?",
+		id, userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrViewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// scanView 读取一行视图数据
+func scanView(row interface{ Scan(...interface{}) error }) (*SavedView, error) {
+	view := &SavedView{}
+	var filter string
+	err := row.Scan(&view.ID, &view.UserID, &view.Name, &filter, &view.CreatedAt, &view.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		logs.Error("Error scanning view row: %v", err)
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(filter), &view.Filter); err != nil {
+		logs.Error("Error decoding filter of view %d: %v", view.ID, err)
+		return nil, err
+	}
+
+	return view, nil
+}
+
+// CreateView 创建视图
+func CreateView(ctx context.Context, userID uint, req *ViewRequest) (*SavedView, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if err := checkFilterCategory(ctx, userID, &req.Filter); err != nil {
+		return nil, err
+	}
+
+	filter, err := json.Marshal(req.Filter)
+	if err != nil {
+		logs.Error("Error encoding view filter: %v", err)
+		return nil, err
+	}
+
+	result, err := dbExec(ctx,
+		"INSERT INTO saved_views (user_id, name, filter) VALUES (?, ?, ?)",
+		userID, req.Name, string(filter),
+	)
+	if isDuplicateEntry(err) {
+		return nil, ErrViewExists
+	}
+	if err != nil {
+		logs.Error("Error creating view: %v", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logs.Error("Error getting view ID: %v", err)
+		return nil, err
+	}
+
+	return GetView(ctx, uint(id), userID)
+}
+
+// UpdateView 更新视图
+func UpdateView(ctx context.Context, id, userID uint, req *ViewRequest) (*SavedView, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := GetView(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	if err := checkFilterCategory(ctx, userID, &req.Filter); err != nil {
+		return nil, err
+	}
+
+	filter, err := json.Marshal(req.Filter)
+	if err != nil {
+		logs.Error("Error encoding view filter: %v", err)
+		return nil, err
+	}
+
+	_, err = dbExec(ctx,
+		"UPDATE saved_views SET name = ?, filter = ? WHERE id = ? AND user_id = ?",
+		req.Name, string(filter), id, userID,
+	)
+	if isDuplicateEntry(err) {
+		return nil, ErrViewExists
+	}
+	if err != nil {
+		logs.Error("Error updating view: %v", err)
+		return nil, err
+	}
+
+	return GetView(ctx, id, userID)
+}
+
+// DeleteView 删除视图
+func DeleteView(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx, "DELETE FROM saved_views WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		logs.Error("Error deleting view: %v", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrViewNotFound
+	}
+
+	return nil
+}
+
+// checkFilterCategory 筛选条件中的分类必须属于该用户
+func checkFilterCategory(ctx context.Context, userID uint, filter *ViewFilter) error {
+	if filter.CategoryID == 0 {
+		return nil
+	}
+
+	var exists bool
+	err := dbQueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM categories WHERE id = ? AND user_id = ?)",
+		filter.CategoryID, userID,
+	).Scan(&exists)
+	if err != nil {
+		logs.Error("Error checking category: %v", err)
+		return err
+	}
+	if !exists {
+		return ErrCategoryNotOwned
+	}
+
+	return nil
+}
@@ -0,0 +1,474 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// Webhook投递状态
+const (
+	WebhookPending   = "pending"
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed"
+)
+
+// 每个用户最多注册的Webhook数
+const maxWebhooksPerUser = 10
+
+// Webhook 用户注册的事件回调地址
+type Webhook struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret"` // 签名密钥，接收方用它校验X-FinWise-Signature
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookRequest Webhook请求参数
+type WebhookRequest struct {
+	URL      string   `json:"url" valid:"Required;MaxSize(500)"`
+	Events   []string `json:"events" valid:"Required"`
+	IsActive bool     `json:"is_active"`
+}
+
+// WebhookDelivery Webhook投递记录，每次重新投递都会生成新的记录
+type WebhookDelivery struct {
+	ID            uint            `json:"id"`
+	WebhookID     uint            `json:"webhook_id"`
+	UserID        uint            `json:"-"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	ResponseBody  string          `json:"response_body,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// validateWebhookURL 只允许http和https地址
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrWebhookURLInvalid
+	}
+	return nil
+}
+
+// GetWebhooks 获取用户的Webhook
+func GetWebhooks(ctx context.Context, userID uint) ([]*Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT id, user_id, url, events, secret, is_active, created_at, updated_at
+		FROM webhooks
+		WHERE user_id = ?
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		logs.Error("Error querying webhooks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]*Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating webhook rows: %v", err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// scanWebhook 读取一行Webhook数据，事件列表以逗号分隔保存
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*Webhook, error) {
+	webhook := &Webhook{}
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &events, &webhook.Secret,
+		&webhook.IsActive, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logs.Error("Error scanning webhook row: %v", err)
+		}
+		return nil, err
+	}
+	webhook.Events = strings.Split(events, ",")
+	return webhook, nil
+}
+
+// GetWebhook 获取单个Webhook
+func GetWebhook(ctx context.Context, id, userID uint) (*Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	webhook, err := scanWebhook(dbQueryRow(ctx, `
+		SELECT id, user_id, url, events, secret, is_active, created_at, updated_at
+		FROM webhooks
+		WHERE id = ? AND user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// GetSubscribedWebhooks 获取用户已启用且订阅了该事件的Webhook
+func GetSubscribedWebhooks(ctx context.Context, userID uint, event string) ([]*Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT id, user_id, url, events, secret, is_active, created_at, updated_at
+		FROM webhooks
+		WHERE user_id = ? AND is_active = TRUE AND FIND_IN_SET(?, events) > 0
+		ORDER BY id
+	`, userID, event)
+	if err != nil {
+		logs.Error("Error querying subscribed webhooks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]*Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating webhook rows: %v", err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// CreateWebhook 注册Webhook并生成签名密钥，事件类型由调用方校验
+func CreateWebhook(ctx context.Context, userID uint, req *WebhookRequest) (*Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	var count int
+	err := dbQueryRow(ctx, "SELECT COUNT(*) FROM webhooks WHERE user_id = ?", userID).Scan(&count)
+	if err != nil {
+		logs.Error("Error counting webhooks: %v", err)
+		return nil, err
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, Invalid("webhook_limit", maxWebhooksPerUser)
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dbExec(ctx,
+		"INSERT INTO webhooks (user_id, url, events, secret, is_active) VALUES (?, ?, ?, ?, ?)",
+		userID, req.URL, strings.Join(req.Events, ","), secret, req.IsActive,
+	)
+	if err != nil {
+		logs.Error("Error creating webhook: %v", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logs.Error("Error getting webhook ID: %v", err)
+		return nil, err
+	}
+
+	return GetWebhook(ctx, uint(id), userID)
+}
+
+// UpdateWebhook 修改Webhook的地址、事件和启用状态，签名密钥不变
+func UpdateWebhook(ctx context.Context, id, userID uint, req *WebhookRequest) (*Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	if _, err := GetWebhook(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	_, err := dbExec(ctx,
+		"UPDATE webhooks SET url = ?, events = ?, is_active = ? WHERE id = ? AND user_id = ?",
+		req.URL, strings.Join(req.Events, ","), req.IsActive, id, userID,
+	)
+	if err != nil {
+		logs.Error("Error updating webhook: %v", err)
+		return nil, err
+	}
+
+	return GetWebhook(ctx, id, userID)
+}
+
+// DeleteWebhook 删除Webhook及其投递记录
+func DeleteWebhook(ctx context.Context, id, userID uint) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := dbExec(ctx, "DELETE FROM webhooks WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		logs.Error("Error deleting webhook: %v", err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return err
+	}
+	if affected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// webhookDeliveryColumns 投递记录的查询列，与scanWebhookDelivery对应
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.user_id, d.event, d.payload, d.status, d.attempts,
+	d.response_code, d.response_body, d.error, d.next_attempt_at, d.created_at, d.updated_at`
+
+// scanWebhookDelivery 读取一行投递记录，之后可以跟随额外的列
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	var payload string
+	var nextAttemptAt sql.NullTime
+	dest := append([]interface{}{
+		&d.ID, &d.WebhookID, &d.UserID, &d.Event, &payload, &d.Status, &d.Attempts,
+		&d.ResponseCode, &d.ResponseBody, &d.Error, &nextAttemptAt, &d.CreatedAt, &d.UpdatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if err != sql.ErrNoRows {
+			logs.Error("Error scanning webhook delivery row: %v", err)
+		}
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	return d, nil
+}
+
+// CreateWebhookDelivery 创建待投递记录，payload为签名发送的原始内容
+func CreateWebhookDelivery(ctx context.Context, webhook *Webhook, event string, payload []byte) (*WebhookDelivery, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	result, err := dbExec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, user_id, event, payload, status, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, webhook.ID, webhook.UserID, event, string(payload), WebhookPending, now, now, now)
+	if err != nil {
+		logs.Error("Error creating webhook delivery: %v", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logs.Error("Error getting webhook delivery ID: %v", err)
+		return nil, err
+	}
+
+	return &WebhookDelivery{
+		ID:            uint(id),
+		WebhookID:     webhook.ID,
+		UserID:        webhook.UserID,
+		Event:         event,
+		Payload:       payload,
+		Status:        WebhookPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}, nil
+}
+
+// ClaimWebhookDelivery 开始一次投递：增加尝试次数并把下次尝试时间推迟lease，
+// 多个实例同时处理时只有一个能占用；投递中途退出的记录在lease之后会被重试
+func ClaimWebhookDelivery(ctx context.Context, d *WebhookDelivery, lease time.Duration) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	result, err := dbExec(ctx, `
+		UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND status = ? AND attempts = ?
+	`, now.Add(lease), now, d.ID, WebhookPending, d.Attempts)
+	if err != nil {
+		logs.Error("Error claiming webhook delivery: %v", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logs.Error("Error getting affected rows: %v", err)
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	d.Attempts++
+	return true, nil
+}
+
+// RecordWebhookAttempt 保存投递结果，d的状态、响应和下次尝试时间由调用方设置
+func RecordWebhookAttempt(ctx context.Context, d *WebhookDelivery) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	d.UpdatedAt = time.Now()
+	_, err := dbExec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, response_code = ?, response_body = ?, error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`, d.Status, d.ResponseCode, d.ResponseBody, d.Error, d.NextAttemptAt, d.UpdatedAt, d.ID)
+	if err != nil {
+		logs.Error("Error recording webhook attempt: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetDueWebhookDeliveries 获取到达重试时间的投递记录及其Webhook，已禁用或删除的Webhook不再重试
+func GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, []*Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := dbQuery(ctx, `
+		SELECT `+webhookDeliveryColumns+`, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.status = ? AND d.next_attempt_at <= ? AND w.is_active = TRUE
+		ORDER BY d.next_attempt_at
+		LIMIT ?
+	`, WebhookPending, now, limit)
+	if err != nil {
+		logs.Error("Error querying due webhook deliveries: %v", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*WebhookDelivery, 0)
+	webhooks := make([]*Webhook, 0)
+	for rows.Next() {
+		webhook := &Webhook{}
+		d, err := scanWebhookDelivery(rows, &webhook.URL, &webhook.Secret)
+		if err != nil {
+			return nil, nil, err
+		}
+		webhook.ID = d.WebhookID
+		webhook.UserID = d.UserID
+		webhook.IsActive = true
+		deliveries = append(deliveries, d)
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating webhook delivery rows: %v", err)
+		return nil, nil, err
+	}
+
+	return deliveries, webhooks, nil
+}
+
+// GetWebhookDeliveries 获取Webhook最近的投递记录
+func GetWebhookDeliveries(ctx context.Context, webhookID, userID uint, limit int) ([]*WebhookDelivery, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := GetWebhook(ctx, webhookID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := dbQuery(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = ? AND d.user_id = ?
+		ORDER BY d.id DESC
+		LIMIT ?
+	`, webhookID, userID, limit)
+	if err != nil {
+		logs.Error("Error querying webhook deliveries: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.Error("Error iterating webhook delivery rows: %v", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RedeliverWebhookDelivery 以原投递的事件和内容创建一条新的待投递记录，原记录保持不变
+func RedeliverWebhookDelivery(ctx context.Context, id, userID uint) (*WebhookDelivery, *Webhook, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	original, err := scanWebhookDelivery(dbQueryRow(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d
+		WHERE d.id = ? AND d.user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	webhook, err := GetWebhook(ctx, original.WebhookID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d, err := CreateWebhookDelivery(ctx, webhook, original.Event, original.Payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, webhook, nil
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"blog/i18n"
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 预算告警通知的重试策略
+const (
+	AlertMaxAttempts     = 6
+	AlertBaseDelay       = time.Minute
+	AlertMaxDelay        = time.Hour
+	DefaultRetryInterval = time.Minute // 重试检查的默认间隔
+
+	alertClaimLease = 2 * deliverTimeout // 大于单个渠道的投递超时，投递中途退出的记录在此之后重试
+	alertBatchSize  = 100
+)
+
+// CheckBudgetAlerts 检查各月份的预算告警，新达到的阈值在后台通知到所有已注册的渠道
+//
+// 在账单、预算或告警变更后调用；检查失败只记录日志，不影响调用方。
+// 每个渠道的投递状态随触发记录保存，失败或进程退出未完成的投递由重试循环补发。
+func CheckBudgetAlerts(ctx context.Context, userID uint, months ...string) {
+	seen := make(map[string]bool, len(months))
+	for _, month := range months {
+		if month == "" || seen[month] {
+			continue
+		}
+		seen[month] = true
+
+		events, err := models.EvaluateBudgetAlerts(ctx, userID, month, Default.ChannelNames())
+		if err != nil {
+			logs.Error("Error evaluating budget alerts of user %d for %s: %v", userID, month, err)
+			continue
+		}
+		for _, event := range events {
+			go Default.DeliverAlert(context.Background(), event)
+		}
+	}
+}
+
+// CheckBudget 检查预算周期开始月份的告警，用于告警创建或修改后
+func CheckBudget(ctx context.Context, userID, budgetID uint) {
+	budget, err := models.GetBudget(ctx, budgetID, userID)
+	if err != nil {
+		logs.Error("Error getting budget %d for alert check: %v", budgetID, err)
+		return
+	}
+	CheckBudgetAlerts(ctx, userID, budget.Month.Format("2006-01"))
+}
+
+// BudgetAlertEvent 由告警触发记录生成事件，100%阈值为budget.exceeded
+func BudgetAlertEvent(event *models.BudgetAlertEvent) *Event {
+	eventType := EventBudgetThreshold
+	if event.Exceeded() {
+		eventType = EventBudgetExceeded
+	}
+	e := NewEvent(eventType, event.UserID, event)
+	e.CreatedAt = event.TriggeredAt
+	return e
+}
+
+// Message 生成事件的通知标题和正文，未知事件类型返回false
+func Message(lang string, e *Event) (string, string, bool) {
+	switch e.Type {
+	case EventBudgetThreshold, EventBudgetExceeded:
+		event, ok := e.Data.(*models.BudgetAlertEvent)
+		if !ok {
+			return "", "", false
+		}
+		name := event.CategoryName
+		if name == "" {
+			name = i18n.T(lang, "statement_total_budget")
+		}
+		if e.Type == EventBudgetExceeded {
+			return i18n.T(lang, "notify_budget_exceeded_subject", name, event.Period()),
+				i18n.T(lang, "notify_budget_exceeded_body", name, event.Period(), event.UsedAmount, event.BudgetAmount, event.UsedPercent),
+				true
+		}
+		return i18n.T(lang, "notify_budget_threshold_subject", name, event.Threshold),
+			i18n.T(lang, "notify_budget_threshold_body", name, event.Period(), event.Threshold, event.UsedAmount, event.BudgetAmount, event.UsedPercent),
+			true
+	}
+	return "", "", false
+}
+
+// AlertBackoff 第attempt次投递失败后等待的时间，从AlertBaseDelay开始每次翻倍，不超过AlertMaxDelay
+func AlertBackoff(attempt int) time.Duration {
+	delay := AlertBaseDelay
+	for i := 1; i < attempt && delay < AlertMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > AlertMaxDelay {
+		delay = AlertMaxDelay
+	}
+	return delay
+}
+
+// DeliverAlert 按新触发记录的投递记录逐个渠道投递
+func (d *Dispatcher) DeliverAlert(ctx context.Context, event *models.BudgetAlertEvent) {
+	e := BudgetAlertEvent(event)
+	for _, delivery := range event.Deliveries {
+		if err := d.attemptAlert(ctx, e, delivery); err != nil {
+			logs.Error("Error attempting alert delivery %d: %v", delivery.ID, err)
+		}
+	}
+}
+
+// RetryAlerts 重试所有到期的预算告警投递，返回本次投递成功的数量
+func (d *Dispatcher) RetryAlerts(ctx context.Context) (int, error) {
+	deliveries, err := models.GetDueAlertDeliveries(ctx, time.Now(), alertBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	events := make(map[uint]*Event)
+	succeeded := 0
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return succeeded, ctx.Err()
+		}
+		e, ok := events[delivery.EventID]
+		if !ok {
+			event, err := models.GetBudgetAlertEvent(ctx, delivery.EventID)
+			if err != nil {
+				logs.Error("Error loading alert event %d for retry: %v", delivery.EventID, err)
+				continue
+			}
+			e = BudgetAlertEvent(event)
+			events[delivery.EventID] = e
+		}
+		if err := d.attemptAlert(ctx, e, delivery); err != nil {
+			logs.Error("Error attempting alert delivery %d: %v", delivery.ID, err)
+			continue
+		}
+		if delivery.Status == models.AlertDeliverySucceeded {
+			succeeded++
+		}
+	}
+	return succeeded, nil
+}
+
+// attemptAlert 占用投递记录后通过对应渠道投递一次并保存结果，记录已被其他实例占用时直接返回
+//
+// 失败且未达到最大尝试次数时按AlertBackoff安排下次重试；渠道已不再启用时直接标记为失败。
+func (d *Dispatcher) attemptAlert(ctx context.Context, e *Event, delivery *models.AlertDelivery) error {
+	claimed, err := models.ClaimAlertDelivery(ctx, delivery, alertClaimLease)
+	if err != nil || !claimed {
+		return err
+	}
+
+	ch := d.channel(delivery.Channel)
+	var deliverErr error
+	if ch == nil {
+		deliverErr = fmt.Errorf("channel %q is not enabled", delivery.Channel)
+	} else {
+		chCtx, cancel := context.WithTimeout(ctx, deliverTimeout)
+		deliverErr = ch.Deliver(chCtx, e)
+		cancel()
+	}
+
+	delivery.Error = ""
+	delivery.NextAttemptAt = nil
+	switch {
+	case deliverErr == nil:
+		delivery.Status = models.AlertDeliverySucceeded
+	case ch == nil || delivery.Attempts >= AlertMaxAttempts:
+		delivery.Status = models.AlertDeliveryFailed
+	default:
+		delivery.Status = models.AlertDeliveryPending
+		next := time.Now().Add(AlertBackoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if deliverErr != nil {
+		logs.Error("Error delivering %s to user %d via %s: %v", e.Type, e.UserID, delivery.Channel, deliverErr)
+		delivery.Error = truncate(deliverErr.Error(), 255)
+	}
+
+	return models.RecordAlertDelivery(context.Background(), delivery)
+}
+
+// startAlertRetries 按notifyretryinterval配置定期重试预算告警投递
+func startAlertRetries() {
+	interval := DefaultRetryInterval
+	if v, _ := web.AppConfig.String("notifyretryinterval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logs.Error("Invalid notifyretryinterval %q, using %v", v, DefaultRetryInterval)
+		} else {
+			interval = d
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := Default.RetryAlerts(context.Background()); err != nil {
+				logs.Error("Error retrying alert deliveries: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// truncate 按字节截断，不拆开多字节字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+
+	"blog/i18n"
+	"blog/mail"
+	"blog/models"
+)
+
+// EmailChannel 通过邮件通知用户，使用mail.Default发送
+type EmailChannel struct{}
+
+// Name 渠道名称
+func (*EmailChannel) Name() string {
+	return "email"
+}
+
+// Deliver 按用户语言生成邮件并发送，没有对应文案的事件类型不发送
+func (*EmailChannel) Deliver(ctx context.Context, e *Event) error {
+	if _, _, ok := Message(i18n.Default, e); !ok {
+		return nil
+	}
+
+	user, err := models.GetUserByID(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+
+	lang := user.Language
+	if lang == "" {
+		lang = i18n.Default
+	}
+	subject, body, _ := Message(lang, e)
+
+	return mail.Send(ctx, &mail.Message{
+		To:      []string{user.Email},
+		Subject: subject,
+		Body:    body,
+	})
+}
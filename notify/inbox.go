@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"blog/i18n"
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 站内通知保留策略默认值
+const (
+	DefaultRetentionDays = 90
+	purgeInterval        = time.Hour
+)
+
+// InboxChannel 把事件保存为站内通知
+type InboxChannel struct{}
+
+// Name 渠道名称
+func (*InboxChannel) Name() string {
+	return "inbox"
+}
+
+// Deliver 按用户语言生成通知标题和正文，事件数据作为通知的payload，没有对应文案的事件类型不保存
+func (*InboxChannel) Deliver(ctx context.Context, e *Event) error {
+	if _, _, ok := Message(i18n.Default, e); !ok {
+		return nil
+	}
+
+	lang, err := models.UserLanguage(ctx, e.UserID)
+	if err != nil {
+		return err
+	}
+	if lang == "" {
+		lang = i18n.Default
+	}
+	title, body, _ := Message(lang, e)
+
+	_, err = models.CreateNotification(ctx, e.UserID, e.Type, title, body, e.Data)
+	return err
+}
+
+// startRetention 按notificationretentiondays配置定期删除过期的站内通知，配置为0时永久保留
+func startRetention() {
+	days := web.AppConfig.DefaultInt("notificationretentiondays", DefaultRetentionDays)
+	if days <= 0 {
+		return
+	}
+	retention := time.Duration(days) * 24 * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			purged, err := models.PurgeNotifications(context.Background(), time.Now().Add(-retention))
+			if err != nil {
+				logs.Error("Error purging notifications: %v", err)
+			} else if purged > 0 {
+				logs.Info("Purged %d notifications older than %d days", purged, days)
+			}
+			<-ticker.C
+		}
+	}()
+}
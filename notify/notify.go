@@ -0,0 +1,171 @@
+// Package notify 把业务事件投递到通知渠道（邮件、站内信、实时推送、Webhook等）
+//
+// 各渠道实现Channel接口并注册到Dispatcher，notifychannels配置决定默认启用哪些渠道。
+package notify
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 事件类型
+const (
+	EventBillCreated     = "bill.created"
+	EventBillUpdated     = "bill.updated"
+	EventBillDeleted     = "bill.deleted"
+	EventBudgetCreated   = "budget.created"
+	EventBudgetUpdated   = "budget.updated"
+	EventBudgetDeleted   = "budget.deleted"
+	EventAlertCreated    = "budget_alert.created"
+	EventAlertUpdated    = "budget_alert.updated"
+	EventAlertDeleted    = "budget_alert.deleted"
+	EventBudgetThreshold = "budget.threshold_crossed"
+	EventBudgetExceeded  = "budget.exceeded"
+)
+
+// EventTypes 可以通过Webhook订阅的事件类型
+var EventTypes = []string{
+	EventBillCreated, EventBillUpdated, EventBillDeleted,
+	EventBudgetCreated, EventBudgetUpdated, EventBudgetDeleted,
+	EventAlertCreated, EventAlertUpdated, EventAlertDeleted,
+	EventBudgetThreshold, EventBudgetExceeded,
+}
+
+// IsEventType 是否为已知的事件类型
+func IsEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverTimeout 单个渠道投递一个事件的超时
+const deliverTimeout = 30 * time.Second
+
+// Event 通知事件
+type Event struct {
+	Type      string      `json:"type"`
+	UserID    uint        `json:"user_id"`
+	Data      interface{} `json:"data"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// NewEvent 创建事件
+func NewEvent(eventType string, userID uint, data interface{}) *Event {
+	return &Event{
+		Type:      eventType,
+		UserID:    userID,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Channel 通知渠道
+type Channel interface {
+	Name() string
+	Deliver(ctx context.Context, e *Event) error
+}
+
+// Dispatcher 把事件分发到已注册的渠道
+type Dispatcher struct {
+	mu       sync.RWMutex
+	channels []Channel
+}
+
+// Default 默认的事件分发器
+var Default = &Dispatcher{}
+
+// factories 可通过notifychannels配置启用的渠道
+var factories = map[string]func() Channel{
+	"email":    func() Channel { return &EmailChannel{} },
+	"inbox":    func() Channel { return &InboxChannel{} },
+	"realtime": func() Channel { return &RealtimeChannel{} },
+	"webhook":  func() Channel { return &WebhookChannel{} },
+}
+
+// Init 按notifychannels配置（逗号分隔的渠道名）向默认分发器注册渠道，并启动站内通知的过期清理和预算告警通知的重试
+func Init() {
+	startRetention()
+
+	names, _ := web.AppConfig.String("notifychannels")
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		factory, ok := factories[name]
+		if !ok {
+			logs.Error("Unknown notify channel %q", name)
+			continue
+		}
+		Default.Register(factory())
+	}
+
+	startAlertRetries()
+}
+
+// Register 注册渠道
+func (d *Dispatcher) Register(ch Channel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.channels = append(d.channels, ch)
+}
+
+// ChannelNames 已注册渠道的名称
+func (d *Dispatcher) ChannelNames() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	names := make([]string, 0, len(d.channels))
+	for _, ch := range d.channels {
+		names = append(names, ch.Name())
+	}
+	return names
+}
+
+// channel 按名称查找已注册的渠道，未注册时返回nil
+func (d *Dispatcher) channel(name string) Channel {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, ch := range d.channels {
+		if ch.Name() == name {
+			return ch
+		}
+	}
+	return nil
+}
+
+// Deliver 同步投递事件到所有渠道，单个渠道失败不影响其他渠道，返回失败的渠道数
+func (d *Dispatcher) Deliver(ctx context.Context, e *Event) int {
+	d.mu.RLock()
+	channels := append([]Channel(nil), d.channels...)
+	d.mu.RUnlock()
+
+	failed := 0
+	for _, ch := range channels {
+		chCtx, cancel := context.WithTimeout(ctx, deliverTimeout)
+		err := ch.Deliver(chCtx, e)
+		cancel()
+		if err != nil {
+			logs.Error("Error delivering %s to user %d via %s: %v", e.Type, e.UserID, ch.Name(), err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// Publish 在后台投递事件，不阻塞调用方
+func (d *Dispatcher) Publish(e *Event) {
+	go d.Deliver(context.Background(), e)
+}
+
+// Publish 使用默认分发器在后台投递事件
+func Publish(e *Event) {
+	Default.Publish(e)
+}
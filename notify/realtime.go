@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"context"
+
+	"blog/realtime"
+)
+
+// RealtimeChannel 把所有事件推送到用户在线的连接，客户端据此刷新数据
+type RealtimeChannel struct{}
+
+// Name 渠道名称
+func (*RealtimeChannel) Name() string {
+	return "realtime"
+}
+
+// Deliver 通过realtime.Default推送事件，事件数据原样作为消息内容
+func (*RealtimeChannel) Deliver(ctx context.Context, e *Event) error {
+	return realtime.Default.Publish(ctx, e.UserID, e.Type, e.Data)
+}
@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"context"
+
+	"blog/webhook"
+)
+
+// WebhookChannel 把事件投递到用户注册的Webhook
+type WebhookChannel struct{}
+
+// Name 渠道名称
+func (*WebhookChannel) Name() string {
+	return "webhook"
+}
+
+// Deliver 为订阅了该事件的Webhook创建投递记录，请求在后台发送，失败后由webhook包重试
+func (*WebhookChannel) Deliver(ctx context.Context, e *Event) error {
+	return webhook.Default.Dispatch(ctx, e.UserID, e.Type, e.CreatedAt, e.Data)
+}
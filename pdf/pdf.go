@@ -0,0 +1,169 @@
+// Package pdf 生成简单的PDF文档，只支持文本、直线和填充矩形，足够输出报表类文档
+//
+// 文本统一使用PDF阅读器内置的STSong-Light中文字体（不嵌入字体文件），
+// 因此中英文都能显示而无需额外的字体资源。
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// A4纸张尺寸，单位为点(1/72英寸)
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+// Color RGB颜色，各分量取值0-1
+type Color struct {
+	R, G, B float64
+}
+
+// 常用颜色
+var (
+	Black     = Color{0, 0, 0}
+	Gray      = Color{0.5, 0.5, 0.5}
+	LightGray = Color{0.9, 0.9, 0.9}
+)
+
+// Document PDF文档
+type Document struct {
+	pages []*Page
+}
+
+// Page 文档中的一页，坐标原点在左上角
+type Page struct {
+	content bytes.Buffer
+}
+
+// New 创建空白文档
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage 添加一页A4纸
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Text 在(x, y)处输出一行文本，y为文本基线
+func (p *Page) Text(x, y, size float64, c Color, s string) {
+	fmt.Fprintf(&p.content, "BT %.3f %.3f %.3f rg /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n",
+		c.R, c.G, c.B, size, x, PageHeight-y, encodeText(s))
+}
+
+// TextRight 右对齐输出文本，x为文本右边界
+func (p *Page) TextRight(x, y, size float64, c Color, s string) {
+	p.Text(x-TextWidth(s, size), y, size, c, s)
+}
+
+// Rect 填充矩形，(x, y)为左上角
+func (p *Page) Rect(x, y, w, h float64, c Color) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		c.R, c.G, c.B, x, PageHeight-y-h, w, h)
+}
+
+// Line 画直线
+func (p *Page) Line(x1, y1, x2, y2, width float64, c Color) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f RG %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		c.R, c.G, c.B, width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// TextWidth 估算文本宽度，ASCII字符按半角、其余按全角计算
+func TextWidth(s string, size float64) float64 {
+	var width float64
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			width += size / 2
+		} else {
+			width += size
+		}
+	}
+	return width
+}
+
+// Truncate 截断文本使其宽度不超过maxWidth，截断时以省略号结尾
+func Truncate(s string, size, maxWidth float64) string {
+	if TextWidth(s, size) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && TextWidth(string(runes)+"…", size) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// encodeText 将文本编码为UCS-2大端十六进制串，超出基本多文种平面的字符以?代替
+func encodeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// WriteTo 输出PDF文件
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{}}
+	}
+
+	// 对象编号：1目录 2页面树 3字体 4后代字体 5字体描述，之后每页占用页面和内容两个对象
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // 页面树，页面编号确定后填充
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 4 >> " +
+			"/FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+			"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+	}
+
+	kids := make([]string, len(pages))
+	for i, page := range pages {
+		pageID := len(objects) + 1
+		kids[i] = fmt.Sprintf("%d 0 R", pageID)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				PageWidth, PageHeight, pageID+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+// Bytes 输出PDF文件内容
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
@@ -0,0 +1,195 @@
+// Package realtime 向用户的在线连接实时推送事件
+//
+// Hub维护每个用户的连接并负责扇出。消息先经过Broker再由各实例的Hub投递给本机连接：
+// 单实例部署使用进程内的LocalBroker；多副本部署时实现Broker接口（如基于Redis Pub/Sub），
+// 使任一实例发布的消息都能送达连接在其他实例上的设备。
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 连接限制
+const (
+	DefaultMaxPerUser = 5  // 每个用户同时在线的连接数
+	bufferSize        = 16 // 每个连接缓冲的消息数，客户端读取过慢时丢弃新消息
+)
+
+// ErrTooManyConnections 用户的连接数达到上限
+var ErrTooManyConnections = errors.New("realtime: too many connections")
+
+// Message 推送给用户的消息
+type Message struct {
+	UserID uint            `json:"user_id"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// Broker 在实例之间传递消息
+type Broker interface {
+	// Publish 发布消息，所有实例（包括自身）的订阅者都应收到
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe 注册消息处理函数，每个实例的Hub在创建时调用一次
+	Subscribe(handler func(*Message)) error
+}
+
+// LocalBroker 进程内的Broker，只适用于单实例部署
+type LocalBroker struct {
+	mu       sync.RWMutex
+	handlers []func(*Message)
+}
+
+// Publish 同步调用所有处理函数
+func (b *LocalBroker) Publish(_ context.Context, msg *Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+// Subscribe 注册处理函数
+func (b *LocalBroker) Subscribe(handler func(*Message)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+// Subscription 一个在线连接的订阅
+type Subscription struct {
+	hub    *Hub
+	userID uint
+	ch     chan *Message
+	once   sync.Once
+}
+
+// C 接收消息的通道，订阅关闭后通道关闭
+func (s *Subscription) C() <-chan *Message {
+	return s.ch
+}
+
+// Close 取消订阅，可重复调用
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.remove(s)
+	})
+}
+
+// Hub 本实例的连接表，按用户扇出消息
+type Hub struct {
+	broker     Broker
+	MaxPerUser int
+
+	mu   sync.RWMutex
+	subs map[uint]map[*Subscription]struct{}
+}
+
+// NewHub 创建Hub并订阅broker的消息
+func NewHub(broker Broker) (*Hub, error) {
+	h := &Hub{
+		broker:     broker,
+		MaxPerUser: DefaultMaxPerUser,
+		subs:       make(map[uint]map[*Subscription]struct{}),
+	}
+	if err := broker.Subscribe(h.dispatch); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Default 默认的Hub，使用进程内Broker
+var Default, _ = NewHub(&LocalBroker{})
+
+// SetBroker 替换默认Hub的Broker，多副本部署时在启动阶段调用
+func SetBroker(broker Broker) error {
+	h, err := NewHub(broker)
+	if err != nil {
+		return err
+	}
+	Default = h
+	return nil
+}
+
+// Subscribe 为用户的一个连接创建订阅
+func (h *Hub) Subscribe(userID uint) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subs[userID]
+	if len(subs) >= h.MaxPerUser {
+		return nil, ErrTooManyConnections
+	}
+	if subs == nil {
+		subs = make(map[*Subscription]struct{})
+		h.subs[userID] = subs
+	}
+
+	s := &Subscription{hub: h, userID: userID, ch: make(chan *Message, bufferSize)}
+	subs[s] = struct{}{}
+	return s, nil
+}
+
+// remove 移除订阅并关闭通道
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subs[s.userID], s)
+	if len(h.subs[s.userID]) == 0 {
+		delete(h.subs, s.userID)
+	}
+	close(s.ch)
+}
+
+// Connections 用户在本实例上的连接数
+func (h *Hub) Connections(userID uint) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs[userID])
+}
+
+// Publish 经Broker向用户的所有连接发布消息，data编码为JSON
+func (h *Hub) Publish(ctx context.Context, userID uint, eventType string, data interface{}) error {
+	msg := &Message{UserID: userID, Type: eventType}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		msg.Data = encoded
+	}
+	return h.broker.Publish(ctx, msg)
+}
+
+// dispatch 把消息投递给本实例上该用户的连接，连接缓冲已满时丢弃，不阻塞其他连接
+func (h *Hub) dispatch(msg *Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for s := range h.subs[msg.UserID] {
+		select {
+		case s.ch <- msg:
+		default:
+			logs.Warn("Realtime buffer full for user %d, dropping %s", msg.UserID, msg.Type)
+		}
+	}
+}
+
+// WriteEvent 按Server-Sent Events格式写出一条消息
+func WriteEvent(w io.Writer, id uint64, msg *Message) error {
+	data := msg.Data
+	if data == nil {
+		data = json.RawMessage("null")
+	}
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, msg.Type, data)
+	return err
+}
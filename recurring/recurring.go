@@ -0,0 +1,73 @@
+// Package recurring 按预算模板定期生成每月的预算
+//
+// 调度器按固定间隔检查所有模板，用户时区下进入新的月份后为覆盖该月的模板生成预算。
+// 生成前在数据库中占用月份，多个实例同时运行时每个模板每月只生成一次。
+package recurring
+
+import (
+	"context"
+	"time"
+
+	"blog/models"
+	"blog/notify"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// DefaultInterval 默认的检查间隔
+const DefaultInterval = time.Hour
+
+// Scheduler 预算模板调度器
+type Scheduler struct {
+	Interval time.Duration
+}
+
+// New 创建调度器
+func New() *Scheduler {
+	return &Scheduler{Interval: DefaultInterval}
+}
+
+// Init 根据budgettemplateinterval配置启动调度器
+func Init() {
+	s := New()
+	if interval, _ := web.AppConfig.String("budgettemplateinterval"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			logs.Error("Invalid budgettemplateinterval %q, using %v", interval, DefaultInterval)
+		} else {
+			s.Interval = d
+		}
+	}
+
+	go s.Start(context.Background())
+}
+
+// Start 按间隔生成到期的预算，直到ctx取消
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			logs.Error("Error generating budgets from templates: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 生成所有到期的预算并发布预算创建事件，返回生成的数量
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	budgets, err := models.GenerateDueBudgets(ctx)
+	for _, budget := range budgets {
+		notify.Publish(notify.NewEvent(notify.EventBudgetCreated, budget.UserID, budget))
+	}
+	if len(budgets) > 0 {
+		logs.Info("Generated %d budgets from templates", len(budgets))
+	}
+	return len(budgets), err
+}
@@ -12,6 +12,8 @@ func init() {
 	beego.Router("/api/user/profile", &controllers.UserController{}, "get:Profile;put:UpdateProfile")
 	beego.Router("/api/user/password", &controllers.UserController{}, "put:ChangePassword")
 	beego.Router("/api/user/forgot-password", &controllers.UserController{}, "post:ForgotPassword")
+	beego.Router("/api/user/unlock", &controllers.UserController{}, "get,post:Unlock")
+	beego.Router("/api/user/login-history", &controllers.UserController{}, "get:LoginHistory")
 
 	// 分类相关路由
 	beego.Router("/api/categories", &controllers.CategoryController{}, "get:List;post:Create")
@@ -20,14 +22,55 @@ func init() {
 	// 账单相关路由
 	beego.Router("/api/bills", &controllers.BillController{}, "get:List;post:Create")
 	beego.Router("/api/bills/:id", &controllers.BillController{}, "get:Get;put:Update;delete:Delete")
+	beego.Router("/api/bills/stats", &controllers.BillController{}, "get:Stats")
 	beego.Router("/api/bills/stats/monthly", &controllers.BillController{}, "get:MonthlyStats")
+	beego.Router("/api/bills/export", &controllers.BillController{}, "get:Export")
+
+	// 报表相关路由
+	beego.Router("/api/reports", &controllers.ReportController{}, "get:Get")
+	beego.Router("/api/reports/trend", &controllers.ReportController{}, "get:Trend")
+	beego.Router("/api/reports/category-share", &controllers.ReportController{}, "get:CategoryShare")
+	beego.Router("/api/reports/statement", &controllers.ReportController{}, "get:Statement")
+
+	// 实时事件路由
+	beego.Router("/api/events", &controllers.EventController{}, "get:Stream")
+	beego.Router("/api/events/ticket", &controllers.EventController{}, "post:Ticket")
+
+	// Webhook相关路由
+	beego.Router("/api/webhooks", &controllers.WebhookController{}, "get:List;post:Create")
+	beego.Router("/api/webhooks/:id", &controllers.WebhookController{}, "put:Update;delete:Delete")
+	beego.Router("/api/webhooks/:id/deliveries", &controllers.WebhookController{}, "get:Deliveries")
+	beego.Router("/api/webhooks/deliveries/:id/redeliver", &controllers.WebhookController{}, "post:Redeliver")
+
+	// 站内通知相关路由
+	beego.Router("/api/notifications", &controllers.NotificationController{}, "get:List")
+	beego.Router("/api/notifications/unread-count", &controllers.NotificationController{}, "get:UnreadCount")
+	beego.Router("/api/notifications/read-all", &controllers.NotificationController{}, "post:ReadAll")
+	beego.Router("/api/notifications/:id", &controllers.NotificationController{}, "delete:Delete")
+	beego.Router("/api/notifications/:id/read", &controllers.NotificationController{}, "put:Read")
+
+	// 报告邮件订阅相关路由
+	beego.Router("/api/digests", &controllers.DigestController{}, "get:List;post:Create")
+	beego.Router("/api/digests/history", &controllers.DigestController{}, "get:History")
+	beego.Router("/api/digests/unsubscribe", &controllers.DigestController{}, "get:UnsubscribePage;post:Unsubscribe")
+	beego.Router("/api/digests/:id", &controllers.DigestController{}, "delete:Delete")
+
+	// 账单视图相关路由
+	beego.Router("/api/views", &controllers.ViewController{}, "get:List;post:Create")
+	beego.Router("/api/views/:id", &controllers.ViewController{}, "get:Get;put:Update;delete:Delete")
 
 	// 预算相关路由
 	beego.Router("/api/budgets", &controllers.BudgetController{}, "get:List;post:Create")
+	beego.Router("/api/budgets/copy", &controllers.BudgetController{}, "post:Copy")
 	beego.Router("/api/budgets/:id", &controllers.BudgetController{}, "get:Get;put:Update;delete:Delete")
 
+	// 预算模板相关路由
+	beego.Router("/api/budget-templates", &controllers.BudgetTemplateController{}, "get:List;post:Create")
+	beego.Router("/api/budget-templates/:id", &controllers.BudgetTemplateController{}, "put:Update;delete:Delete")
+
 	// 预算告警相关路由
 	beego.Router("/api/budget-alerts", &controllers.BudgetController{}, "get:ListAlerts;post:CreateAlert")
 	beego.Router("/api/budget-alerts/:id", &controllers.BudgetController{}, "put:UpdateAlert;delete:DeleteAlert")
 	beego.Router("/api/budget-alerts/check", &controllers.BudgetController{}, "get:CheckAlerts")
+	beego.Router("/api/budget-alerts/events", &controllers.BudgetController{}, "get:AlertEvents")
 }
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/validation"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestBudgetPeriod 验证预算周期的对齐和参数校验
+func TestBudgetPeriod(t *testing.T) {
+	Convey("Subject: Budget Periods\n", t, func() {
+		day := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC) // 周三
+
+		Convey("Natural Periods Should Align To Their Start", func() {
+			start, end := models.BudgetPeriodRange(models.BudgetWeekly, day)
+			So(start.Format("2006-01-02"), ShouldEqual, "2024-05-13")
+			So(end.Format("2006-01-02"), ShouldEqual, "2024-05-19")
+
+			start, end = models.BudgetPeriodRange(models.BudgetMonthly, day)
+			So(start.Format("2006-01-02"), ShouldEqual, "2024-05-01")
+			So(end.Format("2006-01-02"), ShouldEqual, "2024-05-31")
+
+			start, end = models.BudgetPeriodRange(models.BudgetQuarterly, day)
+			So(start.Format("2006-01-02"), ShouldEqual, "2024-04-01")
+			So(end.Format("2006-01-02"), ShouldEqual, "2024-06-30")
+
+			start, end = models.BudgetPeriodRange(models.BudgetYearly, day)
+			So(start.Format("2006-01-02"), ShouldEqual, "2024-01-01")
+			So(end.Format("2006-01-02"), ShouldEqual, "2024-12-31")
+		})
+
+		Convey("Period Label Should Show Month Or Dates", func() {
+			monthly := &models.Budget{Period: models.BudgetMonthly, StartDate: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+			So(monthly.PeriodLabel(), ShouldEqual, "2024-05")
+
+			start, end := models.BudgetPeriodRange(models.BudgetWeekly, day)
+			weekly := &models.Budget{Period: models.BudgetWeekly, StartDate: start, EndDate: end}
+			So(weekly.PeriodLabel(), ShouldEqual, "2024-05-13 ~ 2024-05-19")
+		})
+
+		Convey("Budget Request Should Require A Start And Valid Custom Range", func() {
+			valid := validation.Validation{}
+			ok, _ := valid.Valid(&models.BudgetRequest{Amount: 100, Period: models.BudgetWeekly})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Period: models.BudgetWeekly, StartDate: "2024-05-15"})
+			So(ok, ShouldBeTrue)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Period: "daily", Month: "2024-05"})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Period: models.BudgetCustom, StartDate: "2024-07-01"})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Period: models.BudgetCustom, StartDate: "2024-07-10", EndDate: "2024-07-01"})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Period: models.BudgetCustom, StartDate: "2024-07-01", EndDate: "2024-07-21"})
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Budget Periods Should Be Recognized", func() {
+			So(models.IsBudgetPeriod(models.BudgetQuarterly), ShouldBeTrue)
+			So(models.IsBudgetPeriod("daily"), ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package test
+
+import (
+	"testing"
+
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/validation"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestBudgetRollover 验证各结转方式和上限下的结转金额
+func TestBudgetRollover(t *testing.T) {
+	Convey("Subject: Budget Rollover\n", t, func() {
+		surplus := models.MoneyFromFloat(300)
+		deficit := models.MoneyFromFloat(-120)
+		capAmount := models.MoneyFromFloat(100)
+
+		Convey("None Should Never Carry", func() {
+			So(models.RolloverAmount(models.RolloverNone, 0, surplus), ShouldEqual, 0)
+			So(models.RolloverAmount(models.RolloverNone, 0, deficit), ShouldEqual, 0)
+			So(models.RolloverAmount("", 0, surplus), ShouldEqual, 0)
+		})
+
+		Convey("Surplus And Deficit Modes Should Carry One Direction Only", func() {
+			So(models.RolloverAmount(models.RolloverSurplus, 0, surplus), ShouldEqual, surplus)
+			So(models.RolloverAmount(models.RolloverSurplus, 0, deficit), ShouldEqual, 0)
+			So(models.RolloverAmount(models.RolloverDeficit, 0, surplus), ShouldEqual, 0)
+			So(models.RolloverAmount(models.RolloverDeficit, 0, deficit), ShouldEqual, deficit)
+			So(models.RolloverAmount(models.RolloverBoth, 0, surplus), ShouldEqual, surplus)
+			So(models.RolloverAmount(models.RolloverBoth, 0, deficit), ShouldEqual, deficit)
+		})
+
+		Convey("Cap Should Limit Carry In Both Directions", func() {
+			So(models.RolloverAmount(models.RolloverBoth, capAmount, surplus), ShouldEqual, capAmount)
+			So(models.RolloverAmount(models.RolloverBoth, capAmount, deficit), ShouldEqual, -capAmount)
+			So(models.RolloverAmount(models.RolloverSurplus, capAmount, models.MoneyFromFloat(50)), ShouldEqual, models.MoneyFromFloat(50))
+		})
+
+		Convey("Budget Wiped Out By Deficit Should Count As Used Up", func() {
+			So(models.BudgetPercentage(models.MoneyFromFloat(50), models.MoneyFromFloat(200)), ShouldEqual, 25)
+			So(models.BudgetPercentage(0, models.MoneyFromFloat(-20)), ShouldEqual, 100)
+		})
+
+		Convey("Invalid Rollover Settings Should Fail Validation", func() {
+			valid := validation.Validation{}
+			ok, _ := valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-03", RolloverMode: "forever"})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-03", RolloverMode: models.RolloverBoth, RolloverCap: -1})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-03", RolloverMode: models.RolloverSurplus, RolloverCap: capAmount})
+			So(ok, ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// selectColumns 拆分SELECT与FROM之间的列表达式，忽略括号内的逗号
+func selectColumns(query string) []string {
+	start := strings.Index(query, "SELECT") + len("SELECT")
+	end := strings.Index(query, "FROM budgets")
+	columns := make([]string, 0)
+	depth, last := 0, start
+	for i := start; i < end; i++ {
+		switch query[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				columns = append(columns, strings.TrimSpace(query[last:i]))
+				last = i + 1
+			}
+		}
+	}
+	return append(columns, strings.TrimSpace(query[last:end]))
+}
+
+func budgetColumnValue(column string) driver.Value {
+	switch {
+	case column == "b.id", column == "b.user_id":
+		return int64(1)
+	case column == "b.name":
+		return "Food"
+	case column == "b.amount":
+		return "100.00"
+	case strings.Contains(column, "b.month"):
+		return "2024-05"
+	case strings.Contains(column, "b.start_date"):
+		return "2024-05-01"
+	case strings.Contains(column, "b.end_date"):
+		return "2024-05-31"
+	case column == "b.period":
+		return models.BudgetMonthly
+	case column == "b.rollover_mode":
+		return models.RolloverNone
+	case column == "b.scope_key":
+		return ""
+	case column == "b.created_at", column == "b.updated_at":
+		return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return nil
+}
+
+// TestBudgetScan 验证预算查询的列与扫描目标一致
+func TestBudgetScan(t *testing.T) {
+	// 预算查询按SELECT列表返回一行，列数与查询一致，其余查询返回一行0
+	useFakeDB(t, func(query string, _ []driver.Value) fakeResult {
+		if !strings.Contains(query, "FROM budgets b") {
+			return fakeResult{columns: []string{"value"}, rows: [][]driver.Value{{int64(0)}}}
+		}
+		columns := selectColumns(query)
+		row := make([]driver.Value, len(columns))
+		for i, column := range columns {
+			row[i] = budgetColumnValue(column)
+		}
+		return fakeResult{columns: columns, rows: [][]driver.Value{row}}
+	})
+
+	Convey("Subject: Budget Row Scan\n", t, func() {
+		Convey("GetBudget Should Scan Every Selected Column", func() {
+			budget, err := models.GetBudget(context.Background(), 1, 1)
+			So(err, ShouldBeNil)
+			So(budget.Name, ShouldEqual, "Food")
+			So(budget.Period, ShouldEqual, models.BudgetMonthly)
+			So(budget.EndDate.Format("2006-01-02"), ShouldEqual, "2024-05-31")
+		})
+
+		Convey("GetBudgets Should Scan Every Selected Column", func() {
+			budgets, err := models.GetBudgets(context.Background(), 1, "2024-05")
+			So(err, ShouldBeNil)
+			So(len(budgets), ShouldEqual, 1)
+			So(budgets[0].Amount.String(), ShouldEqual, "100.00")
+		})
+	})
+}
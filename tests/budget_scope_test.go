@@ -0,0 +1,62 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/validation"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestBudgetScope 验证组合预算的参数校验和显示名称
+func TestBudgetScope(t *testing.T) {
+	Convey("Subject: Group Budgets\n", t, func() {
+		Convey("Group Budget Should Require A Name", func() {
+			valid := validation.Validation{}
+			ok, _ := valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-05", CategoryIDs: []uint{1, 2}})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-05", Name: "Fun", CategoryIDs: []uint{1, 2}, Tags: []string{"games"}})
+			So(ok, ShouldBeTrue)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-05", Name: "Travel", Tags: []string{"travel"}})
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Group Budget Should Not Also Target A Single Category", func() {
+			valid := validation.Validation{}
+			ok, _ := valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-05", Name: "Fun", CategoryID: 3, CategoryIDs: []uint{1, 2}})
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Group Budget Should Limit Categories And Tags", func() {
+			ids := make([]uint, 21)
+			for i := range ids {
+				ids[i] = uint(i + 1)
+			}
+			valid := validation.Validation{}
+			ok, _ := valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-05", Name: "All", CategoryIDs: ids})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetRequest{Amount: 100, Month: "2024-05", Name: "Long", Tags: []string{strings.Repeat("t", models.MaxTagLength+1)}})
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Display Name Should Prefer The Group Name", func() {
+			group := &models.Budget{Name: "Fun", CategoryIDs: []uint{1, 2}}
+			So(group.IsGroup(), ShouldBeTrue)
+			So(group.DisplayName(), ShouldEqual, "Fun")
+
+			category := &models.Budget{CategoryID: 3, CategoryName: "Food"}
+			So(category.IsGroup(), ShouldBeFalse)
+			So(category.DisplayName(), ShouldEqual, "Food")
+
+			So((&models.Budget{}).DisplayName(), ShouldEqual, "")
+		})
+	})
+}
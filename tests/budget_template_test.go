@@ -0,0 +1,64 @@
+package test
+
+import (
+	"testing"
+
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/validation"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestBudgetTemplate 验证预算模板和复制预算的参数校验以及金额调整
+func TestBudgetTemplate(t *testing.T) {
+	Convey("Subject: Budget Templates And Copy\n", t, func() {
+		amount := models.MoneyFromFloat(200)
+
+		Convey("Adjust Should Scale By Percentage", func() {
+			So(amount.Adjust(10), ShouldEqual, models.MoneyFromFloat(220))
+			So(amount.Adjust(-25), ShouldEqual, models.MoneyFromFloat(150))
+			So(amount.Adjust(0), ShouldEqual, amount)
+			So(models.MoneyFromFloat(0.05).Adjust(50), ShouldEqual, models.MoneyFromFloat(0.08))
+		})
+
+		Convey("Template Range Should Be Ordered", func() {
+			valid := validation.Validation{}
+			ok, _ := valid.Valid(&models.BudgetTemplateRequest{Amount: amount, StartMonth: "2024-03", EndMonth: "2024-02"})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetTemplateRequest{Amount: amount, StartMonth: "2024-03"})
+			So(ok, ShouldBeTrue)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetTemplateRequest{Amount: amount, StartMonth: "2024-03", EndMonth: "2024-03", RolloverMode: models.RolloverSurplus})
+			So(ok, ShouldBeTrue)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetTemplateRequest{Amount: amount, StartMonth: "2024-3"})
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Copy Request Should Reject Same Month, Out Of Range Percentages And Untargeted Adjustments", func() {
+			valid := validation.Validation{}
+			ok, _ := valid.Valid(&models.BudgetCopyRequest{FromMonth: "2024-03", ToMonth: "2024-03"})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetCopyRequest{ToMonth: "2024-04", Percent: -100})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetCopyRequest{ToMonth: "2024-04", Adjustments: []models.BudgetAdjustment{{BudgetID: 1, Percent: 2000}}})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetCopyRequest{ToMonth: "2024-04", Adjustments: []models.BudgetAdjustment{{Percent: -20}}})
+			So(ok, ShouldBeFalse)
+
+			valid = validation.Validation{}
+			ok, _ = valid.Valid(&models.BudgetCopyRequest{ToMonth: "2024-04", Percent: 5, Adjustments: []models.BudgetAdjustment{{BudgetID: 1, Percent: -20}}})
+			So(ok, ShouldBeTrue)
+		})
+	})
+}
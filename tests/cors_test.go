@@ -0,0 +1,116 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog/middleware"
+
+	beego "github.com/beego/beego/v2/server/web"
+	beecontext "github.com/beego/beego/v2/server/web/context"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestCorsHandler 验证来源匹配、Vary响应头以及预检请求在JWT认证之前结束
+func TestCorsHandler(t *testing.T) {
+	beego.AppConfig.Set("corsalloworigins", "https://app.example.com,https://*.example.com")
+	beego.AppConfig.Set("corsallowcredentials", "true")
+	middleware.InitCors()
+
+	beego.InsertFilter("/cors-test/*", beego.BeforeRouter, middleware.CorsHandler)
+	beego.InsertFilter("/cors-test/*", beego.BeforeRouter, middleware.JwtFilter)
+	beego.Get("/cors-test/bills", func(ctx *beecontext.Context) {
+		ctx.Output.Body([]byte("ok"))
+	})
+
+	serve := func(method, origin string, header http.Header) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest(method, "/cors-test/bills", nil)
+		for key, values := range header {
+			r.Header[key] = values
+		}
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		return w
+	}
+	preflight := http.Header{"Access-Control-Request-Method": {"POST"}}
+
+	Convey("Subject: CORS Handler\n", t, func() {
+		Convey("Listed And Wildcard Subdomain Origins Should Be Allowed", func() {
+			for _, origin := range []string{"https://app.example.com", "https://api.example.com", "https://a.b.example.com"} {
+				w := serve("OPTIONS", origin, preflight)
+				So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, origin)
+				So(w.Header().Get("Access-Control-Allow-Credentials"), ShouldEqual, "true")
+			}
+		})
+
+		Convey("Wildcard Should Not Match The Apex, Other Schemes Or Lookalike Hosts", func() {
+			for _, origin := range []string{
+				"https://example.com",
+				"http://api.example.com",
+				"https://evilexample.com",
+				"https://api.example.com.evil.com",
+				"https://api.example.com:8443",
+			} {
+				w := serve("OPTIONS", origin, preflight)
+				So(w.Header().Get("Access-Control-Allow-Origin"), ShouldBeEmpty)
+			}
+		})
+
+		Convey("Vary Origin Should Be Set Whether Or Not The Origin Is Allowed", func() {
+			So(serve("OPTIONS", "https://app.example.com", preflight).Header().Get("Vary"), ShouldEqual, "Origin")
+			So(serve("OPTIONS", "https://evil.com", preflight).Header().Get("Vary"), ShouldEqual, "Origin")
+			So(serve("GET", "", nil).Header().Get("Vary"), ShouldEqual, "Origin")
+		})
+
+		Convey("Preflight Should End Before JWT Authentication", func() {
+			w := serve("OPTIONS", "https://app.example.com", preflight)
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(w.Header().Get("Access-Control-Allow-Methods"), ShouldContainSubstring, "POST")
+			So(w.Header().Get("Access-Control-Max-Age"), ShouldNotBeEmpty)
+			So(w.Body.Len(), ShouldEqual, 0)
+		})
+
+		Convey("Actual Requests Should Still Require Authentication", func() {
+			w := serve("GET", "https://app.example.com", nil)
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+			// 错误响应同样携带跨域头，浏览器才能读取错误内容
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "https://app.example.com")
+			So(w.Header().Get("Access-Control-Allow-Methods"), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Subject: CORS Wildcard With Credentials\n", t, func() {
+		beego.AppConfig.Set("corsalloworigins", "*,https://app.example.com")
+		defer func() {
+			beego.AppConfig.Set("corsalloworigins", "https://app.example.com,https://*.example.com")
+			beego.AppConfig.Set("corsallowcredentials", "true")
+			middleware.InitCors()
+		}()
+
+		Convey("Wildcard Should Not Echo Arbitrary Origins When Credentials Are Allowed", func() {
+			beego.AppConfig.Set("corsallowcredentials", "true")
+			middleware.InitCors()
+
+			w := serve("OPTIONS", "https://evil.com", preflight)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldBeEmpty)
+			So(w.Header().Get("Access-Control-Allow-Credentials"), ShouldBeEmpty)
+
+			w = serve("OPTIONS", "https://app.example.com", preflight)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "https://app.example.com")
+			So(w.Header().Get("Access-Control-Allow-Credentials"), ShouldEqual, "true")
+		})
+
+		Convey("Wildcard Should Allow Any Origin Without Credentials", func() {
+			beego.AppConfig.Set("corsallowcredentials", "false")
+			middleware.InitCors()
+
+			w := serve("OPTIONS", "https://evil.com", preflight)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "*")
+			So(w.Header().Get("Access-Control-Allow-Credentials"), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"testing"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestCursor 验证分页游标的编码与校验
+func TestCursor(t *testing.T) {
+	Convey("Subject: Pagination Cursor\n", t, func() {
+		cursor := &models.Cursor{Sort: "-amount", Value: "12.30", ID: 42}
+		encoded := cursor.Encode()
+
+		Convey("Cursor Should Round Trip", func() {
+			decoded, err := models.DecodeCursor(encoded, "-amount")
+			So(err, ShouldBeNil)
+			So(decoded, ShouldResemble, cursor)
+		})
+
+		Convey("Cursor From Another Sort Order Should Be Rejected", func() {
+			_, err := models.DecodeCursor(encoded, "amount")
+			So(err, ShouldEqual, models.ErrInvalidCursor)
+		})
+
+		Convey("Garbage Should Be Rejected", func() {
+			_, err := models.DecodeCursor("not-a-cursor", "-amount")
+			So(err, ShouldEqual, models.ErrInvalidCursor)
+		})
+	})
+}
@@ -3,11 +3,13 @@ package test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"runtime"
 	"path/filepath"
 	_ "blog/routers"
 
+	"github.com/beego/beego/v2/core/logs"
 	beego "github.com/beego/beego/v2/server/web"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -19,21 +21,24 @@ func init() {
 }
 
 
-// TestBeego is a sample to run an endpoint test
+// TestBeego 未登录请求登录接口且缺少参数时返回422及字段级错误
 func TestBeego(t *testing.T) {
-	r, _ := http.NewRequest("GET", "/", nil)
+	r, _ := http.NewRequest("POST", "/api/user/login", strings.NewReader("{}"))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept-Language", "en-US")
 	w := httptest.NewRecorder()
 	beego.BeeApp.Handlers.ServeHTTP(w, r)
 
-	beego.Trace("testing", "TestBeego", "Code[%d]\n%s", w.Code, w.Body.String())
-
-	Convey("Subject: Test Station Endpoint\n", t, func() {
-	        Convey("Status Code Should Be 200", func() {
-	                So(w.Code, ShouldEqual, 200)
-	        })
-	        Convey("The Result Should Not Be Empty", func() {
-	                So(w.Body.Len(), ShouldBeGreaterThan, 0)
-	        })
+	logs.Trace("testing TestBeego Code[%d]\n%s", w.Code, w.Body.String())
+
+	Convey("Subject: Test Login Endpoint\n", t, func() {
+		Convey("Status Code Should Be 422", func() {
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+		})
+		Convey("The Result Should Report Missing Fields", func() {
+			So(w.Body.String(), ShouldContainSubstring, "validation_failed")
+			So(w.Body.String(), ShouldContainSubstring, "username")
+			So(w.Body.String(), ShouldContainSubstring, "password")
+		})
 	})
 }
-
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/digest"
+	"blog/mail"
+	"blog/models"
+
+	beego "github.com/beego/beego/v2/server/web"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestDigest 验证报告周期计算、邮件渲染和测试用邮件发送器
+func TestDigest(t *testing.T) {
+	Convey("Subject: Email Digests\n", t, func() {
+		Convey("Weekly Digest Should Cover The Previous Monday To Sunday", func() {
+			// 2024-01-03为周三
+			start, end := models.DigestPeriod(models.DigestWeekly, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+			So(start, ShouldEqual, "2023-12-25")
+			So(end, ShouldEqual, "2023-12-31")
+		})
+
+		Convey("Monthly Digest Should Cover The Previous Calendar Month", func() {
+			start, end := models.DigestPeriod(models.DigestMonthly, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+			So(start, ShouldEqual, "2024-02-01")
+			So(end, ShouldEqual, "2024-02-29")
+		})
+
+		Convey("Digest Should Render In The User's Language With An Unsubscribe Link", func() {
+			mailer := &mail.MemoryMailer{}
+			s, err := digest.New(mailer, "https://finwise.example", beego.BConfig.WebConfig.ViewsPath)
+			So(err, ShouldBeNil)
+
+			change := 12.5
+			msg, err := s.Render(&models.Digest{
+				User:      &models.User{Username: "alice", Language: "en-US"},
+				Frequency: models.DigestWeekly,
+				Report: &models.Report{
+					StartDate: "2023-12-25",
+					EndDate:   "2023-12-31",
+					Summary:   models.ReportSummary{Income: 100000, Expense: 25000, Balance: 75000, Count: 4},
+					Previous:  &models.ReportComparison{ExpenseChange: &change},
+				},
+				Categories: []*models.ReportCategory{{Name: "Food", Total: 25000, Percent: 100}},
+				Budgets:    []*models.Budget{{Amount: 20000, UsedAmount: 25000, Percentage: 125}},
+			}, "abc123")
+			So(err, ShouldBeNil)
+			So(msg.HTML, ShouldBeTrue)
+			So(msg.Subject, ShouldEqual, "FinWise weekly digest (2023-12-25 ~ 2023-12-31)")
+			So(msg.Body, ShouldContainSubstring, "Hi alice")
+			So(msg.Body, ShouldContainSubstring, "250.00")
+			So(msg.Body, ShouldContainSubstring, "&#43;12.50%")
+			So(msg.Body, ShouldContainSubstring, "https://finwise.example/api/digests/unsubscribe?token=abc123")
+			So(msg.Headers["List-Unsubscribe"], ShouldEqual, "<https://finwise.example/api/digests/unsubscribe?token=abc123>")
+			So(msg.Headers["List-Unsubscribe-Post"], ShouldEqual, "List-Unsubscribe=One-Click")
+		})
+
+		Convey("Opening The Unsubscribe Link Should Only Show A Confirmation Form", func() {
+			r, _ := http.NewRequest("GET", "/api/digests/unsubscribe?token=abc123", nil)
+			r.Header.Set("Accept-Language", "en-US")
+			w := httptest.NewRecorder()
+			beego.BeeApp.Handlers.ServeHTTP(w, r)
+
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldStartWith, "text/html")
+			So(w.Body.String(), ShouldContainSubstring, `method="post"`)
+			So(w.Body.String(), ShouldContainSubstring, `value="abc123"`)
+			So(w.Body.String(), ShouldContainSubstring, "Stop receiving this FinWise digest email?")
+		})
+
+		Convey("Memory Mailer Should Keep Sent Messages", func() {
+			mailer := &mail.MemoryMailer{}
+			So(mailer.Send(context.Background(), &mail.Message{To: []string{"a@example.com"}, Subject: "hi"}), ShouldBeNil)
+			So(len(mailer.Messages()), ShouldEqual, 1)
+			So(mailer.Messages()[0].Subject, ShouldEqual, "hi")
+		})
+
+		Convey("File Mailer Should Write One File Per Message", func() {
+			dir := t.TempDir()
+			mailer := &mail.FileMailer{Dir: dir}
+			err := mailer.Send(context.Background(), &mail.Message{
+				To: []string{"a@example.com"}, Subject: "hi", Body: "<p>body</p>", HTML: true,
+				Headers: map[string]string{"List-Unsubscribe": "<https://finwise.example/u>", "X-Bad": "a\r\nBcc: x@example.com"},
+			})
+			So(err, ShouldBeNil)
+
+			files, _ := filepath.Glob(filepath.Join(dir, "*.html"))
+			So(len(files), ShouldEqual, 1)
+			content, _ := os.ReadFile(files[0])
+			So(strings.Contains(string(content), "Subject: hi"), ShouldBeTrue)
+			So(strings.Contains(string(content), "<p>body</p>"), ShouldBeTrue)
+			So(strings.Contains(string(content), "List-Unsubscribe: <https://finwise.example/u>"), ShouldBeTrue)
+			So(strings.Contains(string(content), "Bcc"), ShouldBeFalse)
+		})
+	})
+}
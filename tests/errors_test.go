@@ -0,0 +1,75 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog/controllers"
+	"blog/models"
+
+	beego "github.com/beego/beego/v2/server/web"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type errorsTestController struct {
+	controllers.BaseController
+}
+
+var errorsTestCases = map[string]error{
+	"not-found":  models.ErrBillNotFound,
+	"conflict":   models.Conflict("alert_threshold_exists", 80),
+	"validation": models.ErrBillTypeMismatch,
+	"throttled":  &models.LoginThrottledError{},
+	"database":   errors.New("Error 1045: Access denied for user 'root'@'10.0.0.5'"),
+}
+
+func (c *errorsTestController) Get() {
+	c.Error(errorsTestCases[c.Ctx.Input.Param(":case")])
+}
+
+// TestErrorMapping 验证业务错误类别到HTTP状态码的统一映射
+func TestErrorMapping(t *testing.T) {
+	beego.Router("/errors-test/:case", &errorsTestController{})
+
+	get := func(name string) (int, controllers.Response) {
+		r, _ := http.NewRequest("GET", "/errors-test/"+name, nil)
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		var resp controllers.Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	Convey("Subject: Typed Error Mapping\n", t, func() {
+		Convey("Domain Errors Should Map To Their Status", func() {
+			status, resp := get("not-found")
+			So(status, ShouldEqual, http.StatusNotFound)
+			So(resp.ErrorCode, ShouldEqual, "bill_not_found")
+
+			status, resp = get("conflict")
+			So(status, ShouldEqual, http.StatusConflict)
+			So(resp.Message, ShouldContainSubstring, "80%")
+
+			status, _ = get("validation")
+			So(status, ShouldEqual, http.StatusBadRequest)
+
+			status, _ = get("throttled")
+			So(status, ShouldEqual, http.StatusTooManyRequests)
+		})
+
+		Convey("Internal Errors Should Not Leak Details", func() {
+			status, resp := get("database")
+			So(status, ShouldEqual, http.StatusInternalServerError)
+			So(resp.ErrorCode, ShouldEqual, "internal_error")
+			So(resp.Message, ShouldNotContainSubstring, "Access denied")
+		})
+
+		Convey("Errors Should Compare By Code", func() {
+			So(errors.Is(models.Conflict("alert_threshold_exists", 50), models.Conflict("alert_threshold_exists")), ShouldBeTrue)
+			So(errors.Is(models.ErrBillNotFound, models.ErrBudgetNotFound), ShouldBeFalse)
+		})
+	})
+}
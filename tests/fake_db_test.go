@@ -0,0 +1,113 @@
+package test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"blog/models"
+)
+
+// fakeResult 查询的返回结果，columns为空表示没有结果集
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeHandler 按SQL和参数返回结果，写操作也会调用，返回值被忽略
+type fakeHandler func(query string, args []driver.Value) fakeResult
+
+var (
+	fakeMu       sync.Mutex
+	fakeHandlers = map[string]fakeHandler{}
+	fakeSeq      int
+)
+
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+// useFakeDB 让models在测试期间使用按handler响应的数据库，测试结束后恢复
+func useFakeDB(t *testing.T, handler fakeHandler) {
+	fakeMu.Lock()
+	fakeSeq++
+	name := fmt.Sprintf("fake-%d", fakeSeq)
+	fakeHandlers[name] = handler
+	fakeMu.Unlock()
+
+	db, err := sql.Open("fakedb", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := models.DB
+	models.DB = db
+	t.Cleanup(func() {
+		models.DB = original
+		db.Close()
+	})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	handler, ok := fakeHandlers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake database %q", name)
+	}
+	return fakeConn{handler: handler}, nil
+}
+
+type fakeConn struct {
+	handler fakeHandler
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{handler: c.handler, query: query}, nil
+}
+func (fakeConn) Close() error              { return nil }
+func (fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	handler fakeHandler
+	query   string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.handler(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	result := s.handler(s.query, args)
+	return &fakeRows{fakeResult: result}, nil
+}
+
+type fakeRows struct {
+	fakeResult
+	next int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog/controllers"
+	"blog/i18n"
+
+	beego "github.com/beego/beego/v2/server/web"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestI18n 验证语言协商、消息目录完整性以及接口按语言返回消息
+func TestI18n(t *testing.T) {
+	beego.Router("/i18n-test", &validationTestController{})
+
+	post := func(body, acceptLanguage string) controllers.Response {
+		r, _ := http.NewRequest("POST", "/i18n-test", bytes.NewBufferString(body))
+		r.Header.Set("Accept-Language", acceptLanguage)
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		var resp controllers.Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	Convey("Subject: Internationalized Messages\n", t, func() {
+		Convey("Catalogs Should Be Complete", func() {
+			So(i18n.Missing(i18n.EnUS), ShouldBeEmpty)
+		})
+
+		Convey("Accept-Language Should Be Negotiated By Quality", func() {
+			So(i18n.Negotiate("fr-FR, en;q=0.8, zh;q=0.5"), ShouldEqual, i18n.EnUS)
+			So(i18n.Negotiate("en-GB;q=0.3, zh-TW"), ShouldEqual, i18n.ZhCN)
+			So(i18n.Negotiate("fr"), ShouldEqual, i18n.Default)
+		})
+
+		Convey("Errors Should Carry Stable Codes And Localized Messages", func() {
+			resp := post(`{`, "en-US,en;q=0.9")
+			So(resp.ErrorCode, ShouldEqual, "invalid_request_body")
+			So(resp.Message, ShouldEqual, "Malformed request body")
+
+			resp = post(`{`, "zh-CN")
+			So(resp.ErrorCode, ShouldEqual, "invalid_request_body")
+			So(resp.Message, ShouldEqual, "请求参数格式错误")
+		})
+
+		Convey("Field Errors Should Be Localized", func() {
+			resp := post(`{"amount":"1","type":"expense","date":"2024-01-01"}`, "en")
+			So(resp.ErrorCode, ShouldEqual, "validation_failed")
+			So(len(resp.Errors), ShouldEqual, 1)
+			So(resp.Errors[0].Field, ShouldEqual, "category_id")
+			So(resp.Errors[0].Message, ShouldEqual, "is required")
+		})
+	})
+}
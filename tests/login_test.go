@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestLoginBackoff 验证连续失败后的指数退避
+func TestLoginBackoff(t *testing.T) {
+	Convey("Subject: Login Backoff\n", t, func() {
+		Convey("Free Attempts Should Not Back Off", func() {
+			So(models.LoginBackoff(0, 3), ShouldEqual, 0)
+			So(models.LoginBackoff(2, 3), ShouldEqual, 0)
+		})
+		Convey("Backoff Should Double After Free Attempts", func() {
+			So(models.LoginBackoff(3, 3), ShouldEqual, time.Second)
+			So(models.LoginBackoff(4, 3), ShouldEqual, 2*time.Second)
+			So(models.LoginBackoff(6, 3), ShouldEqual, 8*time.Second)
+		})
+		Convey("Backoff Should Be Capped", func() {
+			So(models.LoginBackoff(12, 3), ShouldEqual, models.LoginMaxBackoff)
+			So(models.LoginBackoff(1000, 3), ShouldEqual, models.LoginMaxBackoff)
+		})
+	})
+}
+
+// TestAccountThrottle 验证账户锁定、退避窗口和失败次数重置规则
+func TestAccountThrottle(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	Convey("Subject: Account Throttle\n", t, func() {
+		Convey("Active Lock Should Wait Until Unlock", func() {
+			failures, wait := models.AccountThrottle(true, models.LoginMaxFailures, at(-time.Minute), at(10*time.Minute), now)
+			So(failures, ShouldEqual, models.LoginMaxFailures)
+			So(wait, ShouldEqual, 10*time.Minute)
+		})
+
+		Convey("Expired Lock Should Reset The Count", func() {
+			failures, wait := models.AccountThrottle(true, models.LoginMaxFailures, at(-time.Minute), at(-time.Second), now)
+			So(failures, ShouldEqual, 0)
+			So(wait, ShouldEqual, 0)
+		})
+
+		Convey("Failures Outside The Window Should Reset The Count", func() {
+			failures, wait := models.AccountThrottle(true, 8, at(-models.LoginLockout), nil, now)
+			So(failures, ShouldEqual, 0)
+			So(wait, ShouldEqual, 0)
+		})
+
+		Convey("Recent Failures Should Back Off From The Last Failure", func() {
+			failures, wait := models.AccountThrottle(true, 5, at(-time.Second), nil, now)
+			So(failures, ShouldEqual, 5)
+			So(wait, ShouldEqual, models.LoginBackoff(5, models.LoginFreeAttempts)-time.Second)
+
+			_, wait = models.AccountThrottle(true, 5, at(-time.Minute), nil, now)
+			So(wait, ShouldBeLessThanOrEqualTo, 0)
+		})
+
+		Convey("Unknown Accounts Should Be Locked Out At The Limit Like Known Ones", func() {
+			failures, wait := models.AccountThrottle(false, models.LoginMaxFailures, at(-time.Minute), nil, now)
+			So(failures, ShouldEqual, models.LoginMaxFailures)
+			So(wait, ShouldEqual, models.LoginLockout-time.Minute)
+		})
+
+		Convey("Unknown Accounts Without Failures Should Not Wait", func() {
+			failures, wait := models.AccountThrottle(false, 0, nil, nil, now)
+			So(failures, ShouldEqual, 0)
+			So(wait, ShouldEqual, 0)
+		})
+	})
+}
+
+// loginDB 模拟登录相关的表，stored为account_lockouts中的失败次数，
+// storedAfter为原子累计之后读回的次数，用于模拟并发请求的累计
+type loginDB struct {
+	known        bool
+	hash         string
+	stored       int
+	lastFailure  interface{}
+	storedAfter  int
+	nameFailures int
+	locked       bool
+}
+
+func (db *loginDB) handle(query string, _ []driver.Value) fakeResult {
+	switch {
+	case strings.Contains(query, "FROM login_attempts WHERE ip"):
+		return fakeResult{columns: []string{"count", "max"}, rows: [][]driver.Value{{int64(0), nil}}}
+	case strings.Contains(query, "FROM users"):
+		result := fakeResult{columns: []string{"id", "username", "email", "password", "phone", "avatar", "timezone", "language", "created_at", "updated_at"}}
+		if db.known {
+			now := time.Now()
+			result.rows = [][]driver.Value{{int64(1), "alice", "alice@example.com", db.hash, "", "", "", "", now, now}}
+		}
+		return result
+	case strings.Contains(query, "SELECT failed_count, last_failed_at, locked_until"):
+		return fakeResult{columns: []string{"failed_count", "last_failed_at", "locked_until"}, rows: [][]driver.Value{{int64(db.stored), db.lastFailure, nil}}}
+	case strings.Contains(query, "FROM login_attempts WHERE username"):
+		return fakeResult{columns: []string{"count", "max"}, rows: [][]driver.Value{{int64(db.nameFailures), db.lastFailure}}}
+	case strings.Contains(query, "SELECT failed_count FROM account_lockouts"):
+		return fakeResult{columns: []string{"failed_count"}, rows: [][]driver.Value{{int64(db.storedAfter)}}}
+	case strings.Contains(query, "UPDATE account_lockouts SET locked_until"):
+		db.locked = true
+	}
+	return fakeResult{}
+}
+
+// TestAuthenticateUser 验证账户存在与否时错误响应一致，锁定以数据库累计后的次数为准
+func TestAuthenticateUser(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct-password"), bcrypt.MinCost)
+	db := &loginDB{}
+	useFakeDB(t, db.handle)
+
+	login := func(known bool) error {
+		db.known = known
+		_, err := models.AuthenticateUser(context.Background(), &models.LoginRequest{Username: "alice", Password: "wrong-password"}, &models.LoginMeta{IP: "1.2.3.4"})
+		return err
+	}
+
+	Convey("Subject: Authenticate User\n", t, func() {
+		*db = loginDB{hash: string(hash)}
+
+		Convey("Wrong Password Should Fail The Same Way For Known And Unknown Users", func() {
+			db.storedAfter = 1
+			known := login(true)
+			unknown := login(false)
+			So(known, ShouldEqual, models.ErrInvalidCredentials)
+			So(unknown, ShouldEqual, models.ErrInvalidCredentials)
+			So(db.locked, ShouldBeFalse)
+		})
+
+		Convey("Reaching The Limit Should Report The Same Lockout For Known And Unknown Users", func() {
+			db.lastFailure = time.Now().Add(-10 * time.Minute)
+			db.stored = models.LoginMaxFailures - 1
+			db.storedAfter = models.LoginMaxFailures
+			db.nameFailures = models.LoginMaxFailures - 1
+
+			known := login(true)
+			locked, ok := known.(*models.AccountLockedError)
+			So(ok, ShouldBeTrue)
+			So(locked.UnlockToken, ShouldNotBeEmpty)
+			So(db.locked, ShouldBeTrue)
+
+			unknown := login(false)
+			throttled, ok := unknown.(*models.LoginThrottledError)
+			So(ok, ShouldBeTrue)
+			So(throttled.RetryAfter, ShouldEqual, locked.RetryAfter)
+			So(throttled.Error(), ShouldEqual, locked.Error())
+		})
+
+		Convey("Lockout Should Follow The Stored Count Rather Than The Count Read Before bcrypt", func() {
+			// 检查时读到0次，其间并发请求已把计数累计到上限
+			db.stored = 0
+			db.storedAfter = models.LoginMaxFailures + 3
+
+			_, ok := login(true).(*models.AccountLockedError)
+			So(ok, ShouldBeTrue)
+			So(db.locked, ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestMoney 验证金额的精确解析、舍入与JSON编码
+func TestMoney(t *testing.T) {
+	Convey("Subject: Exact Decimal Money\n", t, func() {
+		Convey("Sums Should Not Accumulate Float Errors", func() {
+			a, _ := models.ParseMoney("0.1")
+			b, _ := models.ParseMoney("0.2")
+			So((a + b).String(), ShouldEqual, "0.30")
+		})
+
+		Convey("Parsing Should Round Half Away From Zero", func() {
+			m, _ := models.ParseMoney("1.005")
+			So(m.String(), ShouldEqual, "1.01")
+			m, _ = models.ParseMoney("-1.005")
+			So(m.String(), ShouldEqual, "-1.01")
+			m, _ = models.ParseMoney("1.004")
+			So(m.String(), ShouldEqual, "1.00")
+		})
+
+		Convey("JSON Should Accept Strings And Numbers", func() {
+			var req models.BillRequest
+			So(json.Unmarshal([]byte(`{"amount":"12.3"}`), &req), ShouldBeNil)
+			So(req.Amount, ShouldEqual, models.Money(1230))
+			So(json.Unmarshal([]byte(`{"amount":99999999999.99}`), &req), ShouldBeNil)
+			So(req.Amount, ShouldEqual, models.Money(9999999999999))
+			So(json.Unmarshal([]byte(`{"amount":1.234}`), &req), ShouldNotBeNil)
+		})
+
+		Convey("JSON Output Should Keep Two Decimals", func() {
+			data, _ := json.Marshal(models.Money(30))
+			So(string(data), ShouldEqual, "0.30")
+
+			models.MoneyJSONString = true
+			defer func() { models.MoneyJSONString = false }()
+			data, _ = json.Marshal(models.Money(-1050))
+			So(string(data), ShouldEqual, `"-10.50"`)
+		})
+
+		Convey("Scanning DECIMAL Text Should Be Exact", func() {
+			var m models.Money
+			So(m.Scan([]byte("123456789012345.67")), ShouldBeNil)
+			So(m.String(), ShouldEqual, "123456789012345.67")
+			So(m.Scan(nil), ShouldBeNil)
+			So(m, ShouldEqual, models.Money(0))
+		})
+	})
+}
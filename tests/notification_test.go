@@ -0,0 +1,38 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestNotification 验证站内通知的JSON格式和游标校验
+func TestNotification(t *testing.T) {
+	Convey("Subject: Notification Inbox\n", t, func() {
+		Convey("Payload Should Be Embedded As JSON And Unread Has No Read Time", func() {
+			notification := &models.Notification{
+				ID:        1,
+				Type:      "budget.exceeded",
+				Title:     "Budget exceeded",
+				Payload:   json.RawMessage(`{"threshold":100}`),
+				CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			}
+			data, err := json.Marshal(notification)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldContainSubstring, `"payload":{"threshold":100}`)
+			So(string(data), ShouldContainSubstring, `"read":false`)
+			So(string(data), ShouldNotContainSubstring, "read_at")
+		})
+
+		Convey("Cursor From Another Listing Should Be Rejected", func() {
+			cursor := (&models.Cursor{Sort: "-date", Value: "2024-01-01", ID: 10}).Encode()
+			_, err := models.GetNotifications(context.Background(), 1, &models.NotificationQuery{Cursor: cursor, PageSize: 10})
+			So(err, ShouldEqual, models.ErrInvalidCursor)
+		})
+	})
+}
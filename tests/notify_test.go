@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"blog/models"
+	"blog/notify"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// recordingChannel 记录收到的事件，err不为空时投递失败
+type recordingChannel struct {
+	name   string
+	err    error
+	events []*notify.Event
+}
+
+func (c *recordingChannel) Name() string {
+	return c.name
+}
+
+func (c *recordingChannel) Deliver(_ context.Context, e *notify.Event) error {
+	c.events = append(c.events, e)
+	return c.err
+}
+
+// TestNotify 验证事件分发与预算告警通知内容
+func TestNotify(t *testing.T) {
+	Convey("Subject: Notifications\n", t, func() {
+		Convey("A Failing Channel Should Not Block Other Channels", func() {
+			failing := &recordingChannel{name: "failing", err: errors.New("down")}
+			ok := &recordingChannel{name: "ok"}
+			d := &notify.Dispatcher{}
+			d.Register(failing)
+			d.Register(ok)
+
+			failed := d.Deliver(context.Background(), notify.NewEvent(notify.EventBudgetThreshold, 1, nil))
+			So(failed, ShouldEqual, 1)
+			So(len(failing.events), ShouldEqual, 1)
+			So(len(ok.events), ShouldEqual, 1)
+		})
+
+		Convey("Registered Channel Names Should Be Listed In Order", func() {
+			d := &notify.Dispatcher{}
+			d.Register(&recordingChannel{name: "email"})
+			d.Register(&recordingChannel{name: "inbox"})
+			So(d.ChannelNames(), ShouldResemble, []string{"email", "inbox"})
+		})
+
+		Convey("Alert Retries Should Back Off Up To The Limit", func() {
+			So(notify.AlertBackoff(1), ShouldEqual, notify.AlertBaseDelay)
+			So(notify.AlertBackoff(3), ShouldEqual, 4*notify.AlertBaseDelay)
+			So(notify.AlertBackoff(notify.AlertMaxAttempts+10), ShouldEqual, notify.AlertMaxDelay)
+		})
+
+		Convey("Threshold Below 100 Should Be A Threshold Crossed Event", func() {
+			event := &models.BudgetAlertEvent{
+				UserID: 1, Threshold: 80, Month: "2024-01", CategoryName: "Food",
+				UsedAmount: 8000, BudgetAmount: 10000, UsedPercent: 80,
+			}
+			e := notify.BudgetAlertEvent(event)
+			So(e.Type, ShouldEqual, notify.EventBudgetThreshold)
+
+			subject, body, ok := notify.Message("en-US", e)
+			So(ok, ShouldBeTrue)
+			So(subject, ShouldEqual, "Budget alert: Food is 80% used")
+			So(body, ShouldContainSubstring, "Used 80.00 of 100.00 (80.00%)")
+		})
+
+		Convey("Threshold Of 100 Should Be A Budget Exceeded Event", func() {
+			event := &models.BudgetAlertEvent{
+				UserID: 1, Threshold: 100, Month: "2024-01",
+				UsedAmount: 12000, BudgetAmount: 10000, UsedPercent: 120,
+			}
+			e := notify.BudgetAlertEvent(event)
+			So(e.Type, ShouldEqual, notify.EventBudgetExceeded)
+
+			subject, _, ok := notify.Message("en-US", e)
+			So(ok, ShouldBeTrue)
+			So(subject, ShouldEqual, "Budget exceeded: Total budget (2024-01)")
+		})
+
+		Convey("Unknown Events Should Have No Message", func() {
+			_, _, ok := notify.Message("en-US", notify.NewEvent("bill.created", 1, nil))
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
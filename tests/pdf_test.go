@@ -0,0 +1,46 @@
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"blog/pdf"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestPDF 验证PDF文档结构与文本宽度估算
+func TestPDF(t *testing.T) {
+	Convey("Subject: PDF Document\n", t, func() {
+		Convey("Document Should Be A Complete PDF File", func() {
+			doc := pdf.New()
+			page := doc.AddPage()
+			page.Text(48, 60, 12, pdf.Black, "月度对账单 2024-01")
+			page.Rect(48, 80, 100, 20, pdf.LightGray)
+			doc.AddPage().Line(48, 48, 200, 48, 1, pdf.Gray)
+
+			out := doc.Bytes()
+			So(bytes.HasPrefix(out, []byte("%PDF-1.4")), ShouldBeTrue)
+			So(bytes.HasSuffix(out, []byte("%%EOF\n")), ShouldBeTrue)
+			So(bytes.Contains(out, []byte("/Count 2")), ShouldBeTrue)
+			// 中文按UCS-2编码："月"为U+6708
+			So(bytes.Contains(out, []byte("<6708")), ShouldBeTrue)
+		})
+
+		Convey("Empty Document Should Still Have One Page", func() {
+			So(bytes.Contains(pdf.New().Bytes(), []byte("/Count 1")), ShouldBeTrue)
+		})
+
+		Convey("Wide Characters Should Count As Full Width", func() {
+			So(pdf.TextWidth("ab", 10), ShouldEqual, 10)
+			So(pdf.TextWidth("餐饮", 10), ShouldEqual, 20)
+		})
+
+		Convey("Long Text Should Be Truncated With Ellipsis", func() {
+			So(pdf.Truncate("short", 10, 100), ShouldEqual, "short")
+			truncated := pdf.Truncate("一二三四五六七八九十", 10, 50)
+			So(truncated, ShouldEqual, "一二三四…")
+			So(pdf.TextWidth(truncated, 10), ShouldBeLessThanOrEqualTo, 50)
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog/middleware"
+
+	"github.com/alicebob/miniredis/v2"
+	beecontext "github.com/beego/beego/v2/server/web/context"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func takeN(store middleware.RateLimitStore, policy middleware.RateLimitPolicy, n int) (allowed int, last middleware.RateLimitResult) {
+	for i := 0; i < n; i++ {
+		result, err := store.Take(context.Background(), "test:key", policy)
+		So(err, ShouldBeNil)
+		if result.Allowed {
+			allowed++
+		}
+		last = result
+	}
+	return allowed, last
+}
+
+// TestRateLimitStores 验证内存与Redis两种存储的令牌桶行为一致
+func TestRateLimitStores(t *testing.T) {
+	policy, err := middleware.ParseRateLimitPolicy("login", "5/m:3")
+
+	Convey("Subject: Rate Limit Policy\n", t, func() {
+		So(err, ShouldBeNil)
+		So(policy.Burst, ShouldEqual, 3)
+		So(policy.Rate, ShouldAlmostEqual, 5.0/60)
+	})
+
+	Convey("Subject: Memory Store\n", t, func() {
+		store := middleware.NewMemoryRateLimitStore()
+		defer store.Close()
+
+		allowed, last := takeN(store, policy, 5)
+		So(allowed, ShouldEqual, 3)
+		So(last.Allowed, ShouldBeFalse)
+		So(last.RetryAfter, ShouldBeGreaterThan, 0)
+	})
+
+	Convey("Subject: Redis Store\n", t, func() {
+		server, err := miniredis.Run()
+		So(err, ShouldBeNil)
+		defer server.Close()
+
+		allowed, last := takeN(middleware.NewRedisRateLimitStore(server.Addr(), ""), policy, 5)
+		So(allowed, ShouldEqual, 3)
+		So(last.Allowed, ShouldBeFalse)
+		So(last.Remaining, ShouldEqual, 0)
+	})
+}
+
+// TestRateLimiterFilter 验证限流响应头与可信代理处理
+func TestRateLimiterFilter(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore()
+	defer store.Close()
+	limiter := middleware.NewLimiter(store, middleware.LimiterConfig{
+		Default:        middleware.RateLimitPolicy{Name: "ip", Rate: 1, Burst: 1},
+		TrustedProxies: []string{"10.0.0.1"},
+	})
+
+	serve := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		r, _ := http.NewRequest("GET", "/api/bills", nil)
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		ctx := beecontext.NewContext()
+		ctx.Reset(w, r)
+		limiter.Filter(ctx)
+		return w
+	}
+
+	Convey("Subject: Rate Limiter Filter\n", t, func() {
+		Convey("Client IP Should Come From Trusted Proxy Header", func() {
+			So(limiter.ClientIP(&http.Request{RemoteAddr: "10.0.0.1:80", Header: http.Header{"X-Forwarded-For": {"1.2.3.4, 10.0.0.1"}}}), ShouldEqual, "1.2.3.4")
+			So(limiter.ClientIP(&http.Request{RemoteAddr: "5.6.7.8:80", Header: http.Header{"X-Forwarded-For": {"1.2.3.4"}}}), ShouldEqual, "5.6.7.8")
+		})
+
+		Convey("Exceeding The Bucket Should Return 429 With Retry-After", func() {
+			first := serve("10.0.0.1:80", "9.9.9.9")
+			So(first.Header().Get("RateLimit-Limit"), ShouldEqual, "1")
+			So(first.Header().Get("RateLimit-Remaining"), ShouldEqual, "0")
+
+			second := serve("10.0.0.1:80", "9.9.9.9")
+			So(second.Code, ShouldEqual, http.StatusTooManyRequests)
+			So(second.Header().Get("Retry-After"), ShouldEqual, "1")
+
+			// 经可信代理转发的不同客户端使用各自的令牌桶
+			other := serve("10.0.0.1:80", "8.8.8.8")
+			So(other.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
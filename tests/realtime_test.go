@@ -0,0 +1,74 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"blog/realtime"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestRealtime 验证实时推送的按用户扇出、取消订阅和连接数限制
+func TestRealtime(t *testing.T) {
+	Convey("Subject: Realtime Hub\n", t, func() {
+		hub, err := realtime.NewHub(&realtime.LocalBroker{})
+		So(err, ShouldBeNil)
+
+		Convey("Messages Should Fan Out To Every Connection Of The User Only", func() {
+			phone, err := hub.Subscribe(1)
+			So(err, ShouldBeNil)
+			laptop, err := hub.Subscribe(1)
+			So(err, ShouldBeNil)
+			other, err := hub.Subscribe(2)
+			So(err, ShouldBeNil)
+
+			So(hub.Publish(context.Background(), 1, "bill.created", map[string]uint{"id": 7}), ShouldBeNil)
+
+			for _, sub := range []*realtime.Subscription{phone, laptop} {
+				msg := <-sub.C()
+				So(msg.Type, ShouldEqual, "bill.created")
+				So(string(msg.Data), ShouldEqual, `{"id":7}`)
+			}
+			So(len(other.C()), ShouldEqual, 0)
+		})
+
+		Convey("Closed Subscription Should Stop Receiving", func() {
+			sub, err := hub.Subscribe(3)
+			So(err, ShouldBeNil)
+			sub.Close()
+			sub.Close()
+			So(hub.Connections(3), ShouldEqual, 0)
+
+			So(hub.Publish(context.Background(), 3, "bill.deleted", nil), ShouldBeNil)
+			_, ok := <-sub.C()
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("Connections Per User Should Be Limited", func() {
+			hub.MaxPerUser = 2
+			_, err := hub.Subscribe(4)
+			So(err, ShouldBeNil)
+			_, err = hub.Subscribe(4)
+			So(err, ShouldBeNil)
+			_, err = hub.Subscribe(4)
+			So(err, ShouldEqual, realtime.ErrTooManyConnections)
+		})
+
+		Convey("Slow Connection Should Not Block Publishing", func() {
+			_, err := hub.Subscribe(5)
+			So(err, ShouldBeNil)
+			for i := 0; i < 100; i++ {
+				So(hub.Publish(context.Background(), 5, "bill.updated", nil), ShouldBeNil)
+			}
+		})
+
+		Convey("Events Should Be Written In SSE Format", func() {
+			var buf bytes.Buffer
+			err := realtime.WriteEvent(&buf, 3, &realtime.Message{Type: "budget.exceeded", Data: []byte(`{"id":1}`)})
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldEqual, "id: 3\nevent: budget.exceeded\ndata: {\"id\":1}\n\n")
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestReportRanges 验证报表范围、对比期和分桶的计算
+func TestReportRanges(t *testing.T) {
+	today := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	format := func(span models.DateSpan) [2]string {
+		return [2]string{span.Start.Format("2006-01-02"), span.End.Format("2006-01-02")}
+	}
+
+	Convey("Subject: Report Ranges\n", t, func() {
+		Convey("Default Period Should Be The Current Month", func() {
+			period, current, previous, lastYear := (&models.ReportParams{}).Ranges(today)
+			So(period, ShouldEqual, models.PeriodMonth)
+			So(format(current), ShouldResemble, [2]string{"2024-02-01", "2024-02-29"})
+			So(format(previous), ShouldResemble, [2]string{"2024-01-01", "2024-01-31"})
+			So(format(lastYear), ShouldResemble, [2]string{"2023-02-01", "2023-02-28"})
+		})
+
+		Convey("Quarter Should Compare With Previous Quarter And Last Year", func() {
+			params := &models.ReportParams{Period: models.PeriodQuarter, Date: "2024-05-10"}
+			_, current, previous, lastYear := params.Ranges(today)
+			So(format(current), ShouldResemble, [2]string{"2024-04-01", "2024-06-30"})
+			So(format(previous), ShouldResemble, [2]string{"2024-01-01", "2024-03-31"})
+			So(format(lastYear), ShouldResemble, [2]string{"2023-04-01", "2023-06-30"})
+		})
+
+		Convey("Custom Range Should Compare With The Preceding Range Of Equal Length", func() {
+			params := &models.ReportParams{StartDate: "2024-02-20", EndDate: "2024-02-29"}
+			period, current, previous, lastYear := params.Ranges(today)
+			So(period, ShouldEqual, "")
+			So(format(current), ShouldResemble, [2]string{"2024-02-20", "2024-02-29"})
+			So(format(previous), ShouldResemble, [2]string{"2024-02-10", "2024-02-19"})
+			So(format(lastYear), ShouldResemble, [2]string{"2023-02-20", "2023-02-28"})
+		})
+
+		Convey("Week Buckets Should Be Clipped To The Range", func() {
+			start := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+			end := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+			buckets := models.ReportBuckets(start, end, models.BucketWeek)
+			So(len(buckets), ShouldEqual, 5)
+			So(buckets[0].StartDate, ShouldEqual, "2024-02-01")
+			So(buckets[0].EndDate, ShouldEqual, "2024-02-04")
+			So(buckets[1].StartDate, ShouldEqual, "2024-02-05")
+			So(buckets[4].EndDate, ShouldEqual, "2024-02-29")
+		})
+	})
+}
+
+// TestFormatChange 验证对账单和报告邮件共用的变化百分比格式
+func TestFormatChange(t *testing.T) {
+	up, down := 12.5, -3.456
+
+	Convey("Subject: Format Change\n", t, func() {
+		So(models.FormatChange(&up), ShouldEqual, "+12.50%")
+		So(models.FormatChange(&down), ShouldEqual, "-3.46%")
+		So(models.FormatChange(nil), ShouldEqual, "—")
+	})
+}
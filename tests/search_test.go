@@ -0,0 +1,52 @@
+package test
+
+import (
+	"testing"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestSearch 验证搜索关键词拆分与结果高亮
+func TestSearch(t *testing.T) {
+	Convey("Subject: Bill Search\n", t, func() {
+		Convey("Terms Should Be Split And Deduplicated", func() {
+			terms := models.SplitSearchTerms(`  咖啡 Coffee "coffee"  星巴克 `)
+			So(terms, ShouldResemble, []string{"咖啡", "Coffee", "星巴克"})
+		})
+
+		Convey("Matches Should Be Highlighted Case-Insensitively", func() {
+			s, ok := models.Highlight("Morning COFFEE & 咖啡", []string{"coffee", "咖啡"})
+			So(ok, ShouldBeTrue)
+			So(s, ShouldEqual, "Morning <em>COFFEE</em> &amp; <em>咖啡</em>")
+		})
+
+		Convey("Overlapping Matches Should Be Merged", func() {
+			s, ok := models.Highlight("星巴克咖啡", []string{"巴克咖", "咖啡"})
+			So(ok, ShouldBeTrue)
+			So(s, ShouldEqual, "星<em>巴克咖啡</em>")
+		})
+
+		Convey("Long Text Should Be Cut Around The First Match", func() {
+			text := ""
+			for i := 0; i < 100; i++ {
+				text += "的"
+			}
+			text += "咖啡"
+			for i := 0; i < 100; i++ {
+				text += "的"
+			}
+			s, ok := models.Highlight(text, []string{"咖啡"})
+			So(ok, ShouldBeTrue)
+			So(s, ShouldStartWith, "…")
+			So(s, ShouldEndWith, "…")
+			So(s, ShouldContainSubstring, "<em>咖啡</em>")
+		})
+
+		Convey("No Match Should Return False", func() {
+			_, ok := models.Highlight("午餐", []string{"咖啡"})
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
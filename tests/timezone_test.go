@@ -0,0 +1,45 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestParseBillDate 验证账单日期支持RFC3339并按用户时区换算
+func TestParseBillDate(t *testing.T) {
+	shanghai := models.LoadLocation("Asia/Shanghai")
+	newYork := models.LoadLocation("America/New_York")
+
+	Convey("Subject: Bill Date Parsing\n", t, func() {
+		Convey("Plain Dates Should Be Kept As Is", func() {
+			date, err := models.ParseBillDate("2024-03-01", newYork)
+			So(err, ShouldBeNil)
+			So(date.Format("2006-01-02"), ShouldEqual, "2024-03-01")
+		})
+
+		Convey("Timestamps Should Use The User's Calendar Day", func() {
+			// 2024-02-29T20:00:00-05:00 在上海已经是3月1日
+			date, err := models.ParseBillDate("2024-02-29T20:00:00-05:00", shanghai)
+			So(err, ShouldBeNil)
+			So(date.Format("2006-01-02"), ShouldEqual, "2024-03-01")
+
+			date, err = models.ParseBillDate("2024-03-01T01:00:00Z", newYork)
+			So(err, ShouldBeNil)
+			So(date.Format("2006-01-02"), ShouldEqual, "2024-02-29")
+		})
+
+		Convey("Invalid Input Should Be Rejected", func() {
+			_, err := models.ParseBillDate("03/01/2024", shanghai)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Unknown Timezones Should Fall Back To Default", func() {
+			So(models.LoadLocation("Mars/Olympus"), ShouldEqual, models.DefaultLocation)
+			So(models.CurrentMonth(time.UTC), ShouldEqual, time.Now().UTC().Format("2006-01"))
+		})
+	})
+}
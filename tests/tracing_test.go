@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog/middleware"
+
+	beego "github.com/beego/beego/v2/server/web"
+	beecontext "github.com/beego/beego/v2/server/web/context"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestTracingChain 验证HTTP请求会生成带路由和用户属性的span
+func TestTracingChain(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := middleware.NewTracerProvider(exporter)
+
+	beego.InsertFilterChain("/trace-test/*", middleware.TracingChain)
+	beego.Get("/trace-test/:id", func(ctx *beecontext.Context) {
+		ctx.Input.SetData("user_id", uint(42))
+		ctx.Output.Body([]byte("ok"))
+	})
+
+	r, _ := http.NewRequest("GET", "/trace-test/1", nil)
+	w := httptest.NewRecorder()
+	beego.BeeApp.Handlers.ServeHTTP(w, r)
+	tp.ForceFlush(context.Background())
+
+	Convey("Subject: Tracing Filter Chain\n", t, func() {
+		spans := exporter.GetSpans()
+		So(len(spans), ShouldEqual, 1)
+
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, kv := range spans[0].Attributes {
+			attrs[kv.Key] = kv.Value
+		}
+
+		Convey("Span Should Be Named After The Route", func() {
+			So(spans[0].Name, ShouldEqual, "HTTP GET /trace-test/:id")
+			So(attrs["http.route"].AsString(), ShouldEqual, "/trace-test/:id")
+		})
+		Convey("Span Should Carry User And Status", func() {
+			So(attrs["enduser.id"].AsInt64(), ShouldEqual, 42)
+			So(attrs["http.status_code"].AsInt64(), ShouldEqual, 200)
+		})
+	})
+}
+
+// TestTracingRedaction 验证span不会记录查询参数中的票据和令牌
+func TestTracingRedaction(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := middleware.NewTracerProvider(exporter)
+
+	beego.InsertFilterChain("/trace-redact/*", middleware.TracingChain)
+	beego.Get("/trace-redact/:id", func(ctx *beecontext.Context) {
+		ctx.Output.Body([]byte("ok"))
+	})
+
+	target := "/trace-redact/1?ticket=secret-ticket&token=secret-token&page=2"
+	r, _ := http.NewRequest("GET", target, nil)
+	r.RequestURI = target
+	w := httptest.NewRecorder()
+	beego.BeeApp.Handlers.ServeHTTP(w, r)
+	tp.ForceFlush(context.Background())
+
+	Convey("Subject: Tracing Query Redaction\n", t, func() {
+		// 过滤器链按注册顺序嵌套，其他测试注册的链也可能为同一请求生成span
+		spans := exporter.GetSpans()
+		So(len(spans), ShouldBeGreaterThan, 0)
+
+		var recorded []string
+		for _, span := range spans {
+			for _, kv := range span.Attributes {
+				if kv.Key == "http.target" {
+					recorded = append(recorded, kv.Value.AsString())
+				}
+			}
+		}
+		So(len(recorded), ShouldEqual, len(spans))
+
+		Convey("Credentials Should Be Redacted", func() {
+			for _, target := range recorded {
+				So(target, ShouldNotContainSubstring, "secret")
+				So(target, ShouldContainSubstring, "ticket=REDACTED")
+				So(target, ShouldContainSubstring, "token=REDACTED")
+			}
+		})
+		Convey("Other Parameters Should Be Kept", func() {
+			for _, target := range recorded {
+				So(target, ShouldContainSubstring, "page=2")
+			}
+		})
+	})
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"blog/models"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestTrend 验证趋势月份序列与分类占比的"其他"合并
+func TestTrend(t *testing.T) {
+	Convey("Subject: Trend And Category Share\n", t, func() {
+		Convey("Trend Months Should Cross Year Boundaries", func() {
+			months := models.TrendMonths(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), 4)
+			So(months, ShouldResemble, []string{"2023-11", "2023-12", "2024-01", "2024-02"})
+		})
+
+		Convey("Shares Beyond Top Should Be Merged Into Other", func() {
+			items := []*models.ShareItem{
+				{CategoryID: 1, Total: 50000, Count: 5},
+				{CategoryID: 2, Total: 30000, Count: 3},
+				{CategoryID: 3, Total: 10000, Count: 1},
+				{CategoryID: 4, Total: 10000, Count: 2},
+			}
+			shares := models.BucketShares(items, 100000, 2)
+			So(len(shares), ShouldEqual, 3)
+			So(shares[0].Percent, ShouldEqual, 50)
+			So(shares[1].Percent, ShouldEqual, 30)
+			So(shares[2].Other, ShouldBeTrue)
+			So(shares[2].Total, ShouldEqual, models.Money(20000))
+			So(shares[2].Count, ShouldEqual, 3)
+			So(shares[2].Percent, ShouldEqual, 20)
+		})
+
+		Convey("Percentages Should Add Up To 100", func() {
+			items := []*models.ShareItem{
+				{CategoryID: 1, Total: 100},
+				{CategoryID: 2, Total: 100},
+				{CategoryID: 3, Total: 100},
+			}
+			shares := models.BucketShares(items, 300, 1)
+			So(shares[0].Percent, ShouldEqual, 33.33)
+			So(shares[1].Percent, ShouldEqual, 66.67)
+		})
+
+		Convey("Empty Shares Should Stay Empty", func() {
+			So(models.BucketShares([]*models.ShareItem{}, 0, 5), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog/controllers"
+	"blog/models"
+
+	beego "github.com/beego/beego/v2/server/web"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type validationTestController struct {
+	controllers.BaseController
+}
+
+func (c *validationTestController) Post() {
+	var req models.BillRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	c.Success(req)
+}
+
+// TestParseAndValidate 验证valid标签会被执行并返回字段级错误
+func TestParseAndValidate(t *testing.T) {
+	beego.Router("/validation-test", &validationTestController{})
+
+	post := func(body string) (*httptest.ResponseRecorder, controllers.Response) {
+		r, _ := http.NewRequest("POST", "/validation-test", bytes.NewBufferString(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		var resp controllers.Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	Convey("Subject: Request Validation\n", t, func() {
+		Convey("Malformed JSON Should Return 400", func() {
+			w, _ := post(`{"amount":`)
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("Invalid Fields Should Return 422 With Field Errors", func() {
+			w, resp := post(`{"category_id":1,"amount":-1,"type":"gift","date":"2024-13-01"}`)
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+
+			rules := map[string]string{}
+			for _, e := range resp.Errors {
+				rules[e.Field] = e.Rule
+				So(e.Message, ShouldNotBeEmpty)
+			}
+			So(rules, ShouldResemble, map[string]string{
+				"amount": "Money",
+				"type":   "Match",
+				"date":   "DateTime",
+			})
+		})
+
+		Convey("Amount With More Than Two Decimals Should Be Rejected", func() {
+			w, resp := post(`{"category_id":1,"amount":1.234,"type":"expense","date":"2024-01-01"}`)
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(len(resp.Errors), ShouldEqual, 1)
+			So(resp.Errors[0].Field, ShouldEqual, "amount")
+			So(resp.Errors[0].Rule, ShouldEqual, "Money")
+		})
+
+		Convey("Valid Request Should Pass", func() {
+			w, _ := post(`{"category_id":1,"amount":12.5,"type":"expense","date":"2024-01-01"}`)
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"blog/controllers"
+	"blog/models"
+
+	beego "github.com/beego/beego/v2/server/web"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type viewValidationTestController struct {
+	controllers.BaseController
+}
+
+func (c *viewValidationTestController) Post() {
+	var req models.ViewRequest
+	if err := c.ParseAndValidate(&req); err != nil {
+		return
+	}
+	c.Success(req)
+}
+
+// TestRelativeDateRange 验证相对日期范围的计算
+func TestRelativeDateRange(t *testing.T) {
+	// 2024-02-15 为周四
+	today := time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+
+	Convey("Subject: Relative Date Range\n", t, func() {
+		cases := map[string][2]string{
+			models.RangeToday:       {"2024-02-15", "2024-02-15"},
+			models.RangeYesterday:   {"2024-02-14", "2024-02-14"},
+			models.RangeLast7Days:   {"2024-02-09", "2024-02-15"},
+			models.RangeLast30Days:  {"2024-01-17", "2024-02-15"},
+			models.RangeThisWeek:    {"2024-02-12", "2024-02-18"},
+			models.RangeLastWeek:    {"2024-02-05", "2024-02-11"},
+			models.RangeThisMonth:   {"2024-02-01", "2024-02-29"},
+			models.RangeLastMonth:   {"2024-01-01", "2024-01-31"},
+			models.RangeThisQuarter: {"2024-01-01", "2024-03-31"},
+			models.RangeLastQuarter: {"2023-10-01", "2023-12-31"},
+			models.RangeThisYear:    {"2024-01-01", "2024-12-31"},
+			models.RangeLastYear:    {"2023-01-01", "2023-12-31"},
+		}
+		for name, expected := range cases {
+			start, end, ok := models.ResolveDateRange(name, today)
+			So(ok, ShouldBeTrue)
+			So([2]string{start, end}, ShouldResemble, expected)
+		}
+
+		Convey("Unknown Range Should Not Resolve", func() {
+			_, _, ok := models.ResolveDateRange("next_decade", today)
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("View Filter Should Resolve Relative Dates Into Query Params", func() {
+			filter := &models.ViewFilter{DateRange: models.RangeLastMonth, Type: "expense"}
+			params := filter.Params(today)
+			So(params.StartDate, ShouldEqual, "2024-01-01")
+			So(params.EndDate, ShouldEqual, "2024-01-31")
+			So(params.Type, ShouldEqual, "expense")
+		})
+	})
+}
+
+// TestViewValidation 验证视图筛选条件作为嵌套字段校验
+func TestViewValidation(t *testing.T) {
+	beego.Router("/view-validation-test", &viewValidationTestController{})
+
+	post := func(body string) (*httptest.ResponseRecorder, controllers.Response) {
+		r, _ := http.NewRequest("POST", "/view-validation-test", bytes.NewBufferString(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		var resp controllers.Response
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	Convey("Subject: Saved View Validation\n", t, func() {
+		Convey("Invalid Filter Fields Should Be Reported With Prefix", func() {
+			w, resp := post(`{"name":"coffee","filter":{"date_range":"next_decade","type":"gift"}}`)
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+
+			rules := map[string]string{}
+			for _, e := range resp.Errors {
+				rules[e.Field] = e.Rule
+			}
+			So(rules, ShouldResemble, map[string]string{
+				"filter.date_range": "DateRange",
+				"filter.type":       "Match",
+			})
+		})
+
+		Convey("Relative And Fixed Dates Should Not Be Combined", func() {
+			w, resp := post(`{"name":"coffee","filter":{"date_range":"this_month","start_date":"2024-01-01"}}`)
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
+			So(len(resp.Errors), ShouldEqual, 1)
+			So(resp.Errors[0].Field, ShouldEqual, "filter.date_range")
+		})
+
+		Convey("Valid View Should Pass", func() {
+			w, _ := post(`{"name":"coffee","filter":{"date_range":"last_30_days","q":"咖啡","min_amount":10}}`)
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+}
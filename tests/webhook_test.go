@@ -0,0 +1,85 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"blog/models"
+	"blog/notify"
+	"blog/webhook"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestWebhook 使用本地HTTP接收端验证Webhook的签名、响应处理、地址限制和退避时间
+func TestWebhook(t *testing.T) {
+	Convey("Subject: Webhook Delivery\n", t, func() {
+		var received *http.Request
+		var body []byte
+		status := http.StatusOK
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(status)
+			w.Write([]byte("received"))
+		}))
+		defer receiver.Close()
+
+		hook := &models.Webhook{ID: 1, URL: receiver.URL, Secret: "s3cret"}
+		delivery := &models.WebhookDelivery{ID: 42, Event: notify.EventBillCreated, Payload: []byte(`{"id":"abc","type":"bill.created"}`)}
+
+		Convey("Request Should Be Signed And Verifiable By The Receiver", func() {
+			result := webhook.New(true).Send(context.Background(), hook, delivery)
+			So(result.OK(), ShouldBeTrue)
+			So(result.StatusCode, ShouldEqual, http.StatusOK)
+			So(result.Body, ShouldEqual, "received")
+
+			So(string(body), ShouldEqual, string(delivery.Payload))
+			So(received.Header.Get(webhook.HeaderEvent), ShouldEqual, "bill.created")
+			So(received.Header.Get(webhook.HeaderDelivery), ShouldEqual, "42")
+
+			timestamp, err := strconv.ParseInt(received.Header.Get(webhook.HeaderTimestamp), 10, 64)
+			So(err, ShouldBeNil)
+			signature := received.Header.Get(webhook.HeaderSignature)
+			So(webhook.Verify("s3cret", timestamp, body, signature), ShouldBeTrue)
+			So(webhook.Verify("wrong", timestamp, body, signature), ShouldBeFalse)
+			So(webhook.Verify("s3cret", timestamp+1, body, signature), ShouldBeFalse)
+		})
+
+		Convey("Non-2xx Response Should Be Recorded As Failure", func() {
+			status = http.StatusInternalServerError
+			result := webhook.New(true).Send(context.Background(), hook, delivery)
+			So(result.OK(), ShouldBeFalse)
+			So(result.StatusCode, ShouldEqual, http.StatusInternalServerError)
+			So(result.Err, ShouldNotBeNil)
+		})
+
+		Convey("Private Addresses Should Be Rejected By Default", func() {
+			result := webhook.New(false).Send(context.Background(), hook, delivery)
+			So(result.OK(), ShouldBeFalse)
+			So(result.StatusCode, ShouldEqual, 0)
+			So(received, ShouldBeNil)
+		})
+
+		Convey("Retry Delay Should Double Up To The Maximum", func() {
+			sender := webhook.New(false)
+			sender.BaseDelay = time.Minute
+			sender.MaxDelay = 10 * time.Minute
+			So(sender.Backoff(1), ShouldEqual, time.Minute)
+			So(sender.Backoff(2), ShouldEqual, 2*time.Minute)
+			So(sender.Backoff(4), ShouldEqual, 8*time.Minute)
+			So(sender.Backoff(5), ShouldEqual, 10*time.Minute)
+			So(sender.Backoff(30), ShouldEqual, 10*time.Minute)
+		})
+
+		Convey("Only Known Events Can Be Subscribed", func() {
+			So(notify.IsEventType("budget.exceeded"), ShouldBeTrue)
+			So(notify.IsEventType("bill.exploded"), ShouldBeFalse)
+		})
+	})
+}
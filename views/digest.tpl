@@ -0,0 +1,49 @@
+<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+    <meta charset="utf-8">
+    <title>{{t .Lang (printf "digest_title_%s" .Digest.Frequency)}}</title>
+</head>
+<body style="margin: 0; padding: 24px; background: #f5f5f5; font-family: 'PingFang SC', 'Microsoft YaHei', sans-serif; color: #222;">
+    <div style="max-width: 600px; margin: 0 auto; background: #fff; padding: 24px;">
+        <h1 style="font-size: 20px; margin: 0 0 4px;">{{t .Lang (printf "digest_title_%s" .Digest.Frequency)}}</h1>
+        <div style="color: #888; font-size: 12px;">{{t .Lang "digest_greeting" .Digest.User.Username}} · {{.Report.StartDate}} ~ {{.Report.EndDate}}</div>
+
+        <table style="width: 100%; margin-top: 20px; border-collapse: collapse; text-align: center;">
+            <tr>
+                <td style="background: #f5f5f5; padding: 12px;">{{t .Lang "statement_income"}}<br><strong style="font-size: 18px;">{{.Report.Summary.Income}}</strong></td>
+                <td style="background: #f5f5f5; padding: 12px;">{{t .Lang "statement_expense"}}<br><strong style="font-size: 18px;">{{.Report.Summary.Expense}}</strong></td>
+                <td style="background: #f5f5f5; padding: 12px;">{{t .Lang "statement_balance"}}<br><strong style="font-size: 18px;">{{.Report.Summary.Balance}}</strong></td>
+            </tr>
+        </table>
+        <p style="color: #888; font-size: 12px;">{{t .Lang "digest_expense_vs_previous"}} {{.PreviousChange}} · {{t .Lang "statement_count"}} {{.Report.Summary.Count}}</p>
+
+        <h2 style="font-size: 15px; border-bottom: 1px solid #ddd; padding-bottom: 4px;">{{t .Lang "statement_categories"}}</h2>
+        {{if .Digest.Categories}}
+        <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
+            {{range .Digest.Categories}}
+            <tr><td style="padding: 4px;">{{.Name}}</td><td style="padding: 4px; text-align: right;">{{.Total}}</td><td style="padding: 4px; text-align: right; color: #888;">{{.Percent}}%</td></tr>
+            {{end}}
+        </table>
+        {{else}}
+        <p style="color: #888; font-size: 13px;">{{t .Lang "statement_no_data"}}</p>
+        {{end}}
+
+        {{if .Digest.Budgets}}
+        <h2 style="font-size: 15px; border-bottom: 1px solid #ddd; padding-bottom: 4px;">{{t .Lang "statement_budgets"}}</h2>
+        <table style="width: 100%; border-collapse: collapse; font-size: 13px;">
+            {{range .Digest.Budgets}}
+            <tr>
+                <td style="padding: 4px;">{{if .DisplayName}}{{.DisplayName}}{{else}}{{t $.Lang "statement_total_budget"}}{{end}}</td>
+                <td style="padding: 4px; text-align: right;">{{.UsedAmount}} / {{.Available}}</td>
+                <td style="padding: 4px; text-align: right;{{if ge .Percentage 100.0}} color: #d9534f;{{end}}">{{.Percentage}}%</td>
+            </tr>
+            {{end}}
+        </table>
+        {{end}}
+
+        <p style="margin-top: 24px;"><a href="{{.SiteURL}}" style="color: #4a90d9;">{{t .Lang "digest_open_app"}}</a></p>
+        <p style="color: #aaa; font-size: 11px; margin-top: 24px;">{{t .Lang "digest_footer"}} <a href="{{.UnsubscribeURL}}" style="color: #aaa;">{{t .Lang "digest_unsubscribe"}}</a></p>
+    </div>
+</body>
+</html>
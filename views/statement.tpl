@@ -0,0 +1,75 @@
+<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+    <meta charset="utf-8">
+    <title>{{t .Lang "statement_title"}} {{.Month}}</title>
+    <style>
+        body { font-family: "PingFang SC", "Microsoft YaHei", "Noto Sans CJK SC", sans-serif; color: #222; margin: 32px auto; max-width: 800px; }
+        h1 { font-size: 24px; margin-bottom: 4px; }
+        h2 { font-size: 16px; border-bottom: 1px solid #ddd; padding-bottom: 4px; margin-top: 28px; }
+        .meta { color: #888; font-size: 12px; }
+        .summary { display: flex; gap: 12px; }
+        .summary div { flex: 1; background: #f5f5f5; padding: 12px; }
+        .summary strong { display: block; font-size: 18px; margin-top: 4px; }
+        table { width: 100%; border-collapse: collapse; font-size: 13px; }
+        th, td { text-align: left; padding: 6px 4px; border-bottom: 1px solid #eee; }
+        td.num, th.num { text-align: right; }
+        .bar { background: #eee; height: 8px; }
+        .bar span { display: block; height: 8px; max-width: 100%; background: #4a90d9; }
+        .bar span.over { background: #d9534f; }
+        .chart { display: flex; align-items: flex-end; height: 120px; gap: 2px; border-bottom: 1px solid #ccc; }
+        .chart span { flex: 1; background: #4a90d9; min-height: 1px; }
+        .chart-labels { display: flex; justify-content: space-between; font-size: 11px; color: #888; }
+        @media print { body { margin: 0; } }
+    </style>
+</head>
+<body>
+    <h1>{{t .Lang "statement_title"}}</h1>
+    <div class="meta">{{.User.Username}} · {{.Report.StartDate}} ~ {{.Report.EndDate}} · {{t .Lang "statement_generated_at"}} {{.GeneratedAt.Format "2006-01-02 15:04"}}</div>
+
+    <h2>{{t .Lang "statement_summary"}}</h2>
+    <div class="summary">
+        <div>{{t .Lang "statement_income"}}<strong>{{.Report.Summary.Income}}</strong></div>
+        <div>{{t .Lang "statement_expense"}}<strong>{{.Report.Summary.Expense}}</strong></div>
+        <div>{{t .Lang "statement_balance"}}<strong>{{.Report.Summary.Balance}}</strong></div>
+        <div>{{t .Lang "statement_count"}}<strong>{{.Report.Summary.Count}}</strong></div>
+    </div>
+    <p class="meta">{{t .Lang "statement_expense_vs_previous"}} {{.PreviousChange}} · {{t .Lang "statement_expense_vs_last_year"}} {{.LastYearChange}}</p>
+
+    <h2>{{t .Lang "statement_daily_expense"}}</h2>
+    <div class="chart">
+        {{range .Daily}}<span style="height: {{.Height}}%" title="{{.Date}} {{.Expense}}"></span>{{end}}
+    </div>
+    <div class="chart-labels"><span>{{.Report.StartDate}}</span><span>{{.Report.EndDate}}</span></div>
+
+    <h2>{{t .Lang "statement_categories"}}</h2>
+    {{if .Expenses}}
+    <table>
+        <tr><th>{{t .Lang "statement_category"}}</th><th class="num">{{t .Lang "statement_count"}}</th><th class="num">{{t .Lang "statement_amount"}}</th><th class="num">{{t .Lang "statement_share"}}</th><th style="width: 30%"></th></tr>
+        {{range .Expenses}}
+        <tr><td>{{.Name}}</td><td class="num">{{.Count}}</td><td class="num">{{.Total}}</td><td class="num">{{.Percent}}%</td><td><div class="bar"><span style="width: {{.Percent}}%"></span></div></td></tr>
+        {{end}}
+    </table>
+    {{else}}<p class="meta">{{t .Lang "statement_no_data"}}</p>{{end}}
+
+    <h2>{{t .Lang "statement_budgets"}}</h2>
+    {{if .Budgets}}
+    <table>
+        <tr><th>{{t .Lang "statement_budget"}}</th><th class="num">{{t .Lang "statement_available"}}</th><th class="num">{{t .Lang "statement_used"}}</th><th class="num">%</th><th style="width: 30%"></th></tr>
+        {{range .Budgets}}
+        <tr><td>{{if .DisplayName}}{{.DisplayName}}{{else}}{{t $.Lang "statement_total_budget"}}{{end}}</td><td class="num">{{.Available}}</td><td class="num">{{.UsedAmount}}</td><td class="num">{{.Percentage}}%</td><td><div class="bar"><span class="{{if ge .Percentage 100.0}}over{{end}}" style="width: {{.Percentage}}%"></span></div></td></tr>
+        {{end}}
+    </table>
+    {{else}}<p class="meta">{{t .Lang "statement_no_data"}}</p>{{end}}
+
+    <h2>{{t .Lang "statement_top_bills"}}</h2>
+    {{if .TopBills}}
+    <table>
+        <tr><th>{{t .Lang "statement_date"}}</th><th>{{t .Lang "statement_category"}}</th><th>{{t .Lang "statement_description"}}</th><th class="num">{{t .Lang "statement_amount"}}</th></tr>
+        {{range .TopBills}}
+        <tr><td>{{.Date.Format "2006-01-02"}}</td><td>{{.CategoryName}}</td><td>{{if .Merchant}}{{.Merchant}} {{end}}{{.Description}}</td><td class="num">{{.Amount}}</td></tr>
+        {{end}}
+    </table>
+    {{else}}<p class="meta">{{t .Lang "statement_no_data"}}</p>{{end}}
+</body>
+</html>
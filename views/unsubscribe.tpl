@@ -0,0 +1,25 @@
+<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <meta name="robots" content="noindex">
+    <title>{{t .Lang "unsubscribe_title"}}</title>
+</head>
+<body style="margin: 0; padding: 24px; background: #f5f5f5; font-family: 'PingFang SC', 'Microsoft YaHei', sans-serif; color: #222;">
+    <div style="max-width: 480px; margin: 0 auto; background: #fff; padding: 24px;">
+        <h1 style="font-size: 20px; margin: 0 0 12px;">{{t .Lang "unsubscribe_title"}}</h1>
+        {{if .Done}}
+        <p>{{t .Lang "unsubscribe_done"}}</p>
+        {{else if .Error}}
+        <p style="color: #c0392b;">{{.Error}}</p>
+        {{else}}
+        <p>{{t .Lang "unsubscribe_confirm"}}</p>
+        <form method="post" action="/api/digests/unsubscribe">
+            <input type="hidden" name="token" value="{{.Token}}">
+            <button type="submit" style="padding: 8px 20px; background: #222; color: #fff; border: 0; cursor: pointer;">{{t .Lang "digest_unsubscribe"}}</button>
+        </form>
+        {{end}}
+    </div>
+</body>
+</html>
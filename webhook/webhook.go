@@ -0,0 +1,339 @@
+// Package webhook 把事件以签名的HTTP请求投递到用户注册的地址
+//
+// 每个事件为每个订阅它的Webhook生成一条投递记录并立即尝试，失败后按指数退避重试，
+// 重试由后台循环从数据库中取出到期的记录执行，多个实例同时运行时每次尝试只执行一次。
+//
+// 请求体为JSON：{"id": 事件ID, "type": 事件类型, "created_at": 时间, "data": 事件数据}。
+// 签名为 X-FinWise-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))，
+// timestamp取自X-FinWise-Timestamp，接收方可据此拒绝过旧的请求。
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"blog/models"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 投递默认值
+const (
+	DefaultMaxAttempts = 8
+	DefaultBaseDelay   = time.Minute
+	DefaultMaxDelay    = 6 * time.Hour
+	DefaultInterval    = 30 * time.Second
+
+	requestTimeout  = 10 * time.Second
+	claimLease      = time.Minute // 大于requestTimeout，投递中途退出的记录在此之后重试
+	maxResponseBody = 1024
+	batchSize       = 100
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-FinWise-Event"
+	HeaderDelivery  = "X-FinWise-Delivery"
+	HeaderTimestamp = "X-FinWise-Timestamp"
+	HeaderSignature = "X-FinWise-Signature"
+)
+
+// errPrivateAddress 目标地址是本机或内网地址
+var errPrivateAddress = errors.New("webhook: private address not allowed")
+
+// Payload 投递的请求体
+type Payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Result 一次HTTP请求的结果
+type Result struct {
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+// OK 是否投递成功，接收方返回2xx即视为成功
+func (r *Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Sender 投递和重试Webhook
+type Sender struct {
+	Client      *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Interval    time.Duration
+}
+
+// New 创建Sender，allowPrivate为false时拒绝投递到本机和内网地址
+func New(allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowPrivate {
+		// 在连接时检查解析后的地址，域名解析到内网地址同样会被拒绝
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &Sender{
+		Client: &http.Client{
+			Timeout: requestTimeout,
+			// 不使用环境变量中的代理，否则连接的是代理地址，地址检查对目标地址不起作用
+			Transport: &http.Transport{Proxy: nil, DialContext: dialer.DialContext},
+			// 不跟随重定向，避免绕过地址检查
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		Interval:    DefaultInterval,
+	}
+}
+
+// isPrivate 是否为本机、内网或链路本地地址
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// Default 默认的Sender
+var Default = New(false)
+
+// Init 根据webhook*配置初始化默认Sender并启动重试循环
+func Init() {
+	if allow, _ := web.AppConfig.Bool("webhookallowprivate"); allow {
+		Default = New(true)
+	}
+	if interval, _ := web.AppConfig.String("webhookretryinterval"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil || d <= 0 {
+			logs.Error("Invalid webhookretryinterval %q, using %v", interval, DefaultInterval)
+		} else {
+			Default.Interval = d
+		}
+	}
+
+	go Default.Start(context.Background())
+}
+
+// Sign 计算请求签名
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验请求签名，供接收方和测试使用
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff 第attempt次尝试失败后等待的时间，从BaseDelay开始每次翻倍，不超过MaxDelay
+func (s *Sender) Backoff(attempt int) time.Duration {
+	delay := s.BaseDelay
+	for i := 1; i < attempt && delay < s.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.MaxDelay {
+		delay = s.MaxDelay
+	}
+	return delay
+}
+
+// Dispatch 为用户订阅了该事件的每个Webhook创建投递记录，并在后台立即尝试；
+// 某个Webhook创建记录失败不影响其余Webhook，返回遇到的第一个错误
+func (s *Sender) Dispatch(ctx context.Context, userID uint, eventType string, createdAt time.Time, data interface{}) error {
+	webhooks, err := models.GetSubscribedWebhooks(ctx, userID, eventType)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	id, err := eventID()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(&Payload{ID: id, Type: eventType, CreatedAt: createdAt, Data: data})
+	if err != nil {
+		logs.Error("Error encoding webhook payload: %v", err)
+		return err
+	}
+
+	var firstErr error
+	for _, webhook := range webhooks {
+		d, err := models.CreateWebhookDelivery(ctx, webhook, eventType, body)
+		if err != nil {
+			logs.Error("Error creating delivery for webhook %d: %v", webhook.ID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		go s.Attempt(context.Background(), webhook, d)
+	}
+	return firstErr
+}
+
+// eventID 生成事件ID，同一事件的所有投递和重新投递使用相同的ID，接收方可据此去重
+func eventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logs.Error("Error generating webhook event ID: %v", err)
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Redeliver 重新投递，创建新的投递记录并同步尝试一次，返回新记录
+func (s *Sender) Redeliver(ctx context.Context, id, userID uint) (*models.WebhookDelivery, error) {
+	d, webhook, err := models.RedeliverWebhookDelivery(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Attempt(ctx, webhook, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Start 按间隔重试到期的投递，直到ctx取消
+func (s *Sender) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			logs.Error("Error retrying webhook deliveries: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 重试所有到期的投递，返回本次投递成功的数量
+func (s *Sender) RunOnce(ctx context.Context) (int, error) {
+	deliveries, webhooks, err := models.GetDueWebhookDeliveries(ctx, time.Now(), batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for i, d := range deliveries {
+		if ctx.Err() != nil {
+			return succeeded, ctx.Err()
+		}
+		if err := s.Attempt(ctx, webhooks[i], d); err != nil {
+			logs.Error("Error attempting webhook delivery %d: %v", d.ID, err)
+			continue
+		}
+		if d.Status == models.WebhookSucceeded {
+			succeeded++
+		}
+	}
+	return succeeded, nil
+}
+
+// Attempt 占用投递记录后发送一次请求并保存结果，记录已被其他实例占用时直接返回
+//
+// 失败且未达到最大尝试次数时按Backoff安排下次重试，否则标记为失败。
+func (s *Sender) Attempt(ctx context.Context, webhook *models.Webhook, d *models.WebhookDelivery) error {
+	claimed, err := models.ClaimWebhookDelivery(ctx, d, claimLease)
+	if err != nil || !claimed {
+		return err
+	}
+
+	result := s.Send(ctx, webhook, d)
+	d.ResponseCode = result.StatusCode
+	d.ResponseBody = result.Body
+	d.Error = ""
+	d.NextAttemptAt = nil
+	switch {
+	case result.OK():
+		d.Status = models.WebhookSucceeded
+	case d.Attempts >= s.MaxAttempts:
+		d.Status = models.WebhookFailed
+	default:
+		d.Status = models.WebhookPending
+		next := time.Now().Add(s.Backoff(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+	if result.Err != nil {
+		d.Error = truncate(result.Err.Error(), 255)
+	}
+
+	return models.RecordWebhookAttempt(context.Background(), d)
+}
+
+// Send 签名并发送投递请求，不修改投递记录
+func (s *Sender) Send(ctx context.Context, webhook *models.Webhook, d *models.WebhookDelivery) *Result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return &Result{Err: err}
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FinWise-Webhook/1.0")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, d.Payload))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return &Result{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	result := &Result{StatusCode: resp.StatusCode, Body: strings.ToValidUTF8(string(body), "")}
+	if !result.OK() {
+		result.Err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return result
+}
+
+// truncate 按字节截断，不拆开多字节字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}